@@ -0,0 +1,1143 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.7
+// 	protoc        (unknown)
+// source: api/ragger/v1/ragger.proto
+
+package raggerv1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// Filter narrows search; empty fields match every chunk.
+type Filter struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Language of the chunk (payload.lang), e.g. "ru" or "en".
+	Lang string `protobuf:"bytes,1,opt,name=lang,proto3" json:"lang,omitempty"`
+	// Only chunks of this document.
+	DocId         string `protobuf:"bytes,2,opt,name=doc_id,json=docId,proto3" json:"doc_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Filter) Reset() {
+	*x = Filter{}
+	mi := &file_api_ragger_v1_ragger_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Filter) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Filter) ProtoMessage() {}
+
+func (x *Filter) ProtoReflect() protoreflect.Message {
+	mi := &file_api_ragger_v1_ragger_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Filter.ProtoReflect.Descriptor instead.
+func (*Filter) Descriptor() ([]byte, []int) {
+	return file_api_ragger_v1_ragger_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Filter) GetLang() string {
+	if x != nil {
+		return x.Lang
+	}
+	return ""
+}
+
+func (x *Filter) GetDocId() string {
+	if x != nil {
+		return x.DocId
+	}
+	return ""
+}
+
+// SearchOptions override the server configuration; unset fields keep it.
+type SearchOptions struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// dense | keyword | hybrid
+	Mode *string `protobuf:"bytes,1,opt,name=mode,proto3,oneof" json:"mode,omitempty"`
+	// Fusion of hybrid search: rrf | weighted
+	Fusion *string `protobuf:"bytes,2,opt,name=fusion,proto3,oneof" json:"fusion,omitempty"`
+	Rerank *bool   `protobuf:"varint,3,opt,name=rerank,proto3,oneof" json:"rerank,omitempty"`
+	Mmr    *bool   `protobuf:"varint,4,opt,name=mmr,proto3,oneof" json:"mmr,omitempty"`
+	// Maximum hits of one document; 0 disables the cap.
+	MaxPerDoc *uint32 `protobuf:"varint,5,opt,name=max_per_doc,json=maxPerDoc,proto3,oneof" json:"max_per_doc,omitempty"`
+	// Adjacent chunks merged into every hit on each side.
+	Neighbors     *uint32 `protobuf:"varint,6,opt,name=neighbors,proto3,oneof" json:"neighbors,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SearchOptions) Reset() {
+	*x = SearchOptions{}
+	mi := &file_api_ragger_v1_ragger_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SearchOptions) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SearchOptions) ProtoMessage() {}
+
+func (x *SearchOptions) ProtoReflect() protoreflect.Message {
+	mi := &file_api_ragger_v1_ragger_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SearchOptions.ProtoReflect.Descriptor instead.
+func (*SearchOptions) Descriptor() ([]byte, []int) {
+	return file_api_ragger_v1_ragger_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *SearchOptions) GetMode() string {
+	if x != nil && x.Mode != nil {
+		return *x.Mode
+	}
+	return ""
+}
+
+func (x *SearchOptions) GetFusion() string {
+	if x != nil && x.Fusion != nil {
+		return *x.Fusion
+	}
+	return ""
+}
+
+func (x *SearchOptions) GetRerank() bool {
+	if x != nil && x.Rerank != nil {
+		return *x.Rerank
+	}
+	return false
+}
+
+func (x *SearchOptions) GetMmr() bool {
+	if x != nil && x.Mmr != nil {
+		return *x.Mmr
+	}
+	return false
+}
+
+func (x *SearchOptions) GetMaxPerDoc() uint32 {
+	if x != nil && x.MaxPerDoc != nil {
+		return *x.MaxPerDoc
+	}
+	return 0
+}
+
+func (x *SearchOptions) GetNeighbors() uint32 {
+	if x != nil && x.Neighbors != nil {
+		return *x.Neighbors
+	}
+	return 0
+}
+
+type SearchRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Query string                 `protobuf:"bytes,1,opt,name=query,proto3" json:"query,omitempty"`
+	// Number of hits; 0 keeps the configured top-k.
+	K             uint32         `protobuf:"varint,2,opt,name=k,proto3" json:"k,omitempty"`
+	Filter        *Filter        `protobuf:"bytes,3,opt,name=filter,proto3" json:"filter,omitempty"`
+	Options       *SearchOptions `protobuf:"bytes,4,opt,name=options,proto3" json:"options,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SearchRequest) Reset() {
+	*x = SearchRequest{}
+	mi := &file_api_ragger_v1_ragger_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SearchRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SearchRequest) ProtoMessage() {}
+
+func (x *SearchRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_ragger_v1_ragger_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SearchRequest.ProtoReflect.Descriptor instead.
+func (*SearchRequest) Descriptor() ([]byte, []int) {
+	return file_api_ragger_v1_ragger_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *SearchRequest) GetQuery() string {
+	if x != nil {
+		return x.Query
+	}
+	return ""
+}
+
+func (x *SearchRequest) GetK() uint32 {
+	if x != nil {
+		return x.K
+	}
+	return 0
+}
+
+func (x *SearchRequest) GetFilter() *Filter {
+	if x != nil {
+		return x.Filter
+	}
+	return nil
+}
+
+func (x *SearchRequest) GetOptions() *SearchOptions {
+	if x != nil {
+		return x.Options
+	}
+	return nil
+}
+
+type SearchResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Hits          []*Hit                 `protobuf:"bytes,1,rep,name=hits,proto3" json:"hits,omitempty"`
+	TookMs        uint32                 `protobuf:"varint,2,opt,name=took_ms,json=tookMs,proto3" json:"took_ms,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SearchResponse) Reset() {
+	*x = SearchResponse{}
+	mi := &file_api_ragger_v1_ragger_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SearchResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SearchResponse) ProtoMessage() {}
+
+func (x *SearchResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_ragger_v1_ragger_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SearchResponse.ProtoReflect.Descriptor instead.
+func (*SearchResponse) Descriptor() ([]byte, []int) {
+	return file_api_ragger_v1_ragger_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *SearchResponse) GetHits() []*Hit {
+	if x != nil {
+		return x.Hits
+	}
+	return nil
+}
+
+func (x *SearchResponse) GetTookMs() uint32 {
+	if x != nil {
+		return x.TookMs
+	}
+	return 0
+}
+
+// Hit is a chunk (or a passage of adjacent chunks) found by search.
+type Hit struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Id    string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	// Ranking score: cosine similarity, BM25, the fused hybrid score or,
+	// after reranking, the rerank score.
+	Score   float32 `protobuf:"fixed32,2,opt,name=score,proto3" json:"score,omitempty"`
+	Title   string  `protobuf:"bytes,3,opt,name=title,proto3" json:"title,omitempty"`
+	Text    string  `protobuf:"bytes,4,opt,name=text,proto3" json:"text,omitempty"`
+	DocId   string  `protobuf:"bytes,5,opt,name=doc_id,json=docId,proto3" json:"doc_id,omitempty"`
+	ChunkId string  `protobuf:"bytes,6,opt,name=chunk_id,json=chunkId,proto3" json:"chunk_id,omitempty"`
+	Path    string  `protobuf:"bytes,7,opt,name=path,proto3" json:"path,omitempty"`
+	Lang    string  `protobuf:"bytes,8,opt,name=lang,proto3" json:"lang,omitempty"`
+	// Byte offsets of text in the document text.
+	Start int32 `protobuf:"varint,9,opt,name=start,proto3" json:"start,omitempty"`
+	End   int32 `protobuf:"varint,10,opt,name=end,proto3" json:"end,omitempty"`
+	// Chunks merged into text by context expansion.
+	ChunkIds []string `protobuf:"bytes,11,rep,name=chunk_ids,json=chunkIds,proto3" json:"chunk_ids,omitempty"`
+	// Scores of the dense and keyword lists; 0 when a list missed the chunk.
+	DenseScore   float32 `protobuf:"fixed32,12,opt,name=dense_score,json=denseScore,proto3" json:"dense_score,omitempty"`
+	KeywordScore float32 `protobuf:"fixed32,13,opt,name=keyword_score,json=keywordScore,proto3" json:"keyword_score,omitempty"`
+	Reranked     bool    `protobuf:"varint,14,opt,name=reranked,proto3" json:"reranked,omitempty"`
+	// Retrieval score of a reranked hit.
+	OriginalScore float32 `protobuf:"fixed32,15,opt,name=original_score,json=originalScore,proto3" json:"original_score,omitempty"`
+	RerankScore   float32 `protobuf:"fixed32,16,opt,name=rerank_score,json=rerankScore,proto3" json:"rerank_score,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Hit) Reset() {
+	*x = Hit{}
+	mi := &file_api_ragger_v1_ragger_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Hit) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Hit) ProtoMessage() {}
+
+func (x *Hit) ProtoReflect() protoreflect.Message {
+	mi := &file_api_ragger_v1_ragger_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Hit.ProtoReflect.Descriptor instead.
+func (*Hit) Descriptor() ([]byte, []int) {
+	return file_api_ragger_v1_ragger_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *Hit) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Hit) GetScore() float32 {
+	if x != nil {
+		return x.Score
+	}
+	return 0
+}
+
+func (x *Hit) GetTitle() string {
+	if x != nil {
+		return x.Title
+	}
+	return ""
+}
+
+func (x *Hit) GetText() string {
+	if x != nil {
+		return x.Text
+	}
+	return ""
+}
+
+func (x *Hit) GetDocId() string {
+	if x != nil {
+		return x.DocId
+	}
+	return ""
+}
+
+func (x *Hit) GetChunkId() string {
+	if x != nil {
+		return x.ChunkId
+	}
+	return ""
+}
+
+func (x *Hit) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+func (x *Hit) GetLang() string {
+	if x != nil {
+		return x.Lang
+	}
+	return ""
+}
+
+func (x *Hit) GetStart() int32 {
+	if x != nil {
+		return x.Start
+	}
+	return 0
+}
+
+func (x *Hit) GetEnd() int32 {
+	if x != nil {
+		return x.End
+	}
+	return 0
+}
+
+func (x *Hit) GetChunkIds() []string {
+	if x != nil {
+		return x.ChunkIds
+	}
+	return nil
+}
+
+func (x *Hit) GetDenseScore() float32 {
+	if x != nil {
+		return x.DenseScore
+	}
+	return 0
+}
+
+func (x *Hit) GetKeywordScore() float32 {
+	if x != nil {
+		return x.KeywordScore
+	}
+	return 0
+}
+
+func (x *Hit) GetReranked() bool {
+	if x != nil {
+		return x.Reranked
+	}
+	return false
+}
+
+func (x *Hit) GetOriginalScore() float32 {
+	if x != nil {
+		return x.OriginalScore
+	}
+	return 0
+}
+
+func (x *Hit) GetRerankScore() float32 {
+	if x != nil {
+		return x.RerankScore
+	}
+	return 0
+}
+
+type AnswerRequest struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	Question string                 `protobuf:"bytes,1,opt,name=question,proto3" json:"question,omitempty"`
+	// Number of context passages; 0 keeps the configured top-k.
+	K             uint32         `protobuf:"varint,2,opt,name=k,proto3" json:"k,omitempty"`
+	Filter        *Filter        `protobuf:"bytes,3,opt,name=filter,proto3" json:"filter,omitempty"`
+	Options       *SearchOptions `protobuf:"bytes,4,opt,name=options,proto3" json:"options,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AnswerRequest) Reset() {
+	*x = AnswerRequest{}
+	mi := &file_api_ragger_v1_ragger_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AnswerRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AnswerRequest) ProtoMessage() {}
+
+func (x *AnswerRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_ragger_v1_ragger_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AnswerRequest.ProtoReflect.Descriptor instead.
+func (*AnswerRequest) Descriptor() ([]byte, []int) {
+	return file_api_ragger_v1_ragger_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *AnswerRequest) GetQuestion() string {
+	if x != nil {
+		return x.Question
+	}
+	return ""
+}
+
+func (x *AnswerRequest) GetK() uint32 {
+	if x != nil {
+		return x.K
+	}
+	return 0
+}
+
+func (x *AnswerRequest) GetFilter() *Filter {
+	if x != nil {
+		return x.Filter
+	}
+	return nil
+}
+
+func (x *AnswerRequest) GetOptions() *SearchOptions {
+	if x != nil {
+		return x.Options
+	}
+	return nil
+}
+
+type AnswerResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Types that are valid to be assigned to Event:
+	//
+	//	*AnswerResponse_Delta
+	//	*AnswerResponse_Done
+	Event         isAnswerResponse_Event `protobuf_oneof:"event"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AnswerResponse) Reset() {
+	*x = AnswerResponse{}
+	mi := &file_api_ragger_v1_ragger_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AnswerResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AnswerResponse) ProtoMessage() {}
+
+func (x *AnswerResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_ragger_v1_ragger_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AnswerResponse.ProtoReflect.Descriptor instead.
+func (*AnswerResponse) Descriptor() ([]byte, []int) {
+	return file_api_ragger_v1_ragger_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *AnswerResponse) GetEvent() isAnswerResponse_Event {
+	if x != nil {
+		return x.Event
+	}
+	return nil
+}
+
+func (x *AnswerResponse) GetDelta() string {
+	if x != nil {
+		if x, ok := x.Event.(*AnswerResponse_Delta); ok {
+			return x.Delta
+		}
+	}
+	return ""
+}
+
+func (x *AnswerResponse) GetDone() *AnswerDone {
+	if x != nil {
+		if x, ok := x.Event.(*AnswerResponse_Done); ok {
+			return x.Done
+		}
+	}
+	return nil
+}
+
+type isAnswerResponse_Event interface {
+	isAnswerResponse_Event()
+}
+
+type AnswerResponse_Delta struct {
+	// Next piece of the answer text.
+	Delta string `protobuf:"bytes,1,opt,name=delta,proto3,oneof"`
+}
+
+type AnswerResponse_Done struct {
+	// Last message of the stream.
+	Done *AnswerDone `protobuf:"bytes,2,opt,name=done,proto3,oneof"`
+}
+
+func (*AnswerResponse_Delta) isAnswerResponse_Event() {}
+
+func (*AnswerResponse_Done) isAnswerResponse_Event() {}
+
+type AnswerDone struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Context passages in prompt order; [n] citations refer to them.
+	Sources       []*Source `protobuf:"bytes,1,rep,name=sources,proto3" json:"sources,omitempty"`
+	Hits          []*Hit    `protobuf:"bytes,2,rep,name=hits,proto3" json:"hits,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AnswerDone) Reset() {
+	*x = AnswerDone{}
+	mi := &file_api_ragger_v1_ragger_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AnswerDone) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AnswerDone) ProtoMessage() {}
+
+func (x *AnswerDone) ProtoReflect() protoreflect.Message {
+	mi := &file_api_ragger_v1_ragger_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AnswerDone.ProtoReflect.Descriptor instead.
+func (*AnswerDone) Descriptor() ([]byte, []int) {
+	return file_api_ragger_v1_ragger_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *AnswerDone) GetSources() []*Source {
+	if x != nil {
+		return x.Sources
+	}
+	return nil
+}
+
+func (x *AnswerDone) GetHits() []*Hit {
+	if x != nil {
+		return x.Hits
+	}
+	return nil
+}
+
+type Source struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	N       uint32                 `protobuf:"varint,1,opt,name=n,proto3" json:"n,omitempty"`
+	Title   string                 `protobuf:"bytes,2,opt,name=title,proto3" json:"title,omitempty"`
+	Path    string                 `protobuf:"bytes,3,opt,name=path,proto3" json:"path,omitempty"`
+	DocId   string                 `protobuf:"bytes,4,opt,name=doc_id,json=docId,proto3" json:"doc_id,omitempty"`
+	ChunkId string                 `protobuf:"bytes,5,opt,name=chunk_id,json=chunkId,proto3" json:"chunk_id,omitempty"`
+	// Whether the answer cites [n].
+	Cited         bool `protobuf:"varint,6,opt,name=cited,proto3" json:"cited,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Source) Reset() {
+	*x = Source{}
+	mi := &file_api_ragger_v1_ragger_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Source) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Source) ProtoMessage() {}
+
+func (x *Source) ProtoReflect() protoreflect.Message {
+	mi := &file_api_ragger_v1_ragger_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Source.ProtoReflect.Descriptor instead.
+func (*Source) Descriptor() ([]byte, []int) {
+	return file_api_ragger_v1_ragger_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *Source) GetN() uint32 {
+	if x != nil {
+		return x.N
+	}
+	return 0
+}
+
+func (x *Source) GetTitle() string {
+	if x != nil {
+		return x.Title
+	}
+	return ""
+}
+
+func (x *Source) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+func (x *Source) GetDocId() string {
+	if x != nil {
+		return x.DocId
+	}
+	return ""
+}
+
+func (x *Source) GetChunkId() string {
+	if x != nil {
+		return x.ChunkId
+	}
+	return ""
+}
+
+func (x *Source) GetCited() bool {
+	if x != nil {
+		return x.Cited
+	}
+	return false
+}
+
+type IngestDocumentRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Types that are valid to be assigned to Part:
+	//
+	//	*IngestDocumentRequest_Info
+	//	*IngestDocumentRequest_Content
+	Part          isIngestDocumentRequest_Part `protobuf_oneof:"part"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *IngestDocumentRequest) Reset() {
+	*x = IngestDocumentRequest{}
+	mi := &file_api_ragger_v1_ragger_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *IngestDocumentRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*IngestDocumentRequest) ProtoMessage() {}
+
+func (x *IngestDocumentRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_ragger_v1_ragger_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use IngestDocumentRequest.ProtoReflect.Descriptor instead.
+func (*IngestDocumentRequest) Descriptor() ([]byte, []int) {
+	return file_api_ragger_v1_ragger_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *IngestDocumentRequest) GetPart() isIngestDocumentRequest_Part {
+	if x != nil {
+		return x.Part
+	}
+	return nil
+}
+
+func (x *IngestDocumentRequest) GetInfo() *DocumentInfo {
+	if x != nil {
+		if x, ok := x.Part.(*IngestDocumentRequest_Info); ok {
+			return x.Info
+		}
+	}
+	return nil
+}
+
+func (x *IngestDocumentRequest) GetContent() []byte {
+	if x != nil {
+		if x, ok := x.Part.(*IngestDocumentRequest_Content); ok {
+			return x.Content
+		}
+	}
+	return nil
+}
+
+type isIngestDocumentRequest_Part interface {
+	isIngestDocumentRequest_Part()
+}
+
+type IngestDocumentRequest_Info struct {
+	// First message.
+	Info *DocumentInfo `protobuf:"bytes,1,opt,name=info,proto3,oneof"`
+}
+
+type IngestDocumentRequest_Content struct {
+	// A piece of the HTML content.
+	Content []byte `protobuf:"bytes,2,opt,name=content,proto3,oneof"`
+}
+
+func (*IngestDocumentRequest_Info) isIngestDocumentRequest_Part() {}
+
+func (*IngestDocumentRequest_Content) isIngestDocumentRequest_Part() {}
+
+type DocumentInfo struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Source path of the document; it determines the doc_id.
+	Path          string `protobuf:"bytes,1,opt,name=path,proto3" json:"path,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DocumentInfo) Reset() {
+	*x = DocumentInfo{}
+	mi := &file_api_ragger_v1_ragger_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DocumentInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DocumentInfo) ProtoMessage() {}
+
+func (x *DocumentInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_api_ragger_v1_ragger_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DocumentInfo.ProtoReflect.Descriptor instead.
+func (*DocumentInfo) Descriptor() ([]byte, []int) {
+	return file_api_ragger_v1_ragger_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *DocumentInfo) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+type IngestDocumentResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	DocId string                 `protobuf:"bytes,1,opt,name=doc_id,json=docId,proto3" json:"doc_id,omitempty"`
+	Path  string                 `protobuf:"bytes,2,opt,name=path,proto3" json:"path,omitempty"`
+	Title string                 `protobuf:"bytes,3,opt,name=title,proto3" json:"title,omitempty"`
+	Lang  string                 `protobuf:"bytes,4,opt,name=lang,proto3" json:"lang,omitempty"`
+	// added | updated | unchanged | skipped
+	Status string `protobuf:"bytes,5,opt,name=status,proto3" json:"status,omitempty"`
+	Chunks uint32 `protobuf:"varint,6,opt,name=chunks,proto3" json:"chunks,omitempty"`
+	// Chunks an update no longer produces.
+	DeletedChunks uint32 `protobuf:"varint,7,opt,name=deleted_chunks,json=deletedChunks,proto3" json:"deleted_chunks,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *IngestDocumentResponse) Reset() {
+	*x = IngestDocumentResponse{}
+	mi := &file_api_ragger_v1_ragger_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *IngestDocumentResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*IngestDocumentResponse) ProtoMessage() {}
+
+func (x *IngestDocumentResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_ragger_v1_ragger_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use IngestDocumentResponse.ProtoReflect.Descriptor instead.
+func (*IngestDocumentResponse) Descriptor() ([]byte, []int) {
+	return file_api_ragger_v1_ragger_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *IngestDocumentResponse) GetDocId() string {
+	if x != nil {
+		return x.DocId
+	}
+	return ""
+}
+
+func (x *IngestDocumentResponse) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+func (x *IngestDocumentResponse) GetTitle() string {
+	if x != nil {
+		return x.Title
+	}
+	return ""
+}
+
+func (x *IngestDocumentResponse) GetLang() string {
+	if x != nil {
+		return x.Lang
+	}
+	return ""
+}
+
+func (x *IngestDocumentResponse) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *IngestDocumentResponse) GetChunks() uint32 {
+	if x != nil {
+		return x.Chunks
+	}
+	return 0
+}
+
+func (x *IngestDocumentResponse) GetDeletedChunks() uint32 {
+	if x != nil {
+		return x.DeletedChunks
+	}
+	return 0
+}
+
+var File_api_ragger_v1_ragger_proto protoreflect.FileDescriptor
+
+const file_api_ragger_v1_ragger_proto_rawDesc = "" +
+	"\n" +
+	"\x1aapi/ragger/v1/ragger.proto\x12\tragger.v1\"3\n" +
+	"\x06Filter\x12\x12\n" +
+	"\x04lang\x18\x01 \x01(\tR\x04lang\x12\x15\n" +
+	"\x06doc_id\x18\x02 \x01(\tR\x05docId\"\x86\x02\n" +
+	"\rSearchOptions\x12\x17\n" +
+	"\x04mode\x18\x01 \x01(\tH\x00R\x04mode\x88\x01\x01\x12\x1b\n" +
+	"\x06fusion\x18\x02 \x01(\tH\x01R\x06fusion\x88\x01\x01\x12\x1b\n" +
+	"\x06rerank\x18\x03 \x01(\bH\x02R\x06rerank\x88\x01\x01\x12\x15\n" +
+	"\x03mmr\x18\x04 \x01(\bH\x03R\x03mmr\x88\x01\x01\x12#\n" +
+	"\vmax_per_doc\x18\x05 \x01(\rH\x04R\tmaxPerDoc\x88\x01\x01\x12!\n" +
+	"\tneighbors\x18\x06 \x01(\rH\x05R\tneighbors\x88\x01\x01B\a\n" +
+	"\x05_modeB\t\n" +
+	"\a_fusionB\t\n" +
+	"\a_rerankB\x06\n" +
+	"\x04_mmrB\x0e\n" +
+	"\f_max_per_docB\f\n" +
+	"\n" +
+	"_neighbors\"\x92\x01\n" +
+	"\rSearchRequest\x12\x14\n" +
+	"\x05query\x18\x01 \x01(\tR\x05query\x12\f\n" +
+	"\x01k\x18\x02 \x01(\rR\x01k\x12)\n" +
+	"\x06filter\x18\x03 \x01(\v2\x11.ragger.v1.FilterR\x06filter\x122\n" +
+	"\aoptions\x18\x04 \x01(\v2\x18.ragger.v1.SearchOptionsR\aoptions\"M\n" +
+	"\x0eSearchResponse\x12\"\n" +
+	"\x04hits\x18\x01 \x03(\v2\x0e.ragger.v1.HitR\x04hits\x12\x17\n" +
+	"\atook_ms\x18\x02 \x01(\rR\x06tookMs\"\xa0\x03\n" +
+	"\x03Hit\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x14\n" +
+	"\x05score\x18\x02 \x01(\x02R\x05score\x12\x14\n" +
+	"\x05title\x18\x03 \x01(\tR\x05title\x12\x12\n" +
+	"\x04text\x18\x04 \x01(\tR\x04text\x12\x15\n" +
+	"\x06doc_id\x18\x05 \x01(\tR\x05docId\x12\x19\n" +
+	"\bchunk_id\x18\x06 \x01(\tR\achunkId\x12\x12\n" +
+	"\x04path\x18\a \x01(\tR\x04path\x12\x12\n" +
+	"\x04lang\x18\b \x01(\tR\x04lang\x12\x14\n" +
+	"\x05start\x18\t \x01(\x05R\x05start\x12\x10\n" +
+	"\x03end\x18\n" +
+	" \x01(\x05R\x03end\x12\x1b\n" +
+	"\tchunk_ids\x18\v \x03(\tR\bchunkIds\x12\x1f\n" +
+	"\vdense_score\x18\f \x01(\x02R\n" +
+	"denseScore\x12#\n" +
+	"\rkeyword_score\x18\r \x01(\x02R\fkeywordScore\x12\x1a\n" +
+	"\breranked\x18\x0e \x01(\bR\breranked\x12%\n" +
+	"\x0eoriginal_score\x18\x0f \x01(\x02R\roriginalScore\x12!\n" +
+	"\frerank_score\x18\x10 \x01(\x02R\vrerankScore\"\x98\x01\n" +
+	"\rAnswerRequest\x12\x1a\n" +
+	"\bquestion\x18\x01 \x01(\tR\bquestion\x12\f\n" +
+	"\x01k\x18\x02 \x01(\rR\x01k\x12)\n" +
+	"\x06filter\x18\x03 \x01(\v2\x11.ragger.v1.FilterR\x06filter\x122\n" +
+	"\aoptions\x18\x04 \x01(\v2\x18.ragger.v1.SearchOptionsR\aoptions\"^\n" +
+	"\x0eAnswerResponse\x12\x16\n" +
+	"\x05delta\x18\x01 \x01(\tH\x00R\x05delta\x12+\n" +
+	"\x04done\x18\x02 \x01(\v2\x15.ragger.v1.AnswerDoneH\x00R\x04doneB\a\n" +
+	"\x05event\"]\n" +
+	"\n" +
+	"AnswerDone\x12+\n" +
+	"\asources\x18\x01 \x03(\v2\x11.ragger.v1.SourceR\asources\x12\"\n" +
+	"\x04hits\x18\x02 \x03(\v2\x0e.ragger.v1.HitR\x04hits\"\x88\x01\n" +
+	"\x06Source\x12\f\n" +
+	"\x01n\x18\x01 \x01(\rR\x01n\x12\x14\n" +
+	"\x05title\x18\x02 \x01(\tR\x05title\x12\x12\n" +
+	"\x04path\x18\x03 \x01(\tR\x04path\x12\x15\n" +
+	"\x06doc_id\x18\x04 \x01(\tR\x05docId\x12\x19\n" +
+	"\bchunk_id\x18\x05 \x01(\tR\achunkId\x12\x14\n" +
+	"\x05cited\x18\x06 \x01(\bR\x05cited\"j\n" +
+	"\x15IngestDocumentRequest\x12-\n" +
+	"\x04info\x18\x01 \x01(\v2\x17.ragger.v1.DocumentInfoH\x00R\x04info\x12\x1a\n" +
+	"\acontent\x18\x02 \x01(\fH\x00R\acontentB\x06\n" +
+	"\x04part\"\"\n" +
+	"\fDocumentInfo\x12\x12\n" +
+	"\x04path\x18\x01 \x01(\tR\x04path\"\xc4\x01\n" +
+	"\x16IngestDocumentResponse\x12\x15\n" +
+	"\x06doc_id\x18\x01 \x01(\tR\x05docId\x12\x12\n" +
+	"\x04path\x18\x02 \x01(\tR\x04path\x12\x14\n" +
+	"\x05title\x18\x03 \x01(\tR\x05title\x12\x12\n" +
+	"\x04lang\x18\x04 \x01(\tR\x04lang\x12\x16\n" +
+	"\x06status\x18\x05 \x01(\tR\x06status\x12\x16\n" +
+	"\x06chunks\x18\x06 \x01(\rR\x06chunks\x12%\n" +
+	"\x0edeleted_chunks\x18\a \x01(\rR\rdeletedChunks2\xe1\x01\n" +
+	"\x06Ragger\x12=\n" +
+	"\x06Search\x12\x18.ragger.v1.SearchRequest\x1a\x19.ragger.v1.SearchResponse\x12?\n" +
+	"\x06Answer\x12\x18.ragger.v1.AnswerRequest\x1a\x19.ragger.v1.AnswerResponse0\x01\x12W\n" +
+	"\x0eIngestDocument\x12 .ragger.v1.IngestDocumentRequest\x1a!.ragger.v1.IngestDocumentResponse(\x01B$Z\"test-ragger/api/ragger/v1;raggerv1b\x06proto3"
+
+var (
+	file_api_ragger_v1_ragger_proto_rawDescOnce sync.Once
+	file_api_ragger_v1_ragger_proto_rawDescData []byte
+)
+
+func file_api_ragger_v1_ragger_proto_rawDescGZIP() []byte {
+	file_api_ragger_v1_ragger_proto_rawDescOnce.Do(func() {
+		file_api_ragger_v1_ragger_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_api_ragger_v1_ragger_proto_rawDesc), len(file_api_ragger_v1_ragger_proto_rawDesc)))
+	})
+	return file_api_ragger_v1_ragger_proto_rawDescData
+}
+
+var file_api_ragger_v1_ragger_proto_msgTypes = make([]protoimpl.MessageInfo, 12)
+var file_api_ragger_v1_ragger_proto_goTypes = []any{
+	(*Filter)(nil),                 // 0: ragger.v1.Filter
+	(*SearchOptions)(nil),          // 1: ragger.v1.SearchOptions
+	(*SearchRequest)(nil),          // 2: ragger.v1.SearchRequest
+	(*SearchResponse)(nil),         // 3: ragger.v1.SearchResponse
+	(*Hit)(nil),                    // 4: ragger.v1.Hit
+	(*AnswerRequest)(nil),          // 5: ragger.v1.AnswerRequest
+	(*AnswerResponse)(nil),         // 6: ragger.v1.AnswerResponse
+	(*AnswerDone)(nil),             // 7: ragger.v1.AnswerDone
+	(*Source)(nil),                 // 8: ragger.v1.Source
+	(*IngestDocumentRequest)(nil),  // 9: ragger.v1.IngestDocumentRequest
+	(*DocumentInfo)(nil),           // 10: ragger.v1.DocumentInfo
+	(*IngestDocumentResponse)(nil), // 11: ragger.v1.IngestDocumentResponse
+}
+var file_api_ragger_v1_ragger_proto_depIdxs = []int32{
+	0,  // 0: ragger.v1.SearchRequest.filter:type_name -> ragger.v1.Filter
+	1,  // 1: ragger.v1.SearchRequest.options:type_name -> ragger.v1.SearchOptions
+	4,  // 2: ragger.v1.SearchResponse.hits:type_name -> ragger.v1.Hit
+	0,  // 3: ragger.v1.AnswerRequest.filter:type_name -> ragger.v1.Filter
+	1,  // 4: ragger.v1.AnswerRequest.options:type_name -> ragger.v1.SearchOptions
+	7,  // 5: ragger.v1.AnswerResponse.done:type_name -> ragger.v1.AnswerDone
+	8,  // 6: ragger.v1.AnswerDone.sources:type_name -> ragger.v1.Source
+	4,  // 7: ragger.v1.AnswerDone.hits:type_name -> ragger.v1.Hit
+	10, // 8: ragger.v1.IngestDocumentRequest.info:type_name -> ragger.v1.DocumentInfo
+	2,  // 9: ragger.v1.Ragger.Search:input_type -> ragger.v1.SearchRequest
+	5,  // 10: ragger.v1.Ragger.Answer:input_type -> ragger.v1.AnswerRequest
+	9,  // 11: ragger.v1.Ragger.IngestDocument:input_type -> ragger.v1.IngestDocumentRequest
+	3,  // 12: ragger.v1.Ragger.Search:output_type -> ragger.v1.SearchResponse
+	6,  // 13: ragger.v1.Ragger.Answer:output_type -> ragger.v1.AnswerResponse
+	11, // 14: ragger.v1.Ragger.IngestDocument:output_type -> ragger.v1.IngestDocumentResponse
+	12, // [12:15] is the sub-list for method output_type
+	9,  // [9:12] is the sub-list for method input_type
+	9,  // [9:9] is the sub-list for extension type_name
+	9,  // [9:9] is the sub-list for extension extendee
+	0,  // [0:9] is the sub-list for field type_name
+}
+
+func init() { file_api_ragger_v1_ragger_proto_init() }
+func file_api_ragger_v1_ragger_proto_init() {
+	if File_api_ragger_v1_ragger_proto != nil {
+		return
+	}
+	file_api_ragger_v1_ragger_proto_msgTypes[1].OneofWrappers = []any{}
+	file_api_ragger_v1_ragger_proto_msgTypes[6].OneofWrappers = []any{
+		(*AnswerResponse_Delta)(nil),
+		(*AnswerResponse_Done)(nil),
+	}
+	file_api_ragger_v1_ragger_proto_msgTypes[9].OneofWrappers = []any{
+		(*IngestDocumentRequest_Info)(nil),
+		(*IngestDocumentRequest_Content)(nil),
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_api_ragger_v1_ragger_proto_rawDesc), len(file_api_ragger_v1_ragger_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   12,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_api_ragger_v1_ragger_proto_goTypes,
+		DependencyIndexes: file_api_ragger_v1_ragger_proto_depIdxs,
+		MessageInfos:      file_api_ragger_v1_ragger_proto_msgTypes,
+	}.Build()
+	File_api_ragger_v1_ragger_proto = out.File
+	file_api_ragger_v1_ragger_proto_goTypes = nil
+	file_api_ragger_v1_ragger_proto_depIdxs = nil
+}
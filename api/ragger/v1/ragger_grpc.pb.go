@@ -0,0 +1,210 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: api/ragger/v1/ragger.proto
+
+package raggerv1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	Ragger_Search_FullMethodName         = "/ragger.v1.Ragger/Search"
+	Ragger_Answer_FullMethodName         = "/ragger.v1.Ragger/Answer"
+	Ragger_IngestDocument_FullMethodName = "/ragger.v1.Ragger/IngestDocument"
+)
+
+// RaggerClient is the client API for Ragger service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// Ragger searches the indexed HTML documentation, answers questions
+// grounded on it and ingests new documents.
+type RaggerClient interface {
+	// Search returns the best matching chunks.
+	Search(ctx context.Context, in *SearchRequest, opts ...grpc.CallOption) (*SearchResponse, error)
+	// Answer streams a chat model answer grounded on search results: text
+	// deltas as they are generated, then one final message with the sources.
+	Answer(ctx context.Context, in *AnswerRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[AnswerResponse], error)
+	// IngestDocument ingests one HTML document: the first message carries
+	// its info, the following ones its content in pieces.
+	IngestDocument(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[IngestDocumentRequest, IngestDocumentResponse], error)
+}
+
+type raggerClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewRaggerClient(cc grpc.ClientConnInterface) RaggerClient {
+	return &raggerClient{cc}
+}
+
+func (c *raggerClient) Search(ctx context.Context, in *SearchRequest, opts ...grpc.CallOption) (*SearchResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SearchResponse)
+	err := c.cc.Invoke(ctx, Ragger_Search_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *raggerClient) Answer(ctx context.Context, in *AnswerRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[AnswerResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Ragger_ServiceDesc.Streams[0], Ragger_Answer_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[AnswerRequest, AnswerResponse]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Ragger_AnswerClient = grpc.ServerStreamingClient[AnswerResponse]
+
+func (c *raggerClient) IngestDocument(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[IngestDocumentRequest, IngestDocumentResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Ragger_ServiceDesc.Streams[1], Ragger_IngestDocument_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[IngestDocumentRequest, IngestDocumentResponse]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Ragger_IngestDocumentClient = grpc.ClientStreamingClient[IngestDocumentRequest, IngestDocumentResponse]
+
+// RaggerServer is the server API for Ragger service.
+// All implementations must embed UnimplementedRaggerServer
+// for forward compatibility.
+//
+// Ragger searches the indexed HTML documentation, answers questions
+// grounded on it and ingests new documents.
+type RaggerServer interface {
+	// Search returns the best matching chunks.
+	Search(context.Context, *SearchRequest) (*SearchResponse, error)
+	// Answer streams a chat model answer grounded on search results: text
+	// deltas as they are generated, then one final message with the sources.
+	Answer(*AnswerRequest, grpc.ServerStreamingServer[AnswerResponse]) error
+	// IngestDocument ingests one HTML document: the first message carries
+	// its info, the following ones its content in pieces.
+	IngestDocument(grpc.ClientStreamingServer[IngestDocumentRequest, IngestDocumentResponse]) error
+	mustEmbedUnimplementedRaggerServer()
+}
+
+// UnimplementedRaggerServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedRaggerServer struct{}
+
+func (UnimplementedRaggerServer) Search(context.Context, *SearchRequest) (*SearchResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Search not implemented")
+}
+func (UnimplementedRaggerServer) Answer(*AnswerRequest, grpc.ServerStreamingServer[AnswerResponse]) error {
+	return status.Errorf(codes.Unimplemented, "method Answer not implemented")
+}
+func (UnimplementedRaggerServer) IngestDocument(grpc.ClientStreamingServer[IngestDocumentRequest, IngestDocumentResponse]) error {
+	return status.Errorf(codes.Unimplemented, "method IngestDocument not implemented")
+}
+func (UnimplementedRaggerServer) mustEmbedUnimplementedRaggerServer() {}
+func (UnimplementedRaggerServer) testEmbeddedByValue()                {}
+
+// UnsafeRaggerServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to RaggerServer will
+// result in compilation errors.
+type UnsafeRaggerServer interface {
+	mustEmbedUnimplementedRaggerServer()
+}
+
+func RegisterRaggerServer(s grpc.ServiceRegistrar, srv RaggerServer) {
+	// If the following call pancis, it indicates UnimplementedRaggerServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&Ragger_ServiceDesc, srv)
+}
+
+func _Ragger_Search_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SearchRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RaggerServer).Search(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Ragger_Search_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RaggerServer).Search(ctx, req.(*SearchRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Ragger_Answer_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(AnswerRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(RaggerServer).Answer(m, &grpc.GenericServerStream[AnswerRequest, AnswerResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Ragger_AnswerServer = grpc.ServerStreamingServer[AnswerResponse]
+
+func _Ragger_IngestDocument_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(RaggerServer).IngestDocument(&grpc.GenericServerStream[IngestDocumentRequest, IngestDocumentResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Ragger_IngestDocumentServer = grpc.ClientStreamingServer[IngestDocumentRequest, IngestDocumentResponse]
+
+// Ragger_ServiceDesc is the grpc.ServiceDesc for Ragger service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Ragger_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "ragger.v1.Ragger",
+	HandlerType: (*RaggerServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Search",
+			Handler:    _Ragger_Search_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Answer",
+			Handler:       _Ragger_Answer_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "IngestDocument",
+			Handler:       _Ragger_IngestDocument_Handler,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "api/ragger/v1/ragger.proto",
+}
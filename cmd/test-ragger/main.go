@@ -6,20 +6,32 @@ import (
 	"log"
 	"log/slog"
 	"os"
+	"os/signal"
+	"sort"
+	"syscall"
+	"time"
 
 	"github.com/joho/godotenv"
 	openai "github.com/sashabaranov/go-openai"
 
 	"test-ragger/internal/configure"
 	"test-ragger/internal/configure/config"
+	"test-ragger/internal/delivery/mcp"
+	"test-ragger/internal/delivery/rest"
+	"test-ragger/internal/delivery/rpc"
+	"test-ragger/internal/models"
+	"test-ragger/internal/usecase/answer"
 	"test-ragger/internal/usecase/ingest"
+	"test-ragger/internal/usecase/migrate"
 	"test-ragger/internal/usecase/search"
-	"test-ragger/internal/utils"
+	"test-ragger/internal/utils/embedcache"
 )
 
 func main() {
+	started := time.Now()
 	_ = godotenv.Load()
-	ctx := context.Background()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
 
 	// Setup structured logging
 	logLevel := slog.LevelInfo
@@ -36,11 +48,23 @@ func main() {
 		}
 	}
 
-	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
+	// Logs go to stderr: stdout carries results (and the MCP protocol)
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{
 		Level: logLevel,
 	}))
 	slog.SetDefault(logger)
 
+	// First Ctrl-C asks for graceful shutdown (ingest flushes in-flight
+	// batches), the second one terminates the process immediately
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-sigCh
+		signal.Stop(sigCh)
+		slog.Warn("Interrupt received, finishing in-flight work; press Ctrl-C again to force quit")
+		cancel()
+	}()
+
 	slog.Info("Starting test-ragger application")
 	slog.Debug("Log level set", "level", logLevel.String())
 
@@ -55,26 +79,36 @@ func main() {
 	cfg := container.Config
 	ctx = config.IntoContext(ctx, cfg)
 
-	slog.Info("Configuration loaded successfully", "mode", cfg.Mode, "collection", cfg.Collection)
-	slog.Info("Initialized OpenAI client", "model", cfg.Model)
-	slog.Info("Connected to Qdrant", "endpoint", cfg.QdrantGRPC)
+	// the cache container has neither a provider nor a vector store
+	if cfg.Mode == "cache" {
+		if container.EmbeddingCache == nil {
+			log.Fatal("embedding cache is disabled in config")
+		}
+		if err := cacheCommand(container.EmbeddingCache, cfg.Args); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
 
-	var model openai.EmbeddingModel
-	switch cfg.Model {
-	case "text-embedding-3-small", "text-embedding-3-large":
-		model = openai.EmbeddingModel(cfg.Model)
-	default:
-		log.Fatalf("unknown model: %s", cfg.Model)
+	slog.Info("Configuration loaded successfully", "mode", cfg.Mode, "collection", cfg.Collection)
+	slog.Info("Initialized embedding provider", "provider", cfg.Embedding.Provider, "model", cfg.Model, "dim", cfg.EmbeddingDim)
+	if cfg.Store.Backend == "local" {
+		slog.Info("Using local vector store", "path", cfg.StorePath())
+	} else {
+		slog.Info("Connected to Qdrant", "endpoint", cfg.QdrantGRPC)
 	}
 
+	// the model is validated against the provider registry by the container
+	model := openai.EmbeddingModel(cfg.Model)
+
 	switch cfg.Mode {
 	case "ingest":
 		slog.Info("Starting ingest mode", "html_dir", cfg.HTMLDir)
 		ingestUC := ingest.New(
 			container.IngestEmbeddingClient,
-			container.IngestQdrantCollectionClient,
-			container.IngestQdrantPointsClient,
+			container.IngestVectorStore,
 			container.IngestHTMLParser,
+			container.IngestLangDetector,
 			container.IngestTextChunker,
 		)
 		if err := ingestUC.Run(ctx, cfg.HTMLDir, model); err != nil {
@@ -87,27 +121,216 @@ func main() {
 		if cfg.Query == "" {
 			log.Fatal("-q is required in search mode")
 		}
-		slog.Info("Starting search mode", "query", cfg.Query, "top_k", cfg.TopK)
+		if !validOutput(cfg.Output) {
+			log.Fatalf("unknown output format: %s (want text|json|jsonl|markdown|csv)", cfg.Output)
+		}
+		slog.Info("Starting search mode", "query", cfg.Query, "top_k", cfg.TopK, "search_mode", cfg.Search.Mode)
 		searchUC := search.New(
 			container.SearchEmbeddingClient,
-			container.SearchQdrantPointsClient,
+			container.SearchVectorStore,
+			container.SearchKeywordIndex,
+			container.SearchReranker,
 			container.SearchPromptBuilder,
 		)
-		slog.Info("Creating embedding for search query")
-		hits, err := searchUC.Search(ctx, cfg.Query, cfg.TopK, model, cfg.Lang)
-		if err != nil {
-			log.Fatal(err)
+		opts := search.OptionsFromConfig(cfg)
+		result := searchResult{
+			Query:  cfg.Query,
+			Mode:   cfg.Search.Mode,
+			TopK:   cfg.TopK,
+			Lang:   cfg.Lang,
+			Timing: timing{StartedAt: started.UTC().Format(time.RFC3339Nano)},
+		}
+		var hits []models.Hit
+		searchStart := time.Now()
+		if cfg.Search.Group {
+			docs, err := searchUC.SearchDocuments(ctx, cfg.Query, model, opts)
+			if err != nil {
+				log.Fatal(err)
+			}
+			slog.Info("Search completed", "documents_count", len(docs))
+			result.Documents = append([]models.DocumentHit{}, docs...)
+			for _, d := range docs {
+				hits = append(hits, d.Chunks...)
+			}
+		} else {
+			var err error
+			hits, err = searchUC.Search(ctx, cfg.Query, model, opts)
+			if err != nil {
+				log.Fatal(err)
+			}
+			slog.Info("Search completed", "results_count", len(hits))
+			result.Hits = append([]models.Hit{}, hits...)
 		}
-		slog.Info("Search completed", "results_count", len(hits))
+		result.Timing.SearchMs = millis(time.Since(searchStart))
 
-		fmt.Printf("Query: %s\nTop-%d results:\n", cfg.Query, cfg.TopK)
-		for i, h := range hits {
-			fmt.Printf("#%d score=%.4f %s\n%s\npath=%s\n---\n", i+1, h.Score, h.Title, utils.Snippet(h.Text, 280), h.Path)
+		promptStart := time.Now()
+		result.Prompt = searchUC.BuildPrompt(cfg.Query, hits)
+		result.Timing.PromptMs = millis(time.Since(promptStart))
+		result.Timing.TotalMs = millis(time.Since(started))
+
+		if err := writeSearchResult(os.Stdout, cfg.Output, result); err != nil {
+			log.Fatal(err)
 		}
+	case "answer":
+		if cfg.Query == "" {
+			log.Fatal("-q is required in answer mode")
+		}
+		slog.Info("Starting answer mode", "query", cfg.Query, "top_k", cfg.TopK, "model", cfg.Answer.Model)
+		searchUC := search.New(
+			container.SearchEmbeddingClient,
+			container.SearchVectorStore,
+			container.SearchKeywordIndex,
+			container.SearchReranker,
+			container.SearchPromptBuilder,
+		)
+		answerUC := answer.New(searchUC, container.AnswerChatClient)
 
-		fmt.Println("\n--- PROMPT ---")
-		fmt.Println(searchUC.BuildPrompt(cfg.Query, hits))
+		fmt.Printf("Question: %s\n\n", cfg.Query)
+		ans, err := answerUC.Answer(ctx, cfg.Query, model, answer.OptionsFromConfig(cfg), func(delta string) error {
+			_, err := fmt.Print(delta)
+			return err
+		})
+		fmt.Println()
+		if err != nil {
+			log.Fatal(err)
+		}
+		printSources(ans.Sources)
+	case "serve":
+		slog.Info("Starting serve mode", "addr", cfg.Server.Addr)
+		searchUC := search.New(
+			container.SearchEmbeddingClient,
+			container.SearchVectorStore,
+			container.SearchKeywordIndex,
+			container.SearchReranker,
+			container.SearchPromptBuilder,
+		)
+		var answerer rest.Answerer
+		if container.AnswerChatClient != nil {
+			answerer = answer.New(searchUC, container.AnswerChatClient)
+		} else {
+			slog.Warn("Answer chat model is not configured, /v1/answer is disabled")
+		}
+		ingestUC := ingest.New(
+			container.IngestEmbeddingClient,
+			container.IngestVectorStore,
+			container.IngestHTMLParser,
+			container.IngestLangDetector,
+			container.IngestTextChunker,
+		)
+		if err := rest.New(searchUC, answerer, ingestUC).Run(ctx); err != nil {
+			slog.Error("HTTP server stopped with error", "error", err)
+			os.Exit(1)
+		}
+	case "grpc":
+		slog.Info("Starting gRPC mode", "addr", cfg.Server.GRPCAddr)
+		searchUC := search.New(
+			container.SearchEmbeddingClient,
+			container.SearchVectorStore,
+			container.SearchKeywordIndex,
+			container.SearchReranker,
+			container.SearchPromptBuilder,
+		)
+		var answerer rpc.Answerer
+		if container.AnswerChatClient != nil {
+			answerer = answer.New(searchUC, container.AnswerChatClient)
+		} else {
+			slog.Warn("Answer chat model is not configured, Answer is disabled")
+		}
+		ingestUC := ingest.New(
+			container.IngestEmbeddingClient,
+			container.IngestVectorStore,
+			container.IngestHTMLParser,
+			container.IngestLangDetector,
+			container.IngestTextChunker,
+		)
+		if err := rpc.New(searchUC, answerer, ingestUC).Run(ctx); err != nil {
+			slog.Error("gRPC server stopped with error", "error", err)
+			os.Exit(1)
+		}
+	case "mcp":
+		searchUC := search.New(
+			container.SearchEmbeddingClient,
+			container.SearchVectorStore,
+			container.SearchKeywordIndex,
+			container.SearchReranker,
+			container.SearchPromptBuilder,
+		)
+		if err := mcp.New(searchUC).Run(ctx, os.Stdin, os.Stdout); err != nil {
+			slog.Error("MCP server stopped with error", "error", err)
+			os.Exit(1)
+		}
+	case "migrate-ids":
+		slog.Info("Starting point ID migration", "collection", cfg.Collection, "dry_run", cfg.DryRun)
+		migrateUC := migrate.New(container.MigrateVectorStore)
+		if err := migrateUC.MigratePointIDs(ctx); err != nil {
+			slog.Error("Migration stopped with error", "error", err)
+			os.Exit(1)
+		}
 	default:
 		log.Fatalf("unknown mode: %s", cfg.Mode)
 	}
 }
+
+// cacheCommand runs `-mode=cache stats|purge`
+func cacheCommand(cache *embedcache.Cache, args []string) error {
+	cmd := "stats"
+	if len(args) > 0 {
+		cmd = args[0]
+	}
+
+	switch cmd {
+	case "stats":
+		st := cache.Stats()
+		fmt.Printf("Embedding cache: %s\n", st.Path)
+		fmt.Printf("entries=%d (max %d)\n", st.Entries, st.MaxEntries)
+		fmt.Printf("size=%.1f MiB (max %.1f MiB), file=%.1f MiB\n",
+			float64(st.Bytes)/(1<<20), float64(st.MaxBytes)/(1<<20), float64(st.FileBytes)/(1<<20))
+		names := make([]string, 0, len(st.Models))
+		for m := range st.Models {
+			names = append(names, m)
+		}
+		sort.Strings(names)
+		for _, m := range names {
+			fmt.Printf("  %s: %d\n", m, st.Models[m])
+		}
+	case "purge":
+		entries := cache.Stats().Entries
+		if err := cache.Purge(); err != nil {
+			return fmt.Errorf("purge cache: %w", err)
+		}
+		fmt.Printf("Purged %d cached embeddings\n", entries)
+	default:
+		return fmt.Errorf("unknown cache command: %s (want stats|purge)", cmd)
+	}
+	return nil
+}
+
+// printSources lists the sources cited by an answer, or every context
+// passage when the answer cites none
+func printSources(sources []models.Source) {
+	if len(sources) == 0 {
+		return
+	}
+	cited := make([]models.Source, 0, len(sources))
+	for _, s := range sources {
+		if s.Cited {
+			cited = append(cited, s)
+		}
+	}
+	fmt.Println("\n--- SOURCES ---")
+	if len(cited) == 0 {
+		fmt.Println("(the answer cites no sources; context passages:)")
+		cited = sources
+	}
+	for _, s := range cited {
+		fmt.Printf("[%d] %s — %s (%s)\n", s.N, s.Title, s.Path, s.ChunkID)
+	}
+}
+
+// hitScore formats the score of a hit, with both scores after reranking
+func hitScore(h models.Hit) string {
+	if h.Reranked {
+		return fmt.Sprintf("rerank=%.4f original=%.4f", h.RerankScore, h.OriginalScore)
+	}
+	return fmt.Sprintf("score=%.4f", h.Score)
+}
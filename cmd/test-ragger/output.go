@@ -0,0 +1,232 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"test-ragger/internal/models"
+	"test-ragger/internal/utils"
+)
+
+// Output formats of search mode
+const (
+	outputText     = "text"
+	outputJSON     = "json"
+	outputJSONL    = "jsonl"
+	outputMarkdown = "markdown"
+	outputCSV      = "csv"
+)
+
+// searchResult is everything search mode prints. Documents is set
+// instead of Hits by grouped search.
+type searchResult struct {
+	Query     string               `json:"query"`
+	Mode      string               `json:"mode"`
+	TopK      uint64               `json:"k"`
+	Lang      string               `json:"lang,omitempty"`
+	Hits      []models.Hit         `json:"hits,omitempty"`
+	Documents []models.DocumentHit `json:"documents,omitempty"`
+	Prompt    string               `json:"prompt"`
+	Timing    timing               `json:"timing"`
+}
+
+// timing of a search run in milliseconds; total includes startup
+type timing struct {
+	StartedAt string  `json:"started_at"`
+	SearchMs  float64 `json:"search_ms"`
+	PromptMs  float64 `json:"prompt_ms"`
+	TotalMs   float64 `json:"total_ms"`
+}
+
+// hitRecord is a JSONL line of a hit; Document is the rank of its
+// document in grouped search
+type hitRecord struct {
+	Type     string `json:"type"`
+	Rank     int    `json:"rank"`
+	Document int    `json:"document,omitempty"`
+	models.Hit
+}
+
+// summaryRecord is the last JSONL line
+type summaryRecord struct {
+	Type   string `json:"type"`
+	Query  string `json:"query"`
+	Mode   string `json:"mode"`
+	TopK   uint64 `json:"k"`
+	Lang   string `json:"lang,omitempty"`
+	Hits   int    `json:"hits"`
+	Prompt string `json:"prompt"`
+	Timing timing `json:"timing"`
+}
+
+func validOutput(format string) bool {
+	switch format {
+	case outputText, outputJSON, outputJSONL, outputMarkdown, outputCSV:
+		return true
+	}
+	return false
+}
+
+func millis(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+// writeSearchResult prints r in the given format. JSONL has a line per
+// hit and a summary line; CSV has hits only.
+func writeSearchResult(w io.Writer, format string, r searchResult) error {
+	switch format {
+	case outputText:
+		return writeText(w, r)
+	case outputJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(r)
+	case outputJSONL:
+		return writeJSONL(w, r)
+	case outputMarkdown:
+		return writeMarkdown(w, r)
+	case outputCSV:
+		return writeCSV(w, r)
+	}
+	return fmt.Errorf("unknown output format: %s (want text|json|jsonl|markdown|csv)", format)
+}
+
+// rankedHit is a hit with its rank and, in grouped search, the rank of its document
+type rankedHit struct {
+	rank, document int
+	hit            models.Hit
+}
+
+func (r searchResult) ranked() []rankedHit {
+	var out []rankedHit
+	if r.Documents == nil {
+		for i, h := range r.Hits {
+			out = append(out, rankedHit{rank: i + 1, hit: h})
+		}
+		return out
+	}
+	for i, d := range r.Documents {
+		for _, h := range d.Chunks {
+			out = append(out, rankedHit{rank: len(out) + 1, document: i + 1, hit: h})
+		}
+	}
+	return out
+}
+
+func writeText(w io.Writer, r searchResult) error {
+	if r.Documents != nil {
+		fmt.Fprintf(w, "Query: %s\nTop-%d documents:\n", r.Query, r.TopK)
+		for i, d := range r.Documents {
+			fmt.Fprintf(w, "#%d score=%.4f %s\npath=%s matching_chunks=%d\n", i+1, d.Score, d.Title, d.Path, d.Matches)
+			for _, h := range d.Chunks {
+				fmt.Fprintf(w, "  - %s %s: %s\n", hitScore(h), h.ChunkID, utils.Snippet(h.Text, 200))
+			}
+			fmt.Fprintln(w, "---")
+		}
+	} else {
+		fmt.Fprintf(w, "Query: %s\nTop-%d results:\n", r.Query, r.TopK)
+		for i, h := range r.Hits {
+			fmt.Fprintf(w, "#%d %s %s\n%s\npath=%s\n---\n", i+1, hitScore(h), h.Title, utils.Snippet(h.Text, 280), h.Path)
+		}
+	}
+	fmt.Fprintln(w, "\n--- PROMPT ---")
+	_, err := fmt.Fprintln(w, r.Prompt)
+	return err
+}
+
+func writeJSONL(w io.Writer, r searchResult) error {
+	enc := json.NewEncoder(w)
+	hits := r.ranked()
+	for _, rh := range hits {
+		if err := enc.Encode(hitRecord{Type: "hit", Rank: rh.rank, Document: rh.document, Hit: rh.hit}); err != nil {
+			return err
+		}
+	}
+	return enc.Encode(summaryRecord{
+		Type:   "summary",
+		Query:  r.Query,
+		Mode:   r.Mode,
+		TopK:   r.TopK,
+		Lang:   r.Lang,
+		Hits:   len(hits),
+		Prompt: r.Prompt,
+		Timing: r.Timing,
+	})
+}
+
+func writeMarkdown(w io.Writer, r searchResult) error {
+	fmt.Fprintf(w, "# Search: %s\n\n", r.Query)
+	fmt.Fprintf(w, "mode `%s`, k %d", r.Mode, r.TopK)
+	if r.Lang != "" {
+		fmt.Fprintf(w, ", lang `%s`", r.Lang)
+	}
+	fmt.Fprintf(w, ", search %.1f ms, total %.1f ms\n", r.Timing.SearchMs, r.Timing.TotalMs)
+
+	writeHit := func(heading string, rank int, h models.Hit) {
+		fmt.Fprintf(w, "\n%s %d. %s\n\n", heading, rank, h.Title)
+		fmt.Fprintf(w, "- path: `%s`\n- doc_id: `%s`, chunk_id: `%s`, offsets: %d–%d\n", h.Path, h.DocID, h.ChunkID, h.Start, h.End)
+		if h.Lang != "" {
+			fmt.Fprintf(w, "- lang: %s\n", h.Lang)
+		}
+		fmt.Fprintf(w, "- %s\n\n", hitScore(h))
+		for _, line := range strings.Split(h.Text, "\n") {
+			fmt.Fprintf(w, "> %s\n", line)
+		}
+	}
+	if r.Documents != nil {
+		for i, d := range r.Documents {
+			fmt.Fprintf(w, "\n## %d. %s\n\n`%s` — score %.4f, %d matching chunks\n", i+1, d.Title, d.Path, d.Score, d.Matches)
+			for j, h := range d.Chunks {
+				writeHit("###", j+1, h)
+			}
+		}
+	} else {
+		for i, h := range r.Hits {
+			writeHit("##", i+1, h)
+		}
+	}
+
+	fence := "```"
+	for strings.Contains(r.Prompt, fence) {
+		fence += "`"
+	}
+	_, err := fmt.Fprintf(w, "\n## Prompt\n\n%s\n%s\n%s\n", fence, r.Prompt, fence)
+	return err
+}
+
+func writeCSV(w io.Writer, r searchResult) error {
+	cw := csv.NewWriter(w)
+	header := []string{"rank", "document", "score", "title", "path", "doc_id", "chunk_id", "lang", "start", "end", "text"}
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	for _, rh := range r.ranked() {
+		h := rh.hit
+		document := ""
+		if rh.document > 0 {
+			document = strconv.Itoa(rh.document)
+		}
+		if err := cw.Write([]string{
+			strconv.Itoa(rh.rank),
+			document,
+			strconv.FormatFloat(float64(h.Score), 'f', -1, 32),
+			h.Title,
+			h.Path,
+			h.DocID,
+			h.ChunkID,
+			h.Lang,
+			strconv.Itoa(h.Start),
+			strconv.Itoa(h.End),
+			h.Text,
+		}); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -8,10 +8,12 @@ require (
 	github.com/AlekSi/pointer v1.2.0
 	github.com/PuerkitoBio/goquery v1.10.3
 	github.com/joho/godotenv v1.5.1
+	github.com/kljensen/snowball v0.10.0
 	github.com/pelletier/go-toml/v2 v2.2.4
 	github.com/qdrant/go-client v1.15.2
 	github.com/sashabaranov/go-openai v1.41.1
 	google.golang.org/grpc v1.74.2
+	google.golang.org/protobuf v1.36.7
 )
 
 require (
@@ -20,5 +22,4 @@ require (
 	golang.org/x/sys v0.33.0 // indirect
 	golang.org/x/text v0.25.0 // indirect
 	google.golang.org/genproto/googleapis/rpc v0.0.0-20250528174236-200df99c418a // indirect
-	google.golang.org/protobuf v1.36.7 // indirect
 )
@@ -4,7 +4,9 @@ import (
 	"context"
 	"errors"
 	"flag"
+	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 
 	toml "github.com/pelletier/go-toml/v2"
@@ -13,7 +15,7 @@ import (
 type Config struct {
 	QdrantGRPC   string `toml:"qdrant_grpc"`
 	Collection   string `toml:"collection"`
-	EmbeddingDim int    `toml:"embedding_dim"`
+	EmbeddingDim int    `toml:"embedding_dim"` // 0: resolved from the model registry or a probe call
 	ChunkSize    int    `toml:"chunk_size"`
 	ChunkOverlap int    `toml:"chunk_overlap"`
 
@@ -21,22 +23,236 @@ type Config struct {
 	Model        string `toml:"model"`
 	DefaultModel string `toml:"default_model"`
 
+	// Embedding provider selection and provider definitions
+	Embedding EmbeddingConfig           `toml:"embedding"`
+	Providers map[string]ProviderConfig `toml:"providers"`
+
 	// CLI/runtime options
-	Mode    string `toml:"mode"` // ingest | search
+	Mode    string `toml:"mode"` // ingest | search | answer | serve | grpc | mcp | migrate-ids | cache
 	HTMLDir string `toml:"dir"`
 	TopK    uint64 `toml:"k"`
 	Query   string `toml:"q"`
 	Lang    string `toml:"lang"`
+	Output  string `toml:"output"`  // search output: text | json | jsonl | markdown | csv
+	Force   bool   `toml:"force"`   // re-embed documents even if unchanged
+	Prune   bool   `toml:"prune"`   // delete orphan chunks and removed documents
+	DryRun  bool   `toml:"dry_run"` // only list what ingest would change
+	Resume  bool   `toml:"resume"`  // continue an interrupted ingest from its checkpoint
+
+	// ConfirmPrune allows ingest to delete more than Ingest.PruneMaxShare
+	// of a directory's documents; CLI only
+	ConfirmPrune bool `toml:"-"`
+
+	// Ingest pipeline tuning
+	Ingest IngestConfig `toml:"ingest"`
+
+	// Embedding API resilience
+	Retry RetryConfig `toml:"retry"`
+
+	// Persistent embedding cache
+	Cache CacheConfig `toml:"cache"`
+
+	// Vector store backend
+	Store StoreConfig `toml:"store"`
+
+	// Retrieval: dense, keyword or hybrid with score fusion
+	Search SearchConfig `toml:"search"`
+
+	// Optional second-pass reranking of search results
+	Rerank RerankConfig `toml:"rerank"`
+
+	// Chat model that answers questions in answer mode
+	Answer AnswerConfig `toml:"answer"`
+
+	// HTTP API of serve mode
+	Server ServerConfig `toml:"server"`
 
 	// Not serialized; resolved config path
 	ConfigPath string `toml:"-"`
+	// Not serialized; positional CLI arguments (e.g. "stats" for -mode=cache)
+	Args []string `toml:"-"`
+}
+
+// StoreConfig selects the vector store: a Qdrant server at qdrant_grpc
+// or the embedded file-backed store in Path (relative to the config file)
+type StoreConfig struct {
+	Backend string `toml:"backend"` // qdrant | local
+	Path    string `toml:"path"`
+}
+
+// SearchConfig controls retrieval. Hybrid mode queries the dense vector and
+// the BM25 keyword index, each for Candidates hits, and fuses both lists
+// with Reciprocal Rank Fusion (weight / (rrf_k + rank)) or a weighted sum
+// of min-max normalized scores.
+type SearchConfig struct {
+	Mode          string  `toml:"mode"`   // dense | keyword | hybrid
+	Fusion        string  `toml:"fusion"` // rrf | weighted
+	RRFK          int     `toml:"rrf_k"`
+	DenseWeight   float64 `toml:"dense_weight"`
+	KeywordWeight float64 `toml:"keyword_weight"`
+	Candidates    int     `toml:"candidates"`
+
+	// MMR diversification and the per-document cap pick top-k out of
+	// Candidates hits; MaxPerDoc = 0 disables the cap
+	MMR       bool    `toml:"mmr"`
+	MMRLambda float64 `toml:"mmr_lambda"`
+	MaxPerDoc int     `toml:"max_per_doc"`
+
+	// Group returns documents with their best GroupSize chunks instead of chunks
+	Group     bool `toml:"group"`
+	GroupSize int  `toml:"group_size"`
+
+	// Neighbors adds that many adjacent chunks on each side of a hit
+	Neighbors int `toml:"neighbors"`
+
+	// Keyword index maintained by ingest; relative to the config file
+	KeywordIndex     bool   `toml:"keyword_index"`
+	KeywordIndexPath string `toml:"keyword_index_path"`
+}
+
+// RerankConfig controls the rerank stage: Candidates hits are retrieved and
+// rescored, then cut to top-k. Reranker "llm" asks a chat model of Provider
+// to rate passages in batches of BatchSize, each cut to MaxChars bytes;
+// "lexical" is an offline query-term overlap stand-in.
+type RerankConfig struct {
+	Enabled    bool   `toml:"enabled"`
+	Reranker   string `toml:"reranker"` // llm | lexical
+	Provider   string `toml:"provider"` // key of the [providers.<name>] table
+	Model      string `toml:"model"`
+	Candidates int    `toml:"candidates"`
+	BatchSize  int    `toml:"batch_size"`
+	MaxChars   int    `toml:"max_chars"`
+}
+
+// AnswerConfig selects the chat model of Provider that answers from the
+// search context. MaxTokens = 0 leaves the limit to the provider.
+type AnswerConfig struct {
+	Provider    string  `toml:"provider"` // key of the [providers.<name>] table
+	Model       string  `toml:"model"`
+	Temperature float32 `toml:"temperature"`
+	MaxTokens   int     `toml:"max_tokens"`
+}
+
+// ServerConfig controls the HTTP API of serve mode and the gRPC service of
+// grpc mode. Document uploads get IngestTimeoutMs, other requests
+// RequestTimeoutMs; on shutdown in-flight requests get ShutdownTimeoutMs
+// to finish.
+type ServerConfig struct {
+	Addr              string `toml:"addr"`
+	GRPCAddr          string `toml:"grpc_addr"`
+	RequestTimeoutMs  int    `toml:"request_timeout_ms"`
+	IngestTimeoutMs   int    `toml:"ingest_timeout_ms"`
+	ShutdownTimeoutMs int    `toml:"shutdown_timeout_ms"`
+	MaxUploadMB       int    `toml:"max_upload_mb"`
+}
+
+// EmbeddingConfig selects the provider used for embeddings.
+// Dimensions > 0 requests shortened vectors from models that support it
+// (text-embedding-3-*); 0 keeps the model's native size.
+type EmbeddingConfig struct {
+	Provider   string `toml:"provider"` // key of the [providers.<name>] table
+	Dimensions int    `toml:"dimensions"`
+}
+
+// ProviderConfig describes an embeddings API endpoint.
+// Type is one of "openai", "openai_compatible" (Ollama, vLLM, LocalAI, ...),
+// "azure" or "hash" (deterministic offline vectors for tests and demos).
+// Models maps model names to their vector dimension; an empty map accepts
+// any model, whose dimension is then probed.
+type ProviderConfig struct {
+	Type       string `toml:"type"`
+	BaseURL    string `toml:"base_url"`
+	APIKeyEnv  string `toml:"api_key_env"` // env variable holding the API key
+	APIVersion string `toml:"api_version"` // azure only
+
+	Models map[string]int `toml:"models"`
+	// Deployments maps model names to Azure deployment names (azure only)
+	Deployments map[string]string `toml:"deployments"`
+	// BagOfWords makes texts sharing words land near each other (hash only)
+	BagOfWords bool `toml:"bag_of_words"`
+}
+
+// IngestConfig controls worker counts of the ingest pipeline stages.
+// QueueSize bounds the channels between stages and provides backpressure.
+// EmbedBatchSize and EmbedBatchTokens limit a single embeddings request.
+type IngestConfig struct {
+	ParseWorkers  int `toml:"parse_workers"`
+	ChunkWorkers  int `toml:"chunk_workers"`
+	EmbedWorkers  int `toml:"embed_workers"`
+	UpsertWorkers int `toml:"upsert_workers"`
+	QueueSize     int `toml:"queue_size"`
+
+	EmbedBatchSize   int `toml:"embed_batch_size"`
+	EmbedBatchTokens int `toml:"embed_batch_tokens"`
+
+	// Checkpoint journal; relative paths are resolved next to the config file
+	CheckpointFile string `toml:"checkpoint_file"`
+
+	// DefaultLang is stored as payload.lang when a document neither declares
+	// its language nor is long enough to detect it
+	DefaultLang string `toml:"default_lang"`
+
+	// PruneMaxShare is the largest share of a directory's indexed documents
+	// a run may delete without -confirm-prune; 0 disables the check
+	PruneMaxShare float64 `toml:"prune_max_share"`
+}
+
+// Validate rejects negative worker counts, queue and batch sizes
+// and a prune_max_share outside [0, 1]
+func (c IngestConfig) Validate() error {
+	fields := []struct {
+		name  string
+		value int
+	}{
+		{"parse_workers", c.ParseWorkers},
+		{"chunk_workers", c.ChunkWorkers},
+		{"embed_workers", c.EmbedWorkers},
+		{"upsert_workers", c.UpsertWorkers},
+		{"queue_size", c.QueueSize},
+		{"embed_batch_size", c.EmbedBatchSize},
+		{"embed_batch_tokens", c.EmbedBatchTokens},
+	}
+	for _, f := range fields {
+		if f.value < 0 {
+			return fmt.Errorf("[ingest] %s must not be negative, got %d", f.name, f.value)
+		}
+	}
+	if c.PruneMaxShare < 0 || c.PruneMaxShare > 1 {
+		return fmt.Errorf("[ingest] prune_max_share must be between 0 and 1, got %g", c.PruneMaxShare)
+	}
+	return nil
+}
+
+// RetryConfig controls retries with exponential backoff, client-side
+// rate limits (0 disables a limit) and the circuit breaker of API clients.
+type RetryConfig struct {
+	MaxAttempts      int `toml:"max_attempts"`
+	InitialBackoffMs int `toml:"initial_backoff_ms"`
+	MaxBackoffMs     int `toml:"max_backoff_ms"`
+	// MaxRetryAfterMs bounds the Retry-After delay a server may ask for
+	MaxRetryAfterMs int `toml:"max_retry_after_ms"`
+
+	RequestsPerMinute int `toml:"requests_per_minute"`
+	TokensPerMinute   int `toml:"tokens_per_minute"`
+
+	BreakerThreshold  int `toml:"breaker_threshold"`
+	BreakerCooldownMs int `toml:"breaker_cooldown_ms"`
+}
+
+// CacheConfig controls the local embedding cache shared by ingest and search.
+// Limits of 0 disable them; least recently used entries are evicted first.
+type CacheConfig struct {
+	Enabled    bool   `toml:"enabled"`
+	Path       string `toml:"path"` // relative paths are resolved next to the config file
+	MaxEntries int    `toml:"max_entries"`
+	MaxMB      int    `toml:"max_mb"`
 }
 
 func Defaults() Config {
 	return Config{
 		QdrantGRPC:   "localhost:6334",
 		Collection:   "docs",
-		EmbeddingDim: 1536,
+		EmbeddingDim: 0,
 		ChunkSize:    1200,
 		ChunkOverlap: 250,
 		DefaultModel: "text-embedding-3-small",
@@ -46,9 +262,130 @@ func Defaults() Config {
 		TopK:         5,
 		Query:        "",
 		Lang:         "",
+		Output:       "text",
+		Prune:        true,
+		Embedding: EmbeddingConfig{
+			Provider: "openai",
+		},
+		Providers: map[string]ProviderConfig{
+			"openai": {
+				Type:      "openai",
+				APIKeyEnv: "OPENAI_API_KEY",
+				Models: map[string]int{
+					"text-embedding-3-small": 1536,
+					"text-embedding-3-large": 3072,
+					"text-embedding-ada-002": 1536,
+				},
+			},
+			"hash": {
+				Type:       "hash",
+				BagOfWords: true,
+				Models:     map[string]int{"hash": 256},
+			},
+			"fake": {
+				Type:   "hash",
+				Models: map[string]int{"fake": 256},
+			},
+		},
+		Ingest: IngestConfig{
+			ParseWorkers:  4,
+			ChunkWorkers:  2,
+			EmbedWorkers:  4,
+			UpsertWorkers: 2,
+			QueueSize:     16,
+
+			EmbedBatchSize:   128,
+			EmbedBatchTokens: 100000,
+
+			CheckpointFile: ".ingest-checkpoint.jsonl",
+			DefaultLang:    "ru",
+			PruneMaxShare:  0.5,
+		},
+		Retry: RetryConfig{
+			MaxAttempts:       6,
+			InitialBackoffMs:  500,
+			MaxBackoffMs:      30000,
+			MaxRetryAfterMs:   120000,
+			RequestsPerMinute: 3000,
+			TokensPerMinute:   1000000,
+			BreakerThreshold:  8,
+			BreakerCooldownMs: 30000,
+		},
+		Cache: CacheConfig{
+			Enabled:    true,
+			Path:       ".embedding-cache.bin",
+			MaxEntries: 200000,
+			MaxMB:      1024,
+		},
+		Store: StoreConfig{
+			Backend: "qdrant",
+			Path:    ".vectors",
+		},
+		Search: SearchConfig{
+			Mode:             "hybrid",
+			Fusion:           "rrf",
+			RRFK:             60,
+			DenseWeight:      1,
+			KeywordWeight:    1,
+			Candidates:       50,
+			MMRLambda:        0.7,
+			GroupSize:        3,
+			KeywordIndex:     true,
+			KeywordIndexPath: ".keyword-index",
+		},
+		Rerank: RerankConfig{
+			Enabled:    false,
+			Reranker:   "llm",
+			Provider:   "openai",
+			Model:      "gpt-4o-mini",
+			Candidates: 50,
+			BatchSize:  10,
+			MaxChars:   1200,
+		},
+		Answer: AnswerConfig{
+			Provider:    "openai",
+			Model:       "gpt-4o-mini",
+			Temperature: 0.2,
+		},
+		Server: ServerConfig{
+			Addr:              ":8080",
+			GRPCAddr:          ":9090",
+			RequestTimeoutMs:  60000,
+			IngestTimeoutMs:   300000,
+			ShutdownTimeoutMs: 15000,
+			MaxUploadMB:       10,
+		},
 	}
 }
 
+// CheckpointPath resolves the ingest checkpoint journal path
+func (c Config) CheckpointPath() string {
+	return c.resolvePath(c.Ingest.CheckpointFile)
+}
+
+// StorePath resolves the local vector store directory
+func (c Config) StorePath() string {
+	return c.resolvePath(c.Store.Path)
+}
+
+// KeywordIndexPath resolves the keyword index directory
+func (c Config) KeywordIndexPath() string {
+	return c.resolvePath(c.Search.KeywordIndexPath)
+}
+
+// CachePath resolves the embedding cache file path
+func (c Config) CachePath() string {
+	return c.resolvePath(c.Cache.Path)
+}
+
+// resolvePath makes relative paths relative to the config file directory
+func (c Config) resolvePath(p string) string {
+	if filepath.IsAbs(p) {
+		return p
+	}
+	return filepath.Join(filepath.Dir(c.ConfigPath), p)
+}
+
 func LoadFromFile(path string) (Config, error) {
 	if path == "" {
 		return Defaults(), errors.New("empty config path")
@@ -67,6 +404,9 @@ func LoadFromFile(path string) (Config, error) {
 	if err := toml.Unmarshal(data, &cfg); err != nil {
 		return Defaults(), err
 	}
+	if err := cfg.Ingest.Validate(); err != nil {
+		return Defaults(), fmt.Errorf("%s: %w", path, err)
+	}
 	if cfg.Model == "" && cfg.DefaultModel != "" { // back-compat
 		cfg.Model = cfg.DefaultModel
 	}
@@ -76,19 +416,24 @@ func LoadFromFile(path string) (Config, error) {
 
 // ResolveConfigPath extracts -config from args. Defaults to "config.toml".
 func ResolveConfigPath(args []string) string {
-	path := "config.toml"
+	return resolveFlag(args, "config", "config.toml")
+}
+
+// resolveFlag extracts the value of -name (or --name) from args
+func resolveFlag(args []string, name, def string) string {
 	for i := 1; i < len(args); i++ {
-		a := args[i]
-		if strings.HasPrefix(a, "-config=") {
-			path = strings.TrimPrefix(a, "-config=")
-			break
+		a := strings.TrimPrefix(args[i], "-")
+		if v, ok := strings.CutPrefix(a, "-"+name+"="); ok {
+			return v
 		}
-		if a == "-config" && i+1 < len(args) {
-			path = args[i+1]
-			break
+		if v, ok := strings.CutPrefix(a, name+"="); ok {
+			return v
+		}
+		if (a == name || a == "-"+name) && i+1 < len(args) {
+			return args[i+1]
 		}
 	}
-	return path
+	return def
 }
 
 // Parse merges defaults, config file and CLI flags into a single Config.
@@ -105,24 +450,81 @@ func Parse(args []string) (Config, error) {
 	// define flags using base values
 	cfgPathFlag := flag.String("config", path, "path to config file")
 	_ = cfgPathFlag
-	mode := flag.String("mode", base.Mode, "ingest | search")
+	mode := flag.String("mode", base.Mode, "ingest | search | answer | serve | grpc | mcp | migrate-ids | cache (stats|purge)")
 	dir := flag.String("dir", base.HTMLDir, "папка с HTML (для ingest)")
 	qdr := flag.String("qdrant", base.QdrantGRPC, "Qdrant gRPC addr")
-	topK := flag.Uint64("k", base.TopK, "top-k (для search)")
-	query := flag.String("q", base.Query, "запрос (для search)")
-	modelName := flag.String("model", base.Model, "OpenAI embedding model: text-embedding-3-small|large")
+	store := flag.String("store", base.Store.Backend, "хранилище векторов: qdrant | local")
+	topK := flag.Uint64("k", base.TopK, "top-k (для search и answer)")
+	query := flag.String("q", base.Query, "запрос (для search и answer)")
+	modelName := flag.String("model", base.Model, "модель эмбеддингов (из реестра провайдера)")
+	providerName := flag.String("provider", base.Embedding.Provider, "провайдер эмбеддингов из [providers.<name>]")
+	dimensions := flag.Int("dimensions", base.Embedding.Dimensions, "размерность векторов для моделей text-embedding-3-* (0 — родная)")
 	lang := flag.String("lang", base.Lang, "фильтр языка payload.lang (опц.)")
+	output := flag.String("output", base.Output, "формат вывода search: text | json | jsonl | markdown | csv")
+	searchMode := flag.String("search-mode", base.Search.Mode, "поиск: dense | keyword | hybrid")
+	fusion := flag.String("fusion", base.Search.Fusion, "слияние результатов hybrid: rrf | weighted")
+	rrfK := flag.Int("rrf-k", base.Search.RRFK, "константа k для RRF")
+	denseWeight := flag.Float64("dense-weight", base.Search.DenseWeight, "вес векторного поиска в hybrid")
+	keywordWeight := flag.Float64("keyword-weight", base.Search.KeywordWeight, "вес BM25 в hybrid")
+	mmr := flag.Bool("mmr", base.Search.MMR, "разнообразить выдачу методом MMR")
+	mmrLambda := flag.Float64("mmr-lambda", base.Search.MMRLambda, "баланс MMR: 1 — только релевантность, 0 — только разнообразие")
+	maxPerDoc := flag.Int("max-per-doc", base.Search.MaxPerDoc, "не больше N результатов из одного документа (0 — без ограничения)")
+	group := flag.Bool("group", base.Search.Group, "группировать результаты по документам")
+	groupSize := flag.Int("group-size", base.Search.GroupSize, "сколько лучших чанков показывать на документ")
+	neighbors := flag.Int("neighbors", base.Search.Neighbors, "добавить к каждому результату N соседних чанков с каждой стороны")
+	rerank := flag.Bool("rerank", base.Rerank.Enabled, "переранжировать кандидатов перед выдачей top-k")
+	reranker := flag.String("reranker", base.Rerank.Reranker, "реранкер: llm | lexical")
+	addr := flag.String("addr", base.Server.Addr, "адрес HTTP API (для serve)")
+	grpcAddr := flag.String("grpc-addr", base.Server.GRPCAddr, "адрес gRPC-сервиса (для grpc)")
+	answerProvider := flag.String("answer-provider", base.Answer.Provider, "провайдер чат-модели для answer из [providers.<name>]")
+	answerModel := flag.String("answer-model", base.Answer.Model, "чат-модель для answer")
+	force := flag.Bool("force", base.Force, "переиндексировать все документы, даже неизменённые (для ingest)")
+	prune := flag.Bool("prune", base.Prune, "удалять устаревшие чанки и удалённые документы (для ingest)")
+	noPrune := flag.Bool("no-prune", false, "не удалять устаревшие чанки и документы (для ingest)")
+	confirmPrune := flag.Bool("confirm-prune", false, "разрешить ingest удалить больше prune_max_share документов папки")
+	resume := flag.Bool("resume", base.Resume, "продолжить прерванный ingest с контрольной точки")
+	dryRun := flag.Bool("dry-run", base.DryRun, "только показать изменения, ничего не записывать (для ingest и migrate-ids)")
 	flag.Parse()
 
 	merged := base
 	merged.Mode = *mode
 	merged.HTMLDir = *dir
 	merged.QdrantGRPC = *qdr
+	merged.Store.Backend = *store
 	merged.TopK = *topK
 	merged.Query = *query
 	merged.Model = *modelName
+	if merged.Model == "" {
+		merged.Model = merged.DefaultModel
+	}
+	merged.Embedding.Provider = *providerName
+	merged.Embedding.Dimensions = *dimensions
 	merged.Lang = *lang
+	merged.Output = *output
+	merged.Search.Mode = *searchMode
+	merged.Search.Fusion = *fusion
+	merged.Search.RRFK = *rrfK
+	merged.Search.DenseWeight = *denseWeight
+	merged.Search.KeywordWeight = *keywordWeight
+	merged.Search.MMR = *mmr
+	merged.Search.MMRLambda = *mmrLambda
+	merged.Search.MaxPerDoc = *maxPerDoc
+	merged.Search.Group = *group
+	merged.Search.GroupSize = *groupSize
+	merged.Search.Neighbors = *neighbors
+	merged.Rerank.Enabled = *rerank
+	merged.Rerank.Reranker = *reranker
+	merged.Server.Addr = *addr
+	merged.Server.GRPCAddr = *grpcAddr
+	merged.Answer.Provider = *answerProvider
+	merged.Answer.Model = *answerModel
+	merged.Force = *force
+	merged.Prune = *prune && !*noPrune
+	merged.DryRun = *dryRun
+	merged.Resume = *resume
+	merged.ConfirmPrune = *confirmPrune
 	merged.ConfigPath = path
+	merged.Args = flag.Args()
 
 	return merged, nil
 }
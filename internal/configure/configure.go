@@ -2,8 +2,11 @@ package configure
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"os"
+	"io"
+	"log/slog"
+	"time"
 
 	qdrant "github.com/qdrant/go-client/qdrant"
 	openai "github.com/sashabaranov/go-openai"
@@ -11,13 +14,22 @@ import (
 	"google.golang.org/grpc/credentials/insecure"
 
 	"test-ragger/internal/configure/config"
+	"test-ragger/internal/configure/provider"
 	"test-ragger/internal/models"
+	"test-ragger/internal/usecase/answer"
 	"test-ragger/internal/usecase/ingest"
+	"test-ragger/internal/usecase/migrate"
 	"test-ragger/internal/usecase/search"
-	"test-ragger/internal/utils"
 	"test-ragger/internal/utils/chunker"
+	"test-ragger/internal/utils/embedcache"
+	"test-ragger/internal/utils/hashembed"
 	"test-ragger/internal/utils/htmlx"
+	"test-ragger/internal/utils/keyword"
+	"test-ragger/internal/utils/langdetect"
 	"test-ragger/internal/utils/prompt"
+	"test-ragger/internal/utils/rerank"
+	"test-ragger/internal/utils/resilient"
+	"test-ragger/internal/utils/vectorstore"
 )
 
 // Container holds all application dependencies
@@ -25,27 +37,71 @@ type Container struct {
 	Config config.Config
 
 	// Ingest dependencies
-	IngestEmbeddingClient        ingest.EmbeddingClient
-	IngestQdrantCollectionClient ingest.QdrantCollectionClient
-	IngestQdrantPointsClient     ingest.QdrantPointsClient
-	IngestHTMLParser             ingest.HTMLParser
-	IngestTextChunker            ingest.TextChunker
+	IngestEmbeddingClient ingest.EmbeddingClient
+	IngestVectorStore     ingest.VectorStore
+	IngestHTMLParser      ingest.HTMLParser
+	IngestLangDetector    ingest.LangDetector
+	IngestTextChunker     ingest.TextChunker
 
 	// Search dependencies
-	SearchEmbeddingClient    search.EmbeddingClient
-	SearchQdrantPointsClient search.QdrantPointsClient
-	SearchPromptBuilder      search.PromptBuilder
+	SearchEmbeddingClient search.EmbeddingClient
+	SearchVectorStore     search.VectorStore
+	SearchKeywordIndex    search.KeywordIndex // nil when the keyword index is disabled
+	SearchReranker        search.Reranker     // nil when no reranker could be built
+	SearchPromptBuilder   search.PromptBuilder
+
+	// Answer dependencies
+	AnswerChatClient answer.ChatClient // nil when no chat client could be built
+
+	// Migrate dependencies
+	MigrateVectorStore migrate.VectorStore
+
+	// EmbeddingCache is nil when the cache is disabled
+	EmbeddingCache *embedcache.Cache
 
 	// Internal connections (for cleanup)
-	grpcConn *grpc.ClientConn
+	vectorStore vectorstore.Store
+	grpcConn    *grpc.ClientConn
 }
 
 // Close cleans up resources
 func (c *Container) Close() error {
+	var errs []error
+	if c.EmbeddingCache != nil {
+		errs = append(errs, c.EmbeddingCache.Close())
+	}
+	if c.vectorStore != nil {
+		errs = append(errs, c.vectorStore.Close())
+	}
 	if c.grpcConn != nil {
-		return c.grpcConn.Close()
+		errs = append(errs, c.grpcConn.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// newCacheContainer holds only the embedding cache, for `-mode=cache`
+func newCacheContainer(cfg config.Config) (*Container, error) {
+	c := &Container{Config: cfg}
+	if cfg.Cache.Enabled {
+		cache, err := openCache(cfg)
+		if err != nil {
+			return nil, err
+		}
+		c.EmbeddingCache = cache
 	}
-	return nil
+	return c, nil
+}
+
+func openCache(cfg config.Config) (*embedcache.Cache, error) {
+	cache, err := embedcache.Open(embedcache.Options{
+		Path:       cfg.CachePath(),
+		MaxEntries: cfg.Cache.MaxEntries,
+		MaxBytes:   int64(cfg.Cache.MaxMB) << 20,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("open embedding cache: %w", err)
+	}
+	return cache, nil
 }
 
 // NewContainer creates and configures all dependencies
@@ -55,18 +111,145 @@ func NewContainer(ctx context.Context, args []string) (*Container, error) {
 		return nil, fmt.Errorf("parse config: %w", err)
 	}
 
-	// OpenAI client
-	embeddingClient := openai.NewClient(utils.MustEnv("OPENAI_API_KEY"))
+	// Cache maintenance works on the cache file alone: no provider, no probe
+	if cfg.Mode == "cache" {
+		return newCacheContainer(cfg)
+	}
+
+	// Embedding provider and model from the registry
+	registry := provider.NewRegistry(cfg.Providers)
+	model, err := registry.Resolve(cfg.Embedding.Provider, cfg.Model, cfg.Embedding.Dimensions)
+	if err != nil {
+		return nil, err
+	}
+
+	var embeddingClient embedcache.EmbeddingClient
+	var cache *embedcache.Cache
+	if model.Config.Type == provider.TypeHash {
+		// in-process vectors need neither retries nor a cache
+		embeddingClient = hashembed.New(model.Dimension, model.Config.BagOfWords)
+	} else {
+		// API client; its transport exposes Retry-After to the retry policy
+		apiClient, err := provider.NewClient(model.Config, resilient.NewHTTPClient())
+		if err != nil {
+			return nil, fmt.Errorf("embedding provider %s: %w", cfg.Embedding.Provider, err)
+		}
+		embeddingClient = resilient.NewEmbedder(
+			&openaiClientAdapter{client: apiClient},
+			resilient.NewPolicy(retryOptions(cfg.Retry)),
+		)
+	}
+
+	// Embedding cache in front of the API
+	if cfg.Cache.Enabled {
+		cache, err = openCache(cfg)
+		if err != nil {
+			return nil, err
+		}
+		if model.Config.Type != provider.TypeHash {
+			embeddingClient = embedcache.NewEmbedder(embeddingClient, cache)
+		}
+	}
+
+	// Vector size: registry, then a probe call for unlisted models
+	dim := model.Dimension
+	if dim == 0 {
+		dim, err = probeDimension(ctx, embeddingClient, model)
+		if err != nil {
+			if cache != nil {
+				cache.Close()
+			}
+			return nil, err
+		}
+		slog.Info("Detected embedding dimension", "model", model.Name, "dim", dim)
+	}
+	if cfg.EmbeddingDim != 0 && cfg.EmbeddingDim != dim {
+		if cache != nil {
+			cache.Close()
+		}
+		return nil, fmt.Errorf("embedding_dim = %d in config, but model %s produces %d-dimensional vectors; remove embedding_dim to use the detected size", cfg.EmbeddingDim, model.Name, dim)
+	}
+	cfg.EmbeddingDim = dim
+
+	// Vector store
+	var (
+		store vectorstore.Store
+		conn  *grpc.ClientConn
+	)
+	switch cfg.Store.Backend {
+	case "qdrant":
+		conn, err = grpc.NewClient(cfg.QdrantGRPC, grpc.WithTransportCredentials(insecure.NewCredentials()))
+		if err != nil {
+			err = fmt.Errorf("connect to qdrant: %w", err)
+			break
+		}
+		store = vectorstore.NewQdrant(qdrant.NewCollectionsClient(conn), qdrant.NewPointsClient(conn))
+	case "local":
+		store, err = vectorstore.OpenLocal(cfg.StorePath())
+		if err != nil {
+			err = fmt.Errorf("open local vector store: %w", err)
+		}
+	default:
+		err = fmt.Errorf("unknown vector store backend: %s (want qdrant|local)", cfg.Store.Backend)
+	}
+	if err != nil {
+		if cache != nil {
+			cache.Close()
+		}
+		return nil, err
+	}
+
+	// Keyword index mirrors upserts and deletes of the vector store
+	var keywordIndex search.KeywordIndex
+	if cfg.Search.KeywordIndex {
+		idx, err := keyword.Open(cfg.KeywordIndexPath())
+		if err != nil {
+			store.Close()
+			if conn != nil {
+				conn.Close()
+			}
+			if cache != nil {
+				cache.Close()
+			}
+			return nil, fmt.Errorf("open keyword index: %w", err)
+		}
+		ks := keyword.NewStore(store, idx)
+		store = ks
+		keywordIndex = ks
+	}
 
-	// Qdrant gRPC connection
-	conn, err := grpc.NewClient(cfg.QdrantGRPC, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	// Reranker is built even when disabled by default, so that single
+	// queries can turn it on; it is only required when enabled
+	reranker, err := newReranker(cfg)
 	if err != nil {
-		return nil, fmt.Errorf("connect to qdrant: %w", err)
+		if cfg.Rerank.Enabled {
+			store.Close()
+			if conn != nil {
+				conn.Close()
+			}
+			if cache != nil {
+				cache.Close()
+			}
+			return nil, err
+		}
+		slog.Debug("Reranker unavailable", "error", err)
 	}
 
-	// Qdrant clients
-	collectionsClient := qdrant.NewCollectionsClient(conn)
-	pointsClient := qdrant.NewPointsClient(conn)
+	// Chat model for answers; only required in answer mode
+	chatClient, err := newChatClient(cfg)
+	if err != nil {
+		if cfg.Mode == "answer" {
+			store.Close()
+			if conn != nil {
+				conn.Close()
+			}
+			if cache != nil {
+				cache.Close()
+			}
+			return nil, err
+		}
+		slog.Debug("Answer chat client unavailable", "error", err)
+	}
 
 	// Services
 	htmlParser := &htmlParserImpl{}
@@ -77,32 +260,100 @@ func NewContainer(ctx context.Context, args []string) (*Container, error) {
 		Config: cfg,
 
 		// Ingest dependencies
-		IngestEmbeddingClient:        &openaiClientAdapter{client: embeddingClient},
-		IngestQdrantCollectionClient: &qdrantCollectionClientAdapter{client: collectionsClient},
-		IngestQdrantPointsClient:     &qdrantPointsClientAdapter{client: pointsClient},
-		IngestHTMLParser:             htmlParser,
-		IngestTextChunker:            textChunker,
+		IngestEmbeddingClient: embeddingClient,
+		IngestVectorStore:     store,
+		IngestHTMLParser:      htmlParser,
+		IngestLangDetector:    langdetect.New(),
+		IngestTextChunker:     textChunker,
 
 		// Search dependencies
-		SearchEmbeddingClient:    &openaiClientAdapter{client: embeddingClient},
-		SearchQdrantPointsClient: &qdrantPointsClientAdapter{client: pointsClient},
-		SearchPromptBuilder:      promptBuilder,
+		SearchEmbeddingClient: embeddingClient,
+		SearchVectorStore:     store,
+		SearchKeywordIndex:    keywordIndex,
+		SearchReranker:        reranker,
+		SearchPromptBuilder:   promptBuilder,
+
+		// Answer dependencies
+		AnswerChatClient: chatClient,
 
-		grpcConn: conn,
+		// Migrate dependencies
+		MigrateVectorStore: store,
+
+		EmbeddingCache: cache,
+
+		vectorStore: store,
+		grpcConn:    conn,
 	}, nil
 }
 
-// Implementation adapters
+// newReranker builds the reranker selected in [rerank]
+func newReranker(cfg config.Config) (search.Reranker, error) {
+	switch cfg.Rerank.Reranker {
+	case "lexical":
+		return rerank.NewLexical(), nil
+	case "llm":
+		p, ok := cfg.Providers[cfg.Rerank.Provider]
+		if !ok {
+			return nil, fmt.Errorf("rerank provider %q is not defined in [providers]", cfg.Rerank.Provider)
+		}
+		client, err := provider.NewClient(p, resilient.NewHTTPClient())
+		if err != nil {
+			return nil, fmt.Errorf("rerank provider %s: %w", cfg.Rerank.Provider, err)
+		}
+		policy := resilient.NewPolicy(retryOptions(cfg.Retry))
+		return rerank.NewLLM(client, cfg.Rerank.Model, policy, cfg.Rerank.BatchSize, cfg.Rerank.MaxChars), nil
+	}
+	return nil, fmt.Errorf("unknown reranker: %s (want llm|lexical)", cfg.Rerank.Reranker)
+}
 
-type htmlParserImpl struct{}
+// newChatClient builds the chat client of the [answer] provider
+func newChatClient(cfg config.Config) (answer.ChatClient, error) {
+	p, ok := cfg.Providers[cfg.Answer.Provider]
+	if !ok {
+		return nil, fmt.Errorf("answer provider %q is not defined in [providers]", cfg.Answer.Provider)
+	}
+	client, err := provider.NewClient(p, resilient.NewHTTPClient())
+	if err != nil {
+		return nil, fmt.Errorf("answer provider %s: %w", cfg.Answer.Provider, err)
+	}
+	return resilient.NewChat(client, resilient.NewPolicy(retryOptions(cfg.Retry))), nil
+}
 
-func (h *htmlParserImpl) ToText(ctx context.Context, path string) (string, string, error) {
-	f, err := os.Open(path)
+// probeDimension embeds a short text once to learn the model's vector size
+func probeDimension(ctx context.Context, client embedcache.EmbeddingClient, model provider.Model) (int, error) {
+	res, err := client.CreateEmbeddings(ctx, openai.EmbeddingRequest{
+		Model:      openai.EmbeddingModel(model.Name),
+		Input:      []string{"dimension probe"},
+		Dimensions: model.Dimensions,
+	})
 	if err != nil {
-		return "", "", err
+		return 0, fmt.Errorf("probe embedding dimension of %s: %w", model.Name, err)
+	}
+	if len(res.Data) == 0 || len(res.Data[0].Embedding) == 0 {
+		return 0, fmt.Errorf("probe embedding dimension of %s: empty response", model.Name)
+	}
+	return len(res.Data[0].Embedding), nil
+}
+
+func retryOptions(c config.RetryConfig) resilient.Options {
+	return resilient.Options{
+		MaxAttempts:       c.MaxAttempts,
+		InitialBackoff:    time.Duration(c.InitialBackoffMs) * time.Millisecond,
+		MaxBackoff:        time.Duration(c.MaxBackoffMs) * time.Millisecond,
+		MaxRetryAfter:     time.Duration(c.MaxRetryAfterMs) * time.Millisecond,
+		RequestsPerMinute: c.RequestsPerMinute,
+		TokensPerMinute:   c.TokensPerMinute,
+		BreakerThreshold:  c.BreakerThreshold,
+		BreakerCooldown:   time.Duration(c.BreakerCooldownMs) * time.Millisecond,
 	}
-	defer f.Close()
-	return htmlx.ToText(f, path)
+}
+
+// Implementation adapters
+
+type htmlParserImpl struct{}
+
+func (h *htmlParserImpl) ToText(ctx context.Context, r io.Reader, path string) (string, string, string, error) {
+	return htmlx.ToText(r, path)
 }
 
 type promptBuilderImpl struct{}
@@ -120,27 +371,3 @@ type openaiClientAdapter struct {
 func (a *openaiClientAdapter) CreateEmbeddings(ctx context.Context, req openai.EmbeddingRequestConverter) (openai.EmbeddingResponse, error) {
 	return a.client.CreateEmbeddings(ctx, req)
 }
-
-type qdrantCollectionClientAdapter struct {
-	client qdrant.CollectionsClient
-}
-
-func (a *qdrantCollectionClientAdapter) Get(ctx context.Context, req *qdrant.GetCollectionInfoRequest) (*qdrant.GetCollectionInfoResponse, error) {
-	return a.client.Get(ctx, req)
-}
-
-func (a *qdrantCollectionClientAdapter) Create(ctx context.Context, req *qdrant.CreateCollection) (*qdrant.CollectionOperationResponse, error) {
-	return a.client.Create(ctx, req)
-}
-
-type qdrantPointsClientAdapter struct {
-	client qdrant.PointsClient
-}
-
-func (a *qdrantPointsClientAdapter) Upsert(ctx context.Context, req *qdrant.UpsertPoints) (*qdrant.PointsOperationResponse, error) {
-	return a.client.Upsert(ctx, req)
-}
-
-func (a *qdrantPointsClientAdapter) Search(ctx context.Context, req *qdrant.SearchPoints) (*qdrant.SearchResponse, error) {
-	return a.client.Search(ctx, req)
-}
@@ -0,0 +1,181 @@
+package provider
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+
+	openai "github.com/sashabaranov/go-openai"
+
+	"test-ragger/internal/configure/config"
+)
+
+// Provider types
+const (
+	TypeOpenAI           = "openai"
+	TypeOpenAICompatible = "openai_compatible"
+	TypeAzure            = "azure"
+	TypeHash             = "hash"
+)
+
+// defaultAzureAPIVersion is used when a provider does not pin api_version
+const defaultAzureAPIVersion = "2024-02-01"
+
+// Model is an embedding model resolved from the registry
+type Model struct {
+	Provider string
+	Name     string
+	// Dimension of the returned vectors; 0 when the registry does not
+	// know the model and it has to be probed
+	Dimension int
+	// Dimensions is the requested output size (0 means the model default)
+	Dimensions int
+	// Config of the provider serving the model
+	Config config.ProviderConfig
+}
+
+// Registry resolves embedding providers and their models from config
+type Registry struct {
+	providers map[string]config.ProviderConfig
+}
+
+// NewRegistry creates a registry over the [providers] tables
+func NewRegistry(providers map[string]config.ProviderConfig) *Registry {
+	return &Registry{providers: providers}
+}
+
+// lookup returns the config of a provider by name
+func (r *Registry) lookup(name string) (config.ProviderConfig, error) {
+	p, ok := r.providers[name]
+	if !ok {
+		return config.ProviderConfig{}, fmt.Errorf("unknown embedding provider %q (known: %s)", name, strings.Join(r.names(), ", "))
+	}
+	switch p.Type {
+	case TypeOpenAI, TypeOpenAICompatible, TypeAzure, TypeHash:
+	default:
+		return config.ProviderConfig{}, fmt.Errorf("provider %q: unknown type %q (want %s|%s|%s|%s)", name, p.Type, TypeOpenAI, TypeOpenAICompatible, TypeAzure, TypeHash)
+	}
+	return p, nil
+}
+
+// Resolve checks that the provider serves model and looks up its dimension.
+// A provider without a model list accepts any model. dimensions > 0 asks
+// the model for shortened vectors and must not exceed its native size.
+func (r *Registry) Resolve(providerName, model string, dimensions int) (Model, error) {
+	p, err := r.lookup(providerName)
+	if err != nil {
+		return Model{}, err
+	}
+	if model == "" {
+		return Model{}, fmt.Errorf("provider %q: embedding model is not set", providerName)
+	}
+	m := Model{Provider: providerName, Name: model, Config: p}
+	if len(p.Models) > 0 {
+		dim, ok := p.Models[model]
+		if !ok {
+			known := make([]string, 0, len(p.Models))
+			for name := range p.Models {
+				known = append(known, name)
+			}
+			slices.Sort(known)
+			return Model{}, fmt.Errorf("unknown model %q for provider %q (known: %s)", model, providerName, strings.Join(known, ", "))
+		}
+		m.Dimension = dim
+	}
+
+	if dimensions < 0 {
+		return Model{}, fmt.Errorf("dimensions must be positive, got %d", dimensions)
+	}
+	if dimensions > 0 {
+		// local servers decide for themselves whether they support it
+		if p.Type != TypeOpenAICompatible && p.Type != TypeHash && !SupportsDimensions(model) {
+			return Model{}, fmt.Errorf("model %q does not support the dimensions parameter", model)
+		}
+		if m.Dimension > 0 && dimensions > m.Dimension {
+			return Model{}, fmt.Errorf("dimensions %d exceeds the native size %d of model %q", dimensions, m.Dimension, model)
+		}
+		m.Dimension = dimensions
+		m.Dimensions = dimensions
+	}
+	return m, nil
+}
+
+// SupportsDimensions reports whether an OpenAI model can return
+// shortened vectors via the dimensions request parameter
+func SupportsDimensions(model string) bool {
+	return strings.HasPrefix(model, "text-embedding-3-")
+}
+
+func (r *Registry) names() []string {
+	names := make([]string, 0, len(r.providers))
+	for n := range r.providers {
+		names = append(names, n)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// NewClient builds an OpenAI API client for the provider.
+// Hash providers run in-process and have no API client.
+func NewClient(p config.ProviderConfig, httpClient *http.Client) (*openai.Client, error) {
+	if p.Type == TypeHash {
+		return nil, fmt.Errorf("%s provider has no API client", p.Type)
+	}
+	apiKey, err := apiKey(p)
+	if err != nil {
+		return nil, err
+	}
+
+	var cfg openai.ClientConfig
+	switch p.Type {
+	case TypeOpenAI:
+		cfg = openai.DefaultConfig(apiKey)
+		if p.BaseURL != "" {
+			cfg.BaseURL = p.BaseURL
+		}
+	case TypeOpenAICompatible:
+		if p.BaseURL == "" {
+			return nil, fmt.Errorf("%s provider requires base_url", p.Type)
+		}
+		cfg = openai.DefaultConfig(apiKey)
+		cfg.BaseURL = strings.TrimRight(p.BaseURL, "/")
+	case TypeAzure:
+		if p.BaseURL == "" {
+			return nil, fmt.Errorf("%s provider requires base_url", p.Type)
+		}
+		cfg = openai.DefaultAzureConfig(apiKey, p.BaseURL)
+		cfg.APIVersion = defaultAzureAPIVersion
+		if p.APIVersion != "" {
+			cfg.APIVersion = p.APIVersion
+		}
+		deployments := p.Deployments
+		cfg.AzureModelMapperFunc = func(model string) string {
+			if d, ok := deployments[model]; ok {
+				return d
+			}
+			return model
+		}
+	default:
+		return nil, fmt.Errorf("unknown provider type %q", p.Type)
+	}
+	cfg.HTTPClient = httpClient
+	return openai.NewClientWithConfig(cfg), nil
+}
+
+// apiKey reads the provider key from its env variable. Local
+// OpenAI-compatible servers usually need no key.
+func apiKey(p config.ProviderConfig) (string, error) {
+	if p.APIKeyEnv == "" {
+		if p.Type == TypeOpenAICompatible {
+			return "", nil
+		}
+		return "", fmt.Errorf("%s provider requires api_key_env", p.Type)
+	}
+	key := os.Getenv(p.APIKeyEnv)
+	if key == "" && p.Type != TypeOpenAICompatible {
+		return "", fmt.Errorf("env %s is required", p.APIKeyEnv)
+	}
+	return key, nil
+}
@@ -0,0 +1,17 @@
+package mcp
+
+import (
+	"context"
+
+	openai "github.com/sashabaranov/go-openai"
+
+	"test-ragger/internal/models"
+	"test-ragger/internal/usecase/search"
+)
+
+// Searcher runs retrieval and reads stored documents
+type Searcher interface {
+	Search(ctx context.Context, query string, model openai.EmbeddingModel, opts search.Options) ([]models.Hit, error)
+	Document(ctx context.Context, docID string) (models.Document, error)
+	Documents(ctx context.Context) ([]models.Document, error)
+}
@@ -0,0 +1,76 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+
+	"test-ragger/internal/usecase/search"
+)
+
+// documentURIPrefix prefixes document resource URIs: ragger://documents/<doc_id>
+const documentURIPrefix = "ragger://documents/"
+
+// codeResourceNotFound is the MCP error code for unknown resources
+const codeResourceNotFound = -32002
+
+// resourcesPageSize is the number of documents per resources/list page
+const resourcesPageSize = 100
+
+var resourceTemplates = []resourceTemplate{{
+	URITemplate: documentURIPrefix + "{doc_id}",
+	Name:        "document",
+	Description: "Full text of an indexed HTML document",
+	MimeType:    "text/plain",
+}}
+
+// listResources lists documents, resourcesPageSize per page; the cursor
+// is the offset of the next page
+func (s *Server) listResources(ctx context.Context, p listParams) (map[string]any, error) {
+	offset := 0
+	if p.Cursor != "" {
+		n, err := strconv.Atoi(p.Cursor)
+		if err != nil || n < 0 {
+			return nil, &rpcError{Code: codeInvalidParams, Message: "invalid cursor: " + p.Cursor}
+		}
+		offset = n
+	}
+
+	docs, err := s.searcher.Documents(ctx)
+	if err != nil {
+		return nil, err
+	}
+	offset = min(offset, len(docs))
+	end := min(offset+resourcesPageSize, len(docs))
+
+	resources := make([]resource, 0, end-offset)
+	for _, d := range docs[offset:end] {
+		resources = append(resources, resource{
+			URI:         documentURIPrefix + d.DocID,
+			Name:        d.Title,
+			Description: d.Path,
+			MimeType:    "text/plain",
+		})
+	}
+	result := map[string]any{"resources": resources}
+	if end < len(docs) {
+		result["nextCursor"] = strconv.Itoa(end)
+	}
+	return result, nil
+}
+
+func (s *Server) readResource(ctx context.Context, p readResourceParams) (map[string]any, error) {
+	docID, ok := strings.CutPrefix(p.URI, documentURIPrefix)
+	if !ok || docID == "" {
+		return nil, &rpcError{Code: codeInvalidParams, Message: "unknown resource: " + p.URI}
+	}
+	doc, err := s.searcher.Document(ctx, docID)
+	if errors.Is(err, search.ErrDocumentNotFound) {
+		return nil, &rpcError{Code: codeResourceNotFound, Message: "resource not found: " + p.URI}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return map[string]any{"contents": []resourceContents{{URI: p.URI, MimeType: "text/plain", Text: formatDocument(doc)}}}, nil
+}
@@ -0,0 +1,173 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"slices"
+)
+
+// protocolVersions are the MCP revisions the server speaks, newest first
+var protocolVersions = []string{"2025-06-18", "2025-03-26", "2024-11-05"}
+
+// maxMessage bounds a single JSON-RPC message
+const maxMessage = 4 << 20
+
+// Server is a Model Context Protocol server over stdio exposing search
+// as tools and stored documents as resources
+type Server struct {
+	searcher Searcher
+}
+
+// New creates new MCP server
+func New(searcher Searcher) *Server {
+	return &Server{searcher: searcher}
+}
+
+// Run reads newline-delimited JSON-RPC messages from in and writes
+// responses to out until in is closed or ctx is cancelled. Requests are
+// handled one at a time, in order.
+func (s *Server) Run(ctx context.Context, in io.Reader, out io.Writer) error {
+	lines := make(chan []byte)
+	readErr := make(chan error, 1)
+	go func() {
+		sc := bufio.NewScanner(in)
+		sc.Buffer(make([]byte, 64<<10), maxMessage)
+		for sc.Scan() {
+			line := slices.Clone(sc.Bytes())
+			select {
+			case lines <- line:
+			case <-ctx.Done():
+				return
+			}
+		}
+		readErr <- sc.Err()
+	}()
+
+	enc := json.NewEncoder(out)
+	slog.Info("MCP server ready on stdio")
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err := <-readErr:
+			slog.Info("MCP client closed the connection")
+			return err
+		case line := <-lines:
+			resp := s.handle(ctx, line)
+			if resp == nil {
+				continue
+			}
+			if err := enc.Encode(resp); err != nil {
+				return fmt.Errorf("write response: %w", err)
+			}
+		}
+	}
+}
+
+// handle processes one message; notifications get no response
+func (s *Server) handle(ctx context.Context, line []byte) *response {
+	if len(line) == 0 {
+		return nil
+	}
+	var req request
+	if err := json.Unmarshal(line, &req); err != nil {
+		return &response{JSONRPC: "2.0", ID: json.RawMessage("null"), Error: &rpcError{Code: codeParseError, Message: err.Error()}}
+	}
+	if req.JSONRPC != "2.0" || req.Method == "" {
+		if req.ID == nil {
+			return nil
+		}
+		return &response{JSONRPC: "2.0", ID: req.ID, Error: &rpcError{Code: codeInvalidRequest, Message: "not a JSON-RPC 2.0 request"}}
+	}
+
+	result, err := s.dispatch(ctx, req)
+	if req.ID == nil {
+		if err != nil {
+			slog.Warn("MCP notification failed", "method", req.Method, "error", err)
+		}
+		return nil
+	}
+	resp := &response{JSONRPC: "2.0", ID: req.ID, Result: result}
+	if err != nil {
+		var rpcErr *rpcError
+		if !errors.As(err, &rpcErr) {
+			rpcErr = &rpcError{Code: codeInternalError, Message: err.Error()}
+		}
+		slog.Warn("MCP request failed", "method", req.Method, "error", err)
+		resp.Result, resp.Error = nil, rpcErr
+	}
+	return resp
+}
+
+func (s *Server) dispatch(ctx context.Context, req request) (any, error) {
+	slog.Debug("MCP request", "method", req.Method)
+	switch req.Method {
+	case "initialize":
+		var p initializeParams
+		if err := decodeParams(req.Params, &p); err != nil {
+			return nil, err
+		}
+		return s.initialize(p), nil
+	case "notifications/initialized", "notifications/cancelled":
+		return nil, nil
+	case "ping":
+		return struct{}{}, nil
+	case "tools/list":
+		return map[string]any{"tools": tools}, nil
+	case "tools/call":
+		var p callToolParams
+		if err := decodeParams(req.Params, &p); err != nil {
+			return nil, err
+		}
+		return s.callTool(ctx, p)
+	case "resources/list":
+		var p listParams
+		if err := decodeParams(req.Params, &p); err != nil {
+			return nil, err
+		}
+		return s.listResources(ctx, p)
+	case "resources/templates/list":
+		return map[string]any{"resourceTemplates": resourceTemplates}, nil
+	case "resources/read":
+		var p readResourceParams
+		if err := decodeParams(req.Params, &p); err != nil {
+			return nil, err
+		}
+		return s.readResource(ctx, p)
+	}
+	return nil, &rpcError{Code: codeMethodNotFound, Message: "method not found: " + req.Method}
+}
+
+// initialize agrees on the client's protocol revision when supported,
+// else offers the newest one
+func (s *Server) initialize(p initializeParams) initializeResult {
+	version := protocolVersions[0]
+	if slices.Contains(protocolVersions, p.ProtocolVersion) {
+		version = p.ProtocolVersion
+	}
+	slog.Info("MCP client initialized", "protocol_version", version)
+	return initializeResult{
+		ProtocolVersion: version,
+		Capabilities: map[string]any{
+			"tools":     map[string]any{},
+			"resources": map[string]any{},
+		},
+		ServerInfo:   implementation{Name: "test-ragger", Version: "1.0.0"},
+		Instructions: "Search internal HTML documentation with search_docs and cite the returned paths; read whole documents with get_document.",
+	}
+}
+
+func decodeParams(raw json.RawMessage, v any) error {
+	if len(raw) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(raw, v); err != nil {
+		return &rpcError{Code: codeInvalidParams, Message: "invalid params: " + err.Error()}
+	}
+	return nil
+}
@@ -0,0 +1,181 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	openai "github.com/sashabaranov/go-openai"
+
+	"test-ragger/internal/configure/config"
+	"test-ragger/internal/models"
+	"test-ragger/internal/usecase/search"
+)
+
+// maxK bounds top-k of search_docs
+const maxK = 50
+
+var tools = []tool{
+	{
+		Name:        "search_docs",
+		Description: "Search the internal HTML documentation (hybrid keyword and semantic search). Returns the best matching passages with their document titles, paths and doc_ids.",
+		InputSchema: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"query": map[string]any{"type": "string", "description": "What to look for; error codes and identifiers are matched verbatim"},
+				"k":     map[string]any{"type": "integer", "minimum": 1, "maximum": maxK, "description": "Number of passages to return"},
+				"lang":  map[string]any{"type": "string", "description": "Only passages in this language, e.g. \"ru\" or \"en\""},
+			},
+			"required": []string{"query"},
+		},
+	},
+	{
+		Name:        "get_document",
+		Description: "Read the full text of a document by the doc_id returned by search_docs or list_documents.",
+		InputSchema: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"doc_id": map[string]any{"type": "string", "description": "Document ID, e.g. doc_5f3a..."},
+			},
+			"required": []string{"doc_id"},
+		},
+	},
+	{
+		Name:        "list_documents",
+		Description: "List all indexed documents with their doc_ids, titles and paths.",
+		InputSchema: map[string]any{"type": "object", "properties": map[string]any{}},
+	},
+}
+
+type searchArgs struct {
+	Query string `json:"query"`
+	K     int    `json:"k"`
+	Lang  string `json:"lang"`
+}
+
+type getDocumentArgs struct {
+	DocID string `json:"doc_id"`
+}
+
+// callTool runs a tool. Failures of the tool itself are reported in the
+// result with isError, so the model can see them; unknown tools and
+// malformed arguments are protocol errors.
+func (s *Server) callTool(ctx context.Context, p callToolParams) (callToolResult, error) {
+	var (
+		text string
+		err  error
+	)
+	switch p.Name {
+	case "search_docs":
+		var args searchArgs
+		if err := decodeArgs(p.Arguments, &args); err != nil {
+			return callToolResult{}, err
+		}
+		text, err = s.searchDocs(ctx, args)
+	case "get_document":
+		var args getDocumentArgs
+		if err := decodeArgs(p.Arguments, &args); err != nil {
+			return callToolResult{}, err
+		}
+		text, err = s.getDocument(ctx, args)
+	case "list_documents":
+		text, err = s.listDocuments(ctx)
+	default:
+		return callToolResult{}, &rpcError{Code: codeInvalidParams, Message: "unknown tool: " + p.Name}
+	}
+	if err != nil {
+		return callToolResult{Content: []content{{Type: "text", Text: err.Error()}}, IsError: true}, nil
+	}
+	return callToolResult{Content: []content{{Type: "text", Text: text}}}, nil
+}
+
+func (s *Server) searchDocs(ctx context.Context, args searchArgs) (string, error) {
+	cfg, _ := config.FromContext(ctx)
+
+	query := strings.TrimSpace(args.Query)
+	if query == "" {
+		return "", fmt.Errorf("query is required")
+	}
+	if args.K < 0 || args.K > maxK {
+		return "", fmt.Errorf("k must be between 1 and %d, got %d", maxK, args.K)
+	}
+	opts := search.OptionsFromConfig(cfg)
+	if args.K > 0 {
+		opts.TopK = args.K
+	}
+	if args.Lang != "" {
+		opts.Lang = args.Lang
+	}
+
+	hits, err := s.searcher.Search(ctx, query, openai.EmbeddingModel(cfg.Model), opts)
+	if err != nil {
+		return "", fmt.Errorf("search failed: %w", err)
+	}
+	if len(hits) == 0 {
+		return fmt.Sprintf("No passages found for %q.", query), nil
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Found %d passages for %q.\n", len(hits), query)
+	for i, h := range hits {
+		fmt.Fprintf(&b, "\n[%d] %s — %s\ndoc_id=%s chunk=%s score=%.4f\n%s\n", i+1, h.Title, h.Path, h.DocID, passageChunks(h), h.Score, h.Text)
+	}
+	return b.String(), nil
+}
+
+func (s *Server) getDocument(ctx context.Context, args getDocumentArgs) (string, error) {
+	if args.DocID == "" {
+		return "", fmt.Errorf("doc_id is required")
+	}
+	doc, err := s.searcher.Document(ctx, args.DocID)
+	if err != nil {
+		return "", err
+	}
+	return formatDocument(doc), nil
+}
+
+func (s *Server) listDocuments(ctx context.Context) (string, error) {
+	docs, err := s.searcher.Documents(ctx)
+	if err != nil {
+		return "", err
+	}
+	if len(docs) == 0 {
+		return "No documents are indexed.", nil
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d documents:\n", len(docs))
+	for _, d := range docs {
+		fmt.Fprintf(&b, "- %s — %s (doc_id=%s, chunks=%d)\n", d.Title, d.Path, d.DocID, d.Chunks)
+	}
+	return b.String(), nil
+}
+
+// formatDocument renders a document with a metadata header
+func formatDocument(d models.Document) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\npath: %s\ndoc_id: %s\n", d.Title, d.Path, d.DocID)
+	if d.Lang != "" {
+		fmt.Fprintf(&b, "lang: %s\n", d.Lang)
+	}
+	fmt.Fprintf(&b, "\n%s\n", d.Text)
+	return b.String()
+}
+
+// passageChunks names the chunks a hit's text spans
+func passageChunks(h models.Hit) string {
+	if len(h.ChunkIDs) > 1 {
+		return h.ChunkIDs[0] + ".." + h.ChunkIDs[len(h.ChunkIDs)-1]
+	}
+	return h.ChunkID
+}
+
+func decodeArgs(raw json.RawMessage, v any) error {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil
+	}
+	if err := json.Unmarshal(raw, v); err != nil {
+		return &rpcError{Code: codeInvalidParams, Message: "invalid arguments: " + err.Error()}
+	}
+	return nil
+}
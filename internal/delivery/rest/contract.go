@@ -0,0 +1,30 @@
+package rest
+
+import (
+	"context"
+
+	openai "github.com/sashabaranov/go-openai"
+
+	"test-ragger/internal/models"
+	"test-ragger/internal/usecase/answer"
+	"test-ragger/internal/usecase/search"
+)
+
+// Searcher runs retrieval for /v1/search
+type Searcher interface {
+	Search(ctx context.Context, query string, model openai.EmbeddingModel, opts search.Options) ([]models.Hit, error)
+}
+
+// Answerer generates grounded answers for /v1/answer and
+// /v1/chat/completions
+type Answerer interface {
+	Answer(ctx context.Context, question string, model openai.EmbeddingModel, opts answer.Options, onDelta func(string) error) (models.Answer, error)
+	Complete(ctx context.Context, req openai.ChatCompletionRequest, model openai.EmbeddingModel, opts answer.Options) (openai.ChatCompletionResponse, []models.Source, error)
+	CompleteStream(ctx context.Context, req openai.ChatCompletionRequest, model openai.EmbeddingModel, opts answer.Options, onChunk func(openai.ChatCompletionStreamResponse) error) ([]models.Source, error)
+}
+
+// Ingester adds and removes single documents for /v1/documents
+type Ingester interface {
+	IngestDocument(ctx context.Context, path string, raw []byte, model openai.EmbeddingModel) (models.IngestResult, error)
+	DeleteDocument(ctx context.Context, docID string) (int, error)
+}
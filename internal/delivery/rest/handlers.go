@@ -0,0 +1,244 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	openai "github.com/sashabaranov/go-openai"
+
+	"test-ragger/internal/configure/config"
+	"test-ragger/internal/models"
+	"test-ragger/internal/usecase/answer"
+	"test-ragger/internal/usecase/ingest"
+	"test-ragger/internal/usecase/search"
+)
+
+const (
+	// maxK bounds per-request top-k
+	maxK = 100
+	// maxQueryBody bounds JSON bodies of search and answer requests
+	maxQueryBody = 1 << 20
+)
+
+// queryRequest is the body of /v1/search and /v1/answer. K and Lang
+// override the configured top-k and language filter; Lang "" keeps it.
+type queryRequest struct {
+	Query string `json:"query"`
+	K     int    `json:"k"`
+	Lang  string `json:"lang"`
+}
+
+type searchResponse struct {
+	Query  string       `json:"query"`
+	Hits   []models.Hit `json:"hits"`
+	TookMs int64        `json:"took_ms"`
+}
+
+type answerResponse struct {
+	Query string `json:"query"`
+	models.Answer
+	TookMs int64 `json:"took_ms"`
+}
+
+type deleteResponse struct {
+	DocID         string `json:"doc_id"`
+	DeletedChunks int    `json:"deleted_chunks"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+func (s *Server) health(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+func (s *Server) search(w http.ResponseWriter, r *http.Request) {
+	cfg, _ := config.FromContext(r.Context())
+	start := time.Now()
+
+	req, opts, err := decodeQuery(w, r, cfg)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	hits, err := s.searcher.Search(r.Context(), req.Query, openai.EmbeddingModel(cfg.Model), opts)
+	if err != nil {
+		writeError(w, errorStatus(err), err)
+		return
+	}
+	if hits == nil {
+		hits = []models.Hit{}
+	}
+	writeJSON(w, http.StatusOK, searchResponse{Query: req.Query, Hits: hits, TookMs: time.Since(start).Milliseconds()})
+}
+
+func (s *Server) answer(w http.ResponseWriter, r *http.Request) {
+	cfg, _ := config.FromContext(r.Context())
+	start := time.Now()
+
+	if s.answerer == nil {
+		writeError(w, http.StatusNotImplemented, errors.New("answers are not configured; set up the [answer] provider"))
+		return
+	}
+	req, opts, err := decodeQuery(w, r, cfg)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	answerOpts := answer.OptionsFromConfig(cfg)
+	answerOpts.Search = opts
+	ans, err := s.answerer.Answer(r.Context(), req.Query, openai.EmbeddingModel(cfg.Model), answerOpts, nil)
+	if err != nil {
+		writeError(w, errorStatus(err), err)
+		return
+	}
+	if ans.Sources == nil {
+		ans.Sources = []models.Source{}
+	}
+	if ans.Hits == nil {
+		ans.Hits = []models.Hit{}
+	}
+	writeJSON(w, http.StatusOK, answerResponse{Query: req.Query, Answer: ans, TookMs: time.Since(start).Milliseconds()})
+}
+
+// ingestDocument accepts a multipart form with the HTML in "file" (and an
+// optional "path" naming the document) or the raw HTML body with ?path=
+func (s *Server) ingestDocument(w http.ResponseWriter, r *http.Request) {
+	cfg, _ := config.FromContext(r.Context())
+
+	r.Body = http.MaxBytesReader(w, r.Body, int64(cfg.Server.MaxUploadMB)<<20)
+	path, raw, err := readUpload(r)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeError(w, http.StatusRequestEntityTooLarge, fmt.Errorf("document is larger than %d MB", cfg.Server.MaxUploadMB))
+			return
+		}
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	s.ingestMu.Lock()
+	defer s.ingestMu.Unlock()
+	res, err := s.ingester.IngestDocument(r.Context(), path, raw, openai.EmbeddingModel(cfg.Model))
+	if err != nil {
+		writeError(w, errorStatus(err), err)
+		return
+	}
+	status := http.StatusOK
+	if res.Status == "added" {
+		status = http.StatusCreated
+	}
+	writeJSON(w, status, res)
+}
+
+func (s *Server) deleteDocument(w http.ResponseWriter, r *http.Request) {
+	docID := r.PathValue("doc_id")
+
+	s.ingestMu.Lock()
+	defer s.ingestMu.Unlock()
+	n, err := s.ingester.DeleteDocument(r.Context(), docID)
+	if err != nil {
+		writeError(w, errorStatus(err), err)
+		return
+	}
+	writeJSON(w, http.StatusOK, deleteResponse{DocID: docID, DeletedChunks: n})
+}
+
+// decodeQuery reads a query request and applies its overrides to the
+// configured search options
+func decodeQuery(w http.ResponseWriter, r *http.Request, cfg config.Config) (queryRequest, search.Options, error) {
+	var req queryRequest
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxQueryBody))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		return req, search.Options{}, fmt.Errorf("invalid JSON body: %w", err)
+	}
+	req.Query = strings.TrimSpace(req.Query)
+	if req.Query == "" {
+		return req, search.Options{}, errors.New("query is required")
+	}
+	if req.K < 0 || req.K > maxK {
+		return req, search.Options{}, fmt.Errorf("k must be between 1 and %d, got %d", maxK, req.K)
+	}
+
+	opts := search.OptionsFromConfig(cfg)
+	if req.K > 0 {
+		opts.TopK = req.K
+	}
+	if req.Lang != "" {
+		opts.Lang = req.Lang
+	}
+	return req, opts, nil
+}
+
+// readUpload returns the document path and HTML of an upload
+func readUpload(r *http.Request) (string, []byte, error) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != "multipart/form-data" {
+		path := r.URL.Query().Get("path")
+		if path == "" {
+			return "", nil, errors.New("path query parameter is required for a raw HTML body")
+		}
+		raw, err := io.ReadAll(r.Body)
+		if err != nil {
+			return "", nil, err
+		}
+		return path, raw, nil
+	}
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		return "", nil, fmt.Errorf("multipart field \"file\": %w", err)
+	}
+	defer file.Close()
+	raw, err := io.ReadAll(file)
+	if err != nil {
+		return "", nil, err
+	}
+	path := r.FormValue("path")
+	if path == "" {
+		path = header.Filename
+	}
+	if path == "" {
+		return "", nil, errors.New("document path is required: set the \"path\" field or a file name")
+	}
+	return path, raw, nil
+}
+
+// errorStatus maps usecase errors to HTTP statuses
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, ingest.ErrDocumentNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout
+	case errors.Is(err, context.Canceled):
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusInternalServerError
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	if status >= http.StatusInternalServerError {
+		slog.Error("Request failed", "status", status, "error", err)
+	}
+	writeJSON(w, status, errorResponse{Error: err.Error()})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		slog.Warn("Failed to write response", "error", err)
+	}
+}
@@ -0,0 +1,204 @@
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	openai "github.com/sashabaranov/go-openai"
+
+	"test-ragger/internal/configure/config"
+	"test-ragger/internal/models"
+	"test-ragger/internal/usecase/answer"
+)
+
+const (
+	// modelName is the model test-ragger reports to OpenAI clients; any
+	// requested model is answered by the [answer] chat model
+	modelName = "test-ragger"
+	// maxChatBody bounds chat completions requests, which carry the history
+	maxChatBody = 4 << 20
+)
+
+// chatCompletionRequest is an OpenAI chat completions request that tells
+// an explicit "temperature": 0 from a missing temperature
+type chatCompletionRequest struct {
+	openai.ChatCompletionRequest
+	Temperature *float32 `json:"temperature"`
+}
+
+// chatCompletionResponse is an OpenAI chat completion with the sources
+// of its context
+type chatCompletionResponse struct {
+	openai.ChatCompletionResponse
+	Sources []models.Source `json:"sources"`
+}
+
+// chatCompletionChunk is a streamed chunk; the last one before [DONE]
+// has no choices and carries the sources
+type chatCompletionChunk struct {
+	openai.ChatCompletionStreamResponse
+	Sources []models.Source `json:"sources,omitempty"`
+}
+
+type modelList struct {
+	Object string        `json:"object"`
+	Data   []modelObject `json:"data"`
+}
+
+type modelObject struct {
+	ID      string `json:"id"`
+	Object  string `json:"object"`
+	Created int64  `json:"created"`
+	OwnedBy string `json:"owned_by"`
+}
+
+type openAIErrorResponse struct {
+	Error openAIError `json:"error"`
+}
+
+type openAIError struct {
+	Message string `json:"message"`
+	Type    string `json:"type"`
+}
+
+func (s *Server) listModels(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, modelList{
+		Object: "list",
+		Data:   []modelObject{{ID: modelName, Object: "model", OwnedBy: modelName}},
+	})
+}
+
+// chatCompletions serves the OpenAI chat completions protocol backed by
+// retrieval: the last user message is the search query and the answer is
+// grounded on its results. "stream": true returns server-sent events.
+func (s *Server) chatCompletions(w http.ResponseWriter, r *http.Request) {
+	cfg, _ := config.FromContext(r.Context())
+
+	if s.answerer == nil {
+		writeOpenAIError(w, http.StatusNotImplemented, errors.New("chat completions are not configured; set up the [answer] provider"))
+		return
+	}
+	var body chatCompletionRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxChatBody)).Decode(&body); err != nil {
+		writeOpenAIError(w, http.StatusBadRequest, fmt.Errorf("invalid JSON body: %w", err))
+		return
+	}
+	req := body.ChatCompletionRequest
+	requested := req.Model
+	if requested == "" {
+		requested = modelName
+	}
+	model := openai.EmbeddingModel(cfg.Model)
+	opts := answer.OptionsFromConfig(cfg)
+	// the configured temperature applies only when the client sets none
+	if body.Temperature != nil {
+		opts.Temperature = *body.Temperature
+	}
+
+	if !req.Stream {
+		resp, sources, err := s.answerer.Complete(r.Context(), req, model, opts)
+		if err != nil {
+			writeOpenAIError(w, chatErrorStatus(err), err)
+			return
+		}
+		resp.Model = requested
+		writeJSON(w, http.StatusOK, chatCompletionResponse{ChatCompletionResponse: resp, Sources: sources})
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeOpenAIError(w, http.StatusInternalServerError, errors.New("streaming is not supported by the connection"))
+		return
+	}
+	// headers are sent with the first chunk, so that failures before it
+	// still get a proper status
+	started := false
+	last := openai.ChatCompletionStreamResponse{Object: "chat.completion.chunk", Created: time.Now().Unix()}
+	send := func(v any) error {
+		if !started {
+			w.Header().Set("Content-Type", "text/event-stream")
+			w.Header().Set("Cache-Control", "no-cache")
+			w.Header().Set("Connection", "keep-alive")
+			w.WriteHeader(http.StatusOK)
+			started = true
+		}
+		if err := writeEvent(w, v); err != nil {
+			return err
+		}
+		flusher.Flush()
+		return nil
+	}
+
+	sources, err := s.answerer.CompleteStream(r.Context(), req, model, opts, func(chunk openai.ChatCompletionStreamResponse) error {
+		chunk.Model = requested
+		last = chunk
+		return send(chatCompletionChunk{ChatCompletionStreamResponse: chunk})
+	})
+	if err != nil {
+		if !started {
+			writeOpenAIError(w, chatErrorStatus(err), err)
+			return
+		}
+		// the status is already sent: report the error in-band
+		slog.Error("Chat completion stream failed", "error", err)
+		_ = writeEvent(w, openAIErrorResponse{Error: openAIError{Message: err.Error(), Type: "server_error"}})
+		flusher.Flush()
+		return
+	}
+
+	final := openai.ChatCompletionStreamResponse{
+		ID:      last.ID,
+		Object:  "chat.completion.chunk",
+		Created: last.Created,
+		Model:   requested,
+		Choices: []openai.ChatCompletionStreamChoice{},
+	}
+	if err := send(chatCompletionChunk{ChatCompletionStreamResponse: final, Sources: sources}); err != nil {
+		return
+	}
+	if _, err := fmt.Fprint(w, "data: [DONE]\n\n"); err == nil {
+		flusher.Flush()
+	}
+}
+
+// writeEvent writes v as a server-sent event data line
+func writeEvent(w http.ResponseWriter, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "data: %s\n\n", data)
+	return err
+}
+
+// chatErrorStatus passes upstream API statuses through and maps other
+// errors like errorStatus
+func chatErrorStatus(err error) int {
+	var apiErr *openai.APIError
+	if errors.As(err, &apiErr) && apiErr.HTTPStatusCode != 0 {
+		return apiErr.HTTPStatusCode
+	}
+	var reqErr *openai.RequestError
+	if errors.As(err, &reqErr) && reqErr.HTTPStatusCode != 0 {
+		return reqErr.HTTPStatusCode
+	}
+	if errors.Is(err, answer.ErrNoQuestion) {
+		return http.StatusBadRequest
+	}
+	return errorStatus(err)
+}
+
+// writeOpenAIError responds with an error in the OpenAI format
+func writeOpenAIError(w http.ResponseWriter, status int, err error) {
+	typ := "invalid_request_error"
+	if status >= http.StatusInternalServerError {
+		typ = "server_error"
+		slog.Error("Request failed", "status", status, "error", err)
+	}
+	writeJSON(w, status, openAIErrorResponse{Error: openAIError{Message: err.Error(), Type: typ}})
+}
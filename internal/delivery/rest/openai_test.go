@@ -0,0 +1,67 @@
+package rest
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	openai "github.com/sashabaranov/go-openai"
+
+	"test-ragger/internal/configure/config"
+	"test-ragger/internal/models"
+	"test-ragger/internal/usecase/answer"
+)
+
+// recordingAnswerer keeps the options of the last completion
+type recordingAnswerer struct {
+	opts answer.Options
+}
+
+func (a *recordingAnswerer) Answer(ctx context.Context, question string, model openai.EmbeddingModel, opts answer.Options, onDelta func(string) error) (models.Answer, error) {
+	return models.Answer{}, nil
+}
+
+func (a *recordingAnswerer) Complete(ctx context.Context, req openai.ChatCompletionRequest, model openai.EmbeddingModel, opts answer.Options) (openai.ChatCompletionResponse, []models.Source, error) {
+	a.opts = opts
+	return openai.ChatCompletionResponse{}, nil, nil
+}
+
+func (a *recordingAnswerer) CompleteStream(ctx context.Context, req openai.ChatCompletionRequest, model openai.EmbeddingModel, opts answer.Options, onChunk func(openai.ChatCompletionStreamResponse) error) ([]models.Source, error) {
+	a.opts = opts
+	return nil, nil
+}
+
+func TestChatCompletionsTemperature(t *testing.T) {
+	cfg := config.Defaults()
+	cfg.Answer.Temperature = 0.2
+
+	tests := []struct {
+		name string
+		body string
+		want float32
+	}{
+		{"missing uses config", `{"messages":[{"role":"user","content":"q"}]}`, 0.2},
+		{"explicit zero", `{"temperature":0,"messages":[{"role":"user","content":"q"}]}`, 0},
+		{"explicit value", `{"temperature":0.9,"messages":[{"role":"user","content":"q"}]}`, 0.9},
+		{"streamed zero", `{"stream":true,"temperature":0,"messages":[{"role":"user","content":"q"}]}`, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := &recordingAnswerer{opts: answer.Options{Temperature: -1}}
+			s := New(nil, a, nil)
+			r := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(tt.body))
+			r = r.WithContext(config.IntoContext(r.Context(), cfg))
+			w := httptest.NewRecorder()
+
+			s.chatCompletions(w, r)
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d: %s", w.Code, w.Body)
+			}
+			if a.opts.Temperature != tt.want {
+				t.Errorf("temperature = %v, want %v", a.opts.Temperature, tt.want)
+			}
+		})
+	}
+}
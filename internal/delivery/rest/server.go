@@ -0,0 +1,131 @@
+package rest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"test-ragger/internal/configure/config"
+)
+
+// Server exposes search, answers, OpenAI-compatible chat completions and
+// document ingest over HTTP
+type Server struct {
+	searcher Searcher
+	answerer Answerer
+	ingester Ingester
+
+	// ingestMu serializes document writes, so concurrent uploads of one
+	// document cannot interleave their upserts and orphan deletion
+	ingestMu sync.Mutex
+}
+
+// New creates new HTTP server. answerer may be nil when no chat model is
+// configured, then /v1/answer responds 501.
+func New(searcher Searcher, answerer Answerer, ingester Ingester) *Server {
+	return &Server{searcher: searcher, answerer: answerer, ingester: ingester}
+}
+
+// Handler returns the routes of the API. Every request gets a timeout:
+// [server] request_timeout_ms, or ingest_timeout_ms for document uploads.
+func (s *Server) Handler(cfg config.Config) http.Handler {
+	requestTimeout := time.Duration(cfg.Server.RequestTimeoutMs) * time.Millisecond
+	ingestTimeout := time.Duration(cfg.Server.IngestTimeoutMs) * time.Millisecond
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", s.health)
+	mux.Handle("POST /v1/search", withTimeout(requestTimeout, s.search))
+	mux.Handle("POST /v1/answer", withTimeout(requestTimeout, s.answer))
+	mux.Handle("POST /v1/chat/completions", withTimeout(requestTimeout, s.chatCompletions))
+	mux.HandleFunc("GET /v1/models", s.listModels)
+	mux.Handle("POST /v1/documents", withTimeout(ingestTimeout, s.ingestDocument))
+	mux.Handle("DELETE /v1/documents/{doc_id}", withTimeout(requestTimeout, s.deleteDocument))
+	return withLogging(mux)
+}
+
+// Run serves the API on [server] addr until ctx is cancelled, then shuts
+// down gracefully: in-flight requests get shutdown_timeout_ms to finish.
+func (s *Server) Run(ctx context.Context) error {
+	cfg, _ := config.FromContext(ctx)
+
+	srv := &http.Server{
+		Addr:              cfg.Server.Addr,
+		Handler:           s.Handler(cfg),
+		ReadHeaderTimeout: 10 * time.Second,
+		// requests outlive ctx while the server drains
+		BaseContext: func(net.Listener) context.Context { return context.WithoutCancel(ctx) },
+	}
+
+	ln, err := net.Listen("tcp", srv.Addr)
+	if err != nil {
+		return fmt.Errorf("listen %s: %w", srv.Addr, err)
+	}
+	slog.Info("HTTP server listening", "addr", ln.Addr().String())
+
+	errCh := make(chan error, 1)
+	go func() { errCh <- srv.Serve(ln) }()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+	}
+
+	timeout := time.Duration(cfg.Server.ShutdownTimeoutMs) * time.Millisecond
+	slog.Info("Shutting down HTTP server", "timeout", timeout)
+	shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), timeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("shutdown: %w", err)
+	}
+	if err := <-errCh; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	slog.Info("HTTP server stopped")
+	return nil
+}
+
+// withTimeout bounds the request context; handlers report an exceeded
+// deadline as 504
+func withTimeout(timeout time.Duration, h http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if timeout > 0 {
+			ctx, cancel := context.WithTimeout(r.Context(), timeout)
+			defer cancel()
+			r = r.WithContext(ctx)
+		}
+		h(w, r)
+	})
+}
+
+// statusWriter remembers the response status for the access log
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Flush lets streaming handlers flush through the wrapper
+func (w *statusWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func withLogging(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+		h.ServeHTTP(sw, r)
+		slog.Info("HTTP request", "method", r.Method, "path", r.URL.Path, "status", sw.status, "duration", time.Since(start))
+	})
+}
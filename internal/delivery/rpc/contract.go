@@ -0,0 +1,26 @@
+package rpc
+
+import (
+	"context"
+
+	openai "github.com/sashabaranov/go-openai"
+
+	"test-ragger/internal/models"
+	"test-ragger/internal/usecase/answer"
+	"test-ragger/internal/usecase/search"
+)
+
+// Searcher runs retrieval for Search
+type Searcher interface {
+	Search(ctx context.Context, query string, model openai.EmbeddingModel, opts search.Options) ([]models.Hit, error)
+}
+
+// Answerer generates grounded answers for Answer
+type Answerer interface {
+	Answer(ctx context.Context, question string, model openai.EmbeddingModel, opts answer.Options, onDelta func(string) error) (models.Answer, error)
+}
+
+// Ingester adds single documents for IngestDocument
+type Ingester interface {
+	IngestDocument(ctx context.Context, path string, raw []byte, model openai.EmbeddingModel) (models.IngestResult, error)
+}
@@ -0,0 +1,129 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"slices"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	raggerv1 "test-ragger/api/ragger/v1"
+	"test-ragger/internal/configure/config"
+	"test-ragger/internal/models"
+	"test-ragger/internal/usecase/ingest"
+	"test-ragger/internal/usecase/search"
+)
+
+// maxK bounds per-request top-k
+const maxK = 100
+
+// searchOptions applies request overrides to the configured search options
+func searchOptions(cfg config.Config, k uint32, f *raggerv1.Filter, o *raggerv1.SearchOptions) (search.Options, error) {
+	opts := search.OptionsFromConfig(cfg)
+	if k > maxK {
+		return opts, status.Errorf(codes.InvalidArgument, "k must be between 1 and %d, got %d", maxK, k)
+	}
+	if k > 0 {
+		opts.TopK = int(k)
+	}
+	if f.GetLang() != "" {
+		opts.Lang = f.GetLang()
+	}
+	opts.DocID = f.GetDocId()
+
+	if o == nil {
+		return opts, nil
+	}
+	if o.Mode != nil {
+		if !slices.Contains([]string{search.ModeDense, search.ModeKeyword, search.ModeHybrid}, o.GetMode()) {
+			return opts, status.Errorf(codes.InvalidArgument, "unknown search mode %q (want dense|keyword|hybrid)", o.GetMode())
+		}
+		opts.Mode = o.GetMode()
+	}
+	if o.Fusion != nil {
+		if !slices.Contains([]string{search.FusionRRF, search.FusionWeighted}, o.GetFusion()) {
+			return opts, status.Errorf(codes.InvalidArgument, "unknown fusion %q (want rrf|weighted)", o.GetFusion())
+		}
+		opts.Fusion = o.GetFusion()
+	}
+	if o.Rerank != nil {
+		opts.Rerank = o.GetRerank()
+	}
+	if o.Mmr != nil {
+		opts.MMR = o.GetMmr()
+	}
+	if o.MaxPerDoc != nil {
+		opts.MaxPerDoc = int(o.GetMaxPerDoc())
+	}
+	if o.Neighbors != nil {
+		opts.Neighbors = int(o.GetNeighbors())
+	}
+	return opts, nil
+}
+
+func toHits(hits []models.Hit) []*raggerv1.Hit {
+	out := make([]*raggerv1.Hit, len(hits))
+	for i, h := range hits {
+		out[i] = &raggerv1.Hit{
+			Id:            h.ID,
+			Score:         h.Score,
+			Title:         h.Title,
+			Text:          h.Text,
+			DocId:         h.DocID,
+			ChunkId:       h.ChunkID,
+			Path:          h.Path,
+			Lang:          h.Lang,
+			Start:         int32(h.Start),
+			End:           int32(h.End),
+			ChunkIds:      h.ChunkIDs,
+			DenseScore:    h.DenseScore,
+			KeywordScore:  h.KeywordScore,
+			Reranked:      h.Reranked,
+			OriginalScore: h.OriginalScore,
+			RerankScore:   h.RerankScore,
+		}
+	}
+	return out
+}
+
+func toSources(sources []models.Source) []*raggerv1.Source {
+	out := make([]*raggerv1.Source, len(sources))
+	for i, s := range sources {
+		out[i] = &raggerv1.Source{
+			N:       uint32(s.N),
+			Title:   s.Title,
+			Path:    s.Path,
+			DocId:   s.DocID,
+			ChunkId: s.ChunkID,
+			Cited:   s.Cited,
+		}
+	}
+	return out
+}
+
+func toIngestResponse(r models.IngestResult) *raggerv1.IngestDocumentResponse {
+	return &raggerv1.IngestDocumentResponse{
+		DocId:         r.DocID,
+		Path:          r.Path,
+		Title:         r.Title,
+		Lang:          r.Lang,
+		Status:        r.Status,
+		Chunks:        uint32(r.Chunks),
+		DeletedChunks: uint32(r.DeletedChunks),
+	}
+}
+
+// toStatus maps usecase errors to gRPC statuses
+func toStatus(err error) error {
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	switch {
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, context.Canceled):
+		return status.FromContextError(err).Err()
+	case errors.Is(err, ingest.ErrDocumentNotFound), errors.Is(err, search.ErrDocumentNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	}
+	return status.Error(codes.Internal, err.Error())
+}
@@ -0,0 +1,251 @@
+package rpc
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	openai "github.com/sashabaranov/go-openai"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+
+	raggerv1 "test-ragger/api/ragger/v1"
+	"test-ragger/internal/configure/config"
+	"test-ragger/internal/usecase/answer"
+)
+
+// Server implements the Ragger gRPC service
+type Server struct {
+	raggerv1.UnimplementedRaggerServer
+
+	searcher Searcher
+	answerer Answerer
+	ingester Ingester
+
+	// ingestMu serializes document writes, like the HTTP API does
+	ingestMu sync.Mutex
+}
+
+// New creates new gRPC server. answerer may be nil when no chat model is
+// configured, then Answer returns Unimplemented.
+func New(searcher Searcher, answerer Answerer, ingester Ingester) *Server {
+	return &Server{searcher: searcher, answerer: answerer, ingester: ingester}
+}
+
+// Run serves Ragger with the health and reflection services on [server]
+// grpc_addr until ctx is cancelled, then stops gracefully: in-flight calls
+// get shutdown_timeout_ms to finish.
+func (s *Server) Run(ctx context.Context) error {
+	cfg, _ := config.FromContext(ctx)
+
+	i := &interceptor{
+		ctx:            context.WithoutCancel(ctx),
+		requestTimeout: time.Duration(cfg.Server.RequestTimeoutMs) * time.Millisecond,
+		ingestTimeout:  time.Duration(cfg.Server.IngestTimeoutMs) * time.Millisecond,
+	}
+	srv := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(i.unary),
+		grpc.ChainStreamInterceptor(i.stream),
+	)
+	raggerv1.RegisterRaggerServer(srv, s)
+	healthSrv := health.NewServer()
+	healthpb.RegisterHealthServer(srv, healthSrv)
+	healthSrv.SetServingStatus(raggerv1.Ragger_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_SERVING)
+	reflection.Register(srv)
+
+	ln, err := net.Listen("tcp", cfg.Server.GRPCAddr)
+	if err != nil {
+		return fmt.Errorf("listen %s: %w", cfg.Server.GRPCAddr, err)
+	}
+	slog.Info("gRPC server listening", "addr", ln.Addr().String())
+
+	errCh := make(chan error, 1)
+	go func() { errCh <- srv.Serve(ln) }()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+	}
+
+	timeout := time.Duration(cfg.Server.ShutdownTimeoutMs) * time.Millisecond
+	slog.Info("Shutting down gRPC server", "timeout", timeout)
+	healthSrv.Shutdown()
+	stopped := make(chan struct{})
+	go func() {
+		srv.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(timeout):
+		slog.Warn("Graceful shutdown timed out, cancelling in-flight calls")
+		srv.Stop()
+	}
+	if err := <-errCh; err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+		return err
+	}
+	slog.Info("gRPC server stopped")
+	return nil
+}
+
+func (s *Server) Search(ctx context.Context, req *raggerv1.SearchRequest) (*raggerv1.SearchResponse, error) {
+	cfg, _ := config.FromContext(ctx)
+	start := time.Now()
+
+	query := strings.TrimSpace(req.GetQuery())
+	if query == "" {
+		return nil, status.Error(codes.InvalidArgument, "query is required")
+	}
+	opts, err := searchOptions(cfg, req.GetK(), req.GetFilter(), req.GetOptions())
+	if err != nil {
+		return nil, err
+	}
+	hits, err := s.searcher.Search(ctx, query, openai.EmbeddingModel(cfg.Model), opts)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &raggerv1.SearchResponse{Hits: toHits(hits), TookMs: uint32(time.Since(start).Milliseconds())}, nil
+}
+
+func (s *Server) Answer(req *raggerv1.AnswerRequest, stream grpc.ServerStreamingServer[raggerv1.AnswerResponse]) error {
+	ctx := stream.Context()
+	cfg, _ := config.FromContext(ctx)
+
+	if s.answerer == nil {
+		return status.Error(codes.Unimplemented, "answers are not configured; set up the [answer] provider")
+	}
+	question := strings.TrimSpace(req.GetQuestion())
+	if question == "" {
+		return status.Error(codes.InvalidArgument, "question is required")
+	}
+	searchOpts, err := searchOptions(cfg, req.GetK(), req.GetFilter(), req.GetOptions())
+	if err != nil {
+		return err
+	}
+	opts := answer.OptionsFromConfig(cfg)
+	opts.Search = searchOpts
+
+	ans, err := s.answerer.Answer(ctx, question, openai.EmbeddingModel(cfg.Model), opts, func(delta string) error {
+		return stream.Send(&raggerv1.AnswerResponse{Event: &raggerv1.AnswerResponse_Delta{Delta: delta}})
+	})
+	if err != nil {
+		return toStatus(err)
+	}
+	return stream.Send(&raggerv1.AnswerResponse{Event: &raggerv1.AnswerResponse_Done{Done: &raggerv1.AnswerDone{
+		Sources: toSources(ans.Sources),
+		Hits:    toHits(ans.Hits),
+	}}})
+}
+
+func (s *Server) IngestDocument(stream grpc.ClientStreamingServer[raggerv1.IngestDocumentRequest, raggerv1.IngestDocumentResponse]) error {
+	ctx := stream.Context()
+	cfg, _ := config.FromContext(ctx)
+	limit := cfg.Server.MaxUploadMB << 20
+
+	first, err := stream.Recv()
+	if err != nil {
+		return toStatus(err)
+	}
+	path := first.GetInfo().GetPath()
+	if path == "" {
+		return status.Error(codes.InvalidArgument, "the first message must carry info with the document path")
+	}
+	var raw bytes.Buffer
+	for {
+		msg, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return toStatus(err)
+		}
+		if msg.GetInfo() != nil {
+			return status.Error(codes.InvalidArgument, "info may only be sent in the first message")
+		}
+		if raw.Len()+len(msg.GetContent()) > limit {
+			return status.Errorf(codes.ResourceExhausted, "document is larger than %d MB", cfg.Server.MaxUploadMB)
+		}
+		raw.Write(msg.GetContent())
+	}
+
+	s.ingestMu.Lock()
+	defer s.ingestMu.Unlock()
+	res, err := s.ingester.IngestDocument(ctx, path, raw.Bytes(), openai.EmbeddingModel(cfg.Model))
+	if err != nil {
+		return toStatus(err)
+	}
+	return stream.SendAndClose(toIngestResponse(res))
+}
+
+// interceptor puts the config into call contexts, bounds Ragger calls
+// with the [server] timeouts and logs them
+type interceptor struct {
+	ctx            context.Context // carries the config
+	requestTimeout time.Duration
+	ingestTimeout  time.Duration
+}
+
+func (i *interceptor) unary(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	ctx, cancel := i.callContext(ctx, info.FullMethod)
+	defer cancel()
+	start := time.Now()
+	resp, err := handler(ctx, req)
+	logCall(info.FullMethod, start, err)
+	return resp, err
+}
+
+func (i *interceptor) stream(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, cancel := i.callContext(ss.Context(), info.FullMethod)
+	defer cancel()
+	start := time.Now()
+	err := handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+	logCall(info.FullMethod, start, err)
+	return err
+}
+
+// callContext adds the config and, for Ragger methods, a timeout; health
+// watches and reflection streams are not bounded
+func (i *interceptor) callContext(ctx context.Context, method string) (context.Context, context.CancelFunc) {
+	cfg, _ := config.FromContext(i.ctx)
+	ctx = config.IntoContext(ctx, cfg)
+	if !strings.HasPrefix(method, "/"+raggerv1.Ragger_ServiceDesc.ServiceName+"/") {
+		return ctx, func() {}
+	}
+	timeout := i.requestTimeout
+	if method == raggerv1.Ragger_IngestDocument_FullMethodName {
+		timeout = i.ingestTimeout
+	}
+	if timeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// serverStream overrides the context of a stream
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context { return s.ctx }
+
+func logCall(method string, start time.Time, err error) {
+	code := status.Code(err)
+	if code == codes.Internal || code == codes.Unknown {
+		slog.Error("gRPC call failed", "method", method, "code", code, "duration", time.Since(start), "error", err)
+		return
+	}
+	slog.Info("gRPC call", "method", method, "code", code, "duration", time.Since(start))
+}
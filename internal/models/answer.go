@@ -0,0 +1,19 @@
+package models
+
+// Source is a context passage given to the LLM under the citation [N]
+type Source struct {
+	N       int    `json:"n"`
+	Title   string `json:"title"`
+	Path    string `json:"path"`
+	DocID   string `json:"doc_id"`
+	ChunkID string `json:"chunk_id"`
+	// Cited reports whether the answer refers to [N]
+	Cited bool `json:"cited"`
+}
+
+// Answer is a generated answer with the passages it was grounded on
+type Answer struct {
+	Text    string   `json:"answer"`
+	Sources []Source `json:"sources"`
+	Hits    []Hit    `json:"hits"`
+}
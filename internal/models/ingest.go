@@ -7,3 +7,16 @@ type ChunkInfo struct {
 	End     int
 	ChunkID string
 }
+
+// IngestResult reports what ingesting a single document changed.
+// Status is "added", "updated", "unchanged" or "skipped" (no text);
+// unchanged documents are not parsed, so their Title is empty.
+type IngestResult struct {
+	DocID         string `json:"doc_id"`
+	Path          string `json:"path"`
+	Title         string `json:"title"`
+	Lang          string `json:"lang"`
+	Status        string `json:"status"`
+	Chunks        int    `json:"chunks"`
+	DeletedChunks int    `json:"deleted_chunks"`
+}
@@ -1,11 +1,55 @@
 package models
 
-// Hit represents a search result from vector database
+// Hit represents a search result from vector database.
+// Score is the ranking score: cosine similarity, BM25 or the fused score of
+// hybrid search; DenseScore and KeywordScore are 0 when the list missed the chunk.
+// After reranking Score equals RerankScore and OriginalScore keeps the
+// retrieval score.
 type Hit struct {
-	Score   float32
-	Title   string
-	Text    string
-	DocID   string
-	ChunkID string
-	Path    string
+	ID      string  `json:"id"`
+	Score   float32 `json:"score"`
+	Title   string  `json:"title"`
+	Text    string  `json:"text"`
+	DocID   string  `json:"doc_id"`
+	ChunkID string  `json:"chunk_id"`
+	Path    string  `json:"path"`
+	Lang    string  `json:"lang,omitempty"`
+	// Start and End are byte offsets of Text in the document text
+	Start int `json:"start"`
+	End   int `json:"end"`
+	// ChunkIDs lists the chunks merged into Text by context expansion;
+	// nil when neighbours were not requested
+	ChunkIDs []string `json:"chunk_ids,omitempty"`
+	// Vector is only set when search needs it (MMR)
+	Vector []float32 `json:"-"`
+
+	DenseScore   float32 `json:"dense_score,omitempty"`
+	KeywordScore float32 `json:"keyword_score,omitempty"`
+
+	Reranked      bool    `json:"reranked,omitempty"`
+	OriginalScore float32 `json:"original_score,omitempty"`
+	RerankScore   float32 `json:"rerank_score,omitempty"`
+}
+
+// DocumentHit is a document found by grouped search. Score is the score
+// of its best chunk; Chunks holds the best-matching chunks in rank order
+// and Matches counts all of its chunks among the candidates.
+type DocumentHit struct {
+	DocID   string  `json:"doc_id"`
+	Title   string  `json:"title"`
+	Path    string  `json:"path"`
+	Score   float32 `json:"score"`
+	Matches int     `json:"matches"`
+	Chunks  []Hit   `json:"chunks"`
+}
+
+// Document is a stored document. Text is merged from all of its chunks
+// and is empty in document listings.
+type Document struct {
+	DocID  string `json:"doc_id"`
+	Title  string `json:"title"`
+	Path   string `json:"path"`
+	Lang   string `json:"lang,omitempty"`
+	Chunks int    `json:"chunks"`
+	Text   string `json:"text,omitempty"`
 }
@@ -0,0 +1,87 @@
+package models
+
+// Payload holds point metadata: strings, integers, floats and bools
+type Payload map[string]any
+
+// String returns a string field or "" when it is missing
+func (p Payload) String(key string) string {
+	s, _ := p[key].(string)
+	return s
+}
+
+// Int returns an integer field; floats are truncated, other values give 0
+func (p Payload) Int(key string) int64 {
+	switch v := p[key].(type) {
+	case int64:
+		return v
+	case int:
+		return int64(v)
+	case float64:
+		return int64(v)
+	}
+	return 0
+}
+
+// Point is a stored vector with its payload. IDs are UUIDs; legacy
+// Qdrant points may carry decimal numeric IDs.
+type Point struct {
+	ID      string
+	Vector  []float32
+	Payload Payload
+}
+
+// ScoredPoint is a search result
+type ScoredPoint struct {
+	Point
+	Score float32
+}
+
+// FieldMatch requires a payload field to equal a keyword
+type FieldMatch struct {
+	Key   string
+	Value string
+}
+
+// Filter selects points by ID and payload keywords.
+// All conditions must hold; empty parts match every point.
+type Filter struct {
+	IDs  []string
+	Must []FieldMatch
+}
+
+// Empty reports whether the filter matches every point
+func (f Filter) Empty() bool {
+	return len(f.IDs) == 0 && len(f.Must) == 0
+}
+
+// CollectionInfo describes vector params of a collection
+type CollectionInfo struct {
+	Name      string
+	Dimension int
+	Distance  string // "Cosine", "Dot", "Euclid", ...
+	Points    uint64
+}
+
+// ScrollRequest pages through points ordered by ID
+type ScrollRequest struct {
+	Filter *Filter
+	Offset string // ID of the first point; "" starts from the beginning
+	Limit  int
+	// PayloadFields limits returned payload keys; nil returns all of them
+	PayloadFields []string
+	WithVectors   bool
+}
+
+// ScrollPage is a page of points; NextOffset is "" on the last page
+type ScrollPage struct {
+	Points     []Point
+	NextOffset string
+}
+
+// SearchRequest finds the nearest points to Vector
+type SearchRequest struct {
+	Vector      []float32
+	Limit       int
+	Filter      *Filter
+	WithVectors bool
+}
@@ -0,0 +1,123 @@
+package answer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+
+	openai "github.com/sashabaranov/go-openai"
+
+	"test-ragger/internal/models"
+)
+
+// ErrNoQuestion is returned for chat requests without a user message
+var ErrNoQuestion = errors.New("chat request has no user message")
+
+// Complete answers an OpenAI chat completions request: the last user
+// message is searched for, the retrieved context is prepended as a system
+// message and the request is forwarded to the configured chat model.
+// The sources are the passages of that context.
+func (u *Usecase) Complete(ctx context.Context, req openai.ChatCompletionRequest, model openai.EmbeddingModel, opts Options) (openai.ChatCompletionResponse, []models.Source, error) {
+	req, hits, err := u.ground(ctx, req, model, opts)
+	if err != nil {
+		return openai.ChatCompletionResponse{}, nil, err
+	}
+	req.Stream = false
+	req.StreamOptions = nil
+
+	resp, err := u.chatClient.CreateChatCompletion(ctx, req)
+	if err != nil {
+		return openai.ChatCompletionResponse{}, nil, fmt.Errorf("chat completion: %w", err)
+	}
+	text := ""
+	if len(resp.Choices) > 0 {
+		text = resp.Choices[0].Message.Content
+	}
+	return resp, sources(text, hits), nil
+}
+
+// CompleteStream is Complete with a streaming upstream request: every
+// chunk is passed to onChunk as it arrives. Sources are returned once the
+// stream ends, with citations of the first choice marked.
+func (u *Usecase) CompleteStream(ctx context.Context, req openai.ChatCompletionRequest, model openai.EmbeddingModel, opts Options, onChunk func(openai.ChatCompletionStreamResponse) error) ([]models.Source, error) {
+	req, hits, err := u.ground(ctx, req, model, opts)
+	if err != nil {
+		return nil, err
+	}
+	req.Stream = true
+
+	stream, err := u.chatClient.CreateChatCompletionStream(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("chat completion: %w", err)
+	}
+	defer stream.Close()
+
+	var text strings.Builder
+	for {
+		chunk, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return sources(text.String(), hits), nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("chat completion stream: %w", err)
+		}
+		for _, c := range chunk.Choices {
+			if c.Index == 0 {
+				text.WriteString(c.Delta.Content)
+			}
+		}
+		if err := onChunk(chunk); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// ground searches for the last user message and returns the upstream
+// request with the context system message and the configured model.
+// The temperature is taken from opts, where callers put the one the client
+// asked for; the token limit defaults to the configured one.
+func (u *Usecase) ground(ctx context.Context, req openai.ChatCompletionRequest, model openai.EmbeddingModel, opts Options) (openai.ChatCompletionRequest, []models.Hit, error) {
+	question := lastUserMessage(req.Messages)
+	if question == "" {
+		return req, nil, ErrNoQuestion
+	}
+
+	hits, err := u.searcher.Search(ctx, question, model, opts.Search)
+	if err != nil {
+		return req, nil, err
+	}
+	slog.Info("Grounding chat completion", "model", opts.Model, "context_passages", len(hits), "messages", len(req.Messages))
+
+	system := openai.ChatCompletionMessage{Role: openai.ChatMessageRoleSystem, Content: u.searcher.BuildPrompt(question, hits)}
+	req.Messages = append([]openai.ChatCompletionMessage{system}, req.Messages...)
+	req.Model = opts.Model
+	req.Temperature = temperature(opts.Temperature)
+	if req.MaxTokens == 0 && req.MaxCompletionTokens == 0 {
+		req.MaxTokens = opts.MaxTokens
+	}
+	return req, hits, nil
+}
+
+// lastUserMessage returns the text of the last user message
+func lastUserMessage(messages []openai.ChatCompletionMessage) string {
+	for i := len(messages) - 1; i >= 0; i-- {
+		m := messages[i]
+		if m.Role != openai.ChatMessageRoleUser {
+			continue
+		}
+		if m.Content != "" || len(m.MultiContent) == 0 {
+			return strings.TrimSpace(m.Content)
+		}
+		var parts []string
+		for _, p := range m.MultiContent {
+			if p.Type == openai.ChatMessagePartTypeText {
+				parts = append(parts, p.Text)
+			}
+		}
+		return strings.TrimSpace(strings.Join(parts, "\n"))
+	}
+	return ""
+}
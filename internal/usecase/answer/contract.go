@@ -0,0 +1,22 @@
+package answer
+
+import (
+	"context"
+
+	openai "github.com/sashabaranov/go-openai"
+
+	"test-ragger/internal/models"
+	"test-ragger/internal/usecase/search"
+)
+
+// Searcher retrieves context for the question and builds the prompt
+type Searcher interface {
+	Search(ctx context.Context, query string, model openai.EmbeddingModel, opts search.Options) ([]models.Hit, error)
+	BuildPrompt(query string, hits []models.Hit) string
+}
+
+// ChatClient represents a chat-completions-compatible API client
+type ChatClient interface {
+	CreateChatCompletion(ctx context.Context, req openai.ChatCompletionRequest) (openai.ChatCompletionResponse, error)
+	CreateChatCompletionStream(ctx context.Context, req openai.ChatCompletionRequest) (*openai.ChatCompletionStream, error)
+}
@@ -0,0 +1,150 @@
+package answer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	openai "github.com/sashabaranov/go-openai"
+
+	"test-ragger/internal/configure/config"
+	"test-ragger/internal/models"
+	"test-ragger/internal/usecase/search"
+)
+
+// noContextAnswer is returned without calling the model when search finds nothing
+const noContextAnswer = "В базе знаний не найдено материалов по этому вопросу."
+
+// citationRe matches [1], [1, 3] and [2,4] in answers
+var citationRe = regexp.MustCompile(`\[(\d+(?:\s*,\s*\d+)*)\]`)
+
+// Options tune a single answer
+type Options struct {
+	Search search.Options
+
+	Model       string
+	Temperature float32
+	MaxTokens   int // 0 leaves the limit to the provider
+}
+
+// OptionsFromConfig returns answer options set by config.toml and CLI flags
+func OptionsFromConfig(cfg config.Config) Options {
+	return Options{
+		Search:      search.OptionsFromConfig(cfg),
+		Model:       cfg.Answer.Model,
+		Temperature: cfg.Answer.Temperature,
+		MaxTokens:   cfg.Answer.MaxTokens,
+	}
+}
+
+// Usecase generates answers grounded on search results
+type Usecase struct {
+	searcher   Searcher
+	chatClient ChatClient
+}
+
+// New creates new answer usecase
+func New(searcher Searcher, chatClient ChatClient) *Usecase {
+	return &Usecase{searcher: searcher, chatClient: chatClient}
+}
+
+// Answer retrieves context for the question, sends the prompt to the chat
+// model and passes answer tokens to onDelta as they arrive (onDelta may be
+// nil). The returned answer maps [n] citations back to their sources.
+func (u *Usecase) Answer(ctx context.Context, question string, model openai.EmbeddingModel, opts Options, onDelta func(string) error) (models.Answer, error) {
+	if onDelta == nil {
+		onDelta = func(string) error { return nil }
+	}
+
+	hits, err := u.searcher.Search(ctx, question, model, opts.Search)
+	if err != nil {
+		return models.Answer{}, err
+	}
+	if len(hits) == 0 {
+		slog.Info("No context found, skipping the chat model")
+		return models.Answer{Text: noContextAnswer}, onDelta(noContextAnswer)
+	}
+
+	prompt := u.searcher.BuildPrompt(question, hits)
+	slog.Info("Requesting answer", "model", opts.Model, "context_passages", len(hits))
+	stream, err := u.chatClient.CreateChatCompletionStream(ctx, openai.ChatCompletionRequest{
+		Model:       opts.Model,
+		Temperature: temperature(opts.Temperature),
+		MaxTokens:   opts.MaxTokens,
+		Messages:    []openai.ChatCompletionMessage{{Role: openai.ChatMessageRoleUser, Content: prompt}},
+		Stream:      true,
+	})
+	if err != nil {
+		return models.Answer{}, fmt.Errorf("chat completion: %w", err)
+	}
+	defer stream.Close()
+
+	var text strings.Builder
+	for {
+		resp, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return models.Answer{Text: text.String(), Hits: hits}, fmt.Errorf("chat completion stream: %w", err)
+		}
+		if len(resp.Choices) == 0 {
+			continue
+		}
+		delta := resp.Choices[0].Delta.Content
+		if delta == "" {
+			continue
+		}
+		text.WriteString(delta)
+		if err := onDelta(delta); err != nil {
+			return models.Answer{Text: text.String(), Hits: hits}, err
+		}
+	}
+
+	answer := models.Answer{Text: text.String(), Hits: hits, Sources: sources(text.String(), hits)}
+	slog.Info("Answer completed", "characters", text.Len(), "cited", countCited(answer.Sources))
+	return answer, nil
+}
+
+// sources lists the context passages in prompt order and marks those
+// the answer cites
+func sources(text string, hits []models.Hit) []models.Source {
+	out := make([]models.Source, len(hits))
+	for i, h := range hits {
+		out[i] = models.Source{N: i + 1, Title: h.Title, Path: h.Path, DocID: h.DocID, ChunkID: h.ChunkID}
+	}
+	for _, m := range citationRe.FindAllStringSubmatch(text, -1) {
+		for _, s := range strings.Split(m[1], ",") {
+			n, err := strconv.Atoi(strings.TrimSpace(s))
+			if err == nil && n >= 1 && n <= len(out) {
+				out[n-1].Cited = true
+			}
+		}
+	}
+	return out
+}
+
+func countCited(sources []models.Source) int {
+	n := 0
+	for _, s := range sources {
+		if s.Cited {
+			n++
+		}
+	}
+	return n
+}
+
+// temperature keeps a zero temperature in upstream requests: go-openai
+// omits a zero value, which providers read as their default
+func temperature(t float32) float32 {
+	if t == 0 {
+		return math.SmallestNonzeroFloat32
+	}
+	return t
+}
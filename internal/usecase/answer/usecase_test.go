@@ -0,0 +1,245 @@
+package answer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	openai "github.com/sashabaranov/go-openai"
+
+	"test-ragger/internal/models"
+	"test-ragger/internal/usecase/search"
+)
+
+var testHits = []models.Hit{
+	{Title: "Capitals", Path: "a.html", DocID: "a", ChunkID: "a#0"},
+	{Title: "Rivers", Path: "b.html", DocID: "b", ChunkID: "b#0"},
+	{Title: "France", Path: "c.html", DocID: "c", ChunkID: "c#2"},
+}
+
+type fakeSearcher struct {
+	hits []models.Hit
+}
+
+func (s fakeSearcher) Search(ctx context.Context, query string, model openai.EmbeddingModel, opts search.Options) ([]models.Hit, error) {
+	return s.hits, nil
+}
+
+func (s fakeSearcher) BuildPrompt(query string, hits []models.Hit) string {
+	return fmt.Sprintf("%d passages for %s", len(hits), query)
+}
+
+// sseServer streams deltas as chat completion chunks. A delta starting with
+// "error:" is sent as a stream error event and ends the stream.
+func sseServer(t *testing.T, deltas ...string) (*httptest.Server, *atomic.Pointer[openai.ChatCompletionRequest]) {
+	t.Helper()
+	var got atomic.Pointer[openai.ChatCompletionRequest]
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/chat/completions" {
+			http.NotFound(w, r)
+			return
+		}
+		var req openai.ChatCompletionRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		got.Store(&req)
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		flusher := w.(http.Flusher)
+		for _, d := range deltas {
+			if msg, ok := strings.CutPrefix(d, "error:"); ok {
+				fmt.Fprintf(w, "data: {\"error\":{\"message\":%q,\"type\":\"server_error\"}}\n\n", msg)
+				flusher.Flush()
+				return
+			}
+			chunk := openai.ChatCompletionStreamResponse{
+				Object:  "chat.completion.chunk",
+				Model:   req.Model,
+				Choices: []openai.ChatCompletionStreamChoice{{Delta: openai.ChatCompletionStreamChoiceDelta{Content: d}}},
+			}
+			b, _ := json.Marshal(chunk)
+			fmt.Fprintf(w, "data: %s\n\n", b)
+			flusher.Flush()
+		}
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &got
+}
+
+func newTestUsecase(srv *httptest.Server, hits []models.Hit) *Usecase {
+	cfg := openai.DefaultConfig("test")
+	cfg.BaseURL = srv.URL + "/v1"
+	return New(fakeSearcher{hits: hits}, openai.NewClientWithConfig(cfg))
+}
+
+func TestAnswerStreams(t *testing.T) {
+	srv, got := sseServer(t, "Paris", " is the capital", " of France [1, ", "3].")
+	u := newTestUsecase(srv, testHits)
+
+	var deltas []string
+	ans, err := u.Answer(context.Background(), "capital of France?", "m", Options{Model: "chat"}, func(d string) error {
+		deltas = append(deltas, d)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const want = "Paris is the capital of France [1, 3]."
+	if ans.Text != want {
+		t.Errorf("text = %q, want %q", ans.Text, want)
+	}
+	if strings.Join(deltas, "") != want || len(deltas) != 4 {
+		t.Errorf("deltas = %q, want the 4 streamed pieces", deltas)
+	}
+	req := got.Load()
+	if req == nil || !req.Stream || req.Model != "chat" || req.Messages[0].Content != "3 passages for capital of France?" {
+		t.Errorf("upstream request = %+v", req)
+	}
+
+	// a citation split across chunks is still mapped
+	cited := []bool{true, false, true}
+	if len(ans.Sources) != len(cited) {
+		t.Fatalf("sources = %+v, want %d", ans.Sources, len(cited))
+	}
+	for i, s := range ans.Sources {
+		if s.N != i+1 || s.DocID != testHits[i].DocID || s.Cited != cited[i] {
+			t.Errorf("source %d = %+v, want n=%d doc_id=%s cited=%v", i, s, i+1, testHits[i].DocID, cited[i])
+		}
+	}
+}
+
+func TestAnswerMidStreamError(t *testing.T) {
+	srv, _ := sseServer(t, "Paris", " is [1]", "error:upstream overloaded")
+	u := newTestUsecase(srv, testHits)
+
+	var streamed strings.Builder
+	ans, err := u.Answer(context.Background(), "capital of France?", "m", Options{Model: "chat"}, func(d string) error {
+		streamed.WriteString(d)
+		return nil
+	})
+	if err == nil || !strings.Contains(err.Error(), "upstream overloaded") {
+		t.Fatalf("err = %v, want the stream error", err)
+	}
+	if ans.Text != "Paris is [1]" || streamed.String() != ans.Text {
+		t.Errorf("partial text = %q, streamed %q", ans.Text, streamed.String())
+	}
+	if ans.Sources != nil {
+		t.Errorf("sources of a broken answer = %+v, want none", ans.Sources)
+	}
+}
+
+func TestAnswerWithoutContext(t *testing.T) {
+	srv, got := sseServer(t, "unused")
+	u := newTestUsecase(srv, nil)
+
+	ans, err := u.Answer(context.Background(), "anything?", "m", Options{Model: "chat"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ans.Text != noContextAnswer || got.Load() != nil {
+		t.Errorf("answer = %q, chat model called: %v", ans.Text, got.Load() != nil)
+	}
+}
+
+func TestCompleteStreamSources(t *testing.T) {
+	srv, got := sseServer(t, "See ", "[2]", " and [7].")
+	u := newTestUsecase(srv, testHits)
+
+	req := openai.ChatCompletionRequest{Messages: []openai.ChatCompletionMessage{
+		{Role: openai.ChatMessageRoleUser, Content: "rivers?"},
+	}}
+	var chunks int
+	srcs, err := u.CompleteStream(context.Background(), req, "m", Options{Model: "chat"}, func(openai.ChatCompletionStreamResponse) error {
+		chunks++
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if chunks != 3 {
+		t.Errorf("chunks = %d, want 3", chunks)
+	}
+	if up := got.Load(); up == nil || up.Messages[0].Role != openai.ChatMessageRoleSystem {
+		t.Errorf("upstream request has no context system message: %+v", up)
+	}
+	for i, s := range srcs {
+		if s.Cited != (i == 1) {
+			t.Errorf("source %d cited = %v", s.N, s.Cited)
+		}
+	}
+}
+
+func TestSourcesCitations(t *testing.T) {
+	tests := []struct {
+		text  string
+		cited []int
+	}{
+		{"no citations", nil},
+		{"one [2].", []int{2}},
+		{"list [1, 3]", []int{1, 3}},
+		{"tight list [3,1]", []int{1, 3}},
+		{"repeated [1] and [1]", []int{1}},
+		{"out of range [4] and [0]", nil},
+		{"partly out of range [2, 9]", []int{2}},
+		{"huge [99999999999999999999]", nil},
+		{"not a citation [a] [1-3] [ 2 ]", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			srcs := sources(tt.text, testHits)
+			if len(srcs) != len(testHits) {
+				t.Fatalf("got %d sources, want %d", len(srcs), len(testHits))
+			}
+			var cited []int
+			for _, s := range srcs {
+				if s.Cited {
+					cited = append(cited, s.N)
+				}
+			}
+			if fmt.Sprint(cited) != fmt.Sprint(tt.cited) {
+				t.Errorf("cited = %v, want %v", cited, tt.cited)
+			}
+		})
+	}
+}
+
+func TestCompleteStreamTemperature(t *testing.T) {
+	tests := []struct {
+		name string
+		opts float32
+		want float32
+	}{
+		{"explicit zero is sent", 0, math.SmallestNonzeroFloat32},
+		{"value", 0.7, 0.7},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, got := sseServer(t, "ok")
+			u := newTestUsecase(srv, testHits)
+
+			// a temperature in the request is replaced by opts, where callers put it
+			req := openai.ChatCompletionRequest{Temperature: 1.5, Messages: []openai.ChatCompletionMessage{
+				{Role: openai.ChatMessageRoleUser, Content: "rivers?"},
+			}}
+			_, err := u.CompleteStream(context.Background(), req, "m", Options{Model: "chat", Temperature: tt.opts}, func(openai.ChatCompletionStreamResponse) error {
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if up := got.Load(); up.Temperature != tt.want {
+				t.Errorf("upstream temperature = %v, want %v", up.Temperature, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,270 @@
+package ingest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	openai "github.com/sashabaranov/go-openai"
+
+	"test-ragger/internal/configure/config"
+	"test-ragger/internal/utils"
+)
+
+// batchLinger is how long a partially filled batch waits for more chunks
+// before it is sent anyway
+const batchLinger = 200 * time.Millisecond
+
+// chunkRef points to a single chunk of a document waiting for its embedding
+type chunkRef struct {
+	doc    *document
+	idx    int
+	text   string
+	tokens int
+}
+
+// batch is a group of chunks (possibly from different documents)
+// embedded with a single API request
+type batch struct {
+	items  []chunkRef
+	tokens int
+}
+
+// batcher packs chunks into batches bounded by input count and token budget
+type batcher struct {
+	maxInputs int
+	maxTokens int
+	cur       *batch
+}
+
+func newBatcher(maxInputs, maxTokens int) *batcher {
+	return &batcher{maxInputs: max(maxInputs, 1), maxTokens: maxTokens, cur: &batch{}}
+}
+
+// fits reports whether item can be added without exceeding the limits.
+// An empty batch always accepts an item, even an oversized one.
+func (b *batcher) fits(item chunkRef) bool {
+	if len(b.cur.items) == 0 {
+		return true
+	}
+	if len(b.cur.items)+1 > b.maxInputs {
+		return false
+	}
+	return b.maxTokens <= 0 || b.cur.tokens+item.tokens <= b.maxTokens
+}
+
+func (b *batcher) add(item chunkRef) {
+	b.cur.items = append(b.cur.items, item)
+	b.cur.tokens += item.tokens
+}
+
+func (b *batcher) empty() bool { return len(b.cur.items) == 0 }
+
+func (b *batcher) take() *batch {
+	cur := b.cur
+	b.cur = &batch{}
+	return cur
+}
+
+// runEmbedStage packs chunks of incoming documents into batches and embeds
+// them with a pool of workers. A document is sent downstream once all of
+// its chunks have vectors.
+func (u *Usecase) runEmbedStage(ctx context.Context, wg *sync.WaitGroup, in <-chan *document, fail func(error), j *journal, model openai.EmbeddingModel) <-chan *document {
+	cfg, _ := config.FromContext(ctx)
+	w := cfg.Ingest
+
+	out := make(chan *document, w.QueueSize)
+	batches := make(chan *batch, max(w.EmbedWorkers, 1))
+
+	send := func(d *document) bool {
+		select {
+		case out <- d:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	var stageWG sync.WaitGroup
+
+	// packing loop
+	stageWG.Add(1)
+	go func() {
+		defer stageWG.Done()
+		defer close(batches)
+
+		b := newBatcher(w.EmbedBatchSize, w.EmbedBatchTokens)
+		flush := func() bool {
+			if b.empty() {
+				return true
+			}
+			select {
+			case batches <- b.take():
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		linger := time.NewTimer(batchLinger)
+		linger.Stop()
+		defer linger.Stop()
+
+		for {
+			select {
+			case d, ok := <-in:
+				if !ok {
+					flush()
+					return
+				}
+				if d.resumed {
+					if !send(d) {
+						return
+					}
+					continue
+				}
+				if d.skip || len(d.chunks) == 0 {
+					d.skip = true
+					if !send(d) {
+						return
+					}
+					continue
+				}
+				if err := j.markInFlight(d); err != nil {
+					fail(err)
+					return
+				}
+
+				d.vectors = make([][]float32, len(d.chunks))
+				d.pending.Store(int64(len(d.chunks)))
+				for i, c := range d.chunks {
+					// Clean chunk text from invalid UTF-8 characters
+					text := utils.CleanUTF8(c.Text)
+					item := chunkRef{doc: d, idx: i, text: text, tokens: utils.EstimateTokens(text)}
+					if !b.fits(item) && !flush() {
+						return
+					}
+					b.add(item)
+				}
+				linger.Reset(batchLinger)
+			case <-linger.C:
+				if !flush() {
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	// embedding workers
+	for i := 0; i < max(w.EmbedWorkers, 1); i++ {
+		stageWG.Add(1)
+		go func() {
+			defer stageWG.Done()
+			for b := range batches {
+				if ctx.Err() != nil {
+					return
+				}
+
+				texts := make([]string, len(b.items))
+				for i, it := range b.items {
+					texts[i] = it.text
+				}
+				slog.Debug("Embedding batch", "inputs", len(texts), "estimated_tokens", b.tokens)
+				vecs, err := u.embedTexts(ctx, model, texts)
+				if err != nil {
+					fail(fmt.Errorf("embedding: %w", err))
+					return
+				}
+
+				for i, it := range b.items {
+					if len(vecs[i]) != cfg.EmbeddingDim {
+						fail(fmt.Errorf("dim mismatch: got %d want %d", len(vecs[i]), cfg.EmbeddingDim))
+						return
+					}
+					it.doc.vectors[it.idx] = vecs[i]
+					if it.doc.pending.Add(-1) == 0 && !send(it.doc) {
+						return
+					}
+				}
+			}
+		}()
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		stageWG.Wait()
+		close(out)
+	}()
+	return out
+}
+
+// embedTexts embeds texts with one request, splitting the batch in halves
+// when the provider rejects it as too large
+func (u *Usecase) embedTexts(ctx context.Context, model openai.EmbeddingModel, texts []string) ([][]float32, error) {
+	cfg, _ := config.FromContext(ctx)
+	res, err := u.embeddingClient.CreateEmbeddings(ctx, openai.EmbeddingRequest{
+		Model:      model,
+		Input:      texts,
+		Dimensions: cfg.Embedding.Dimensions,
+	})
+	if err != nil {
+		if len(texts) > 1 && isBatchTooLarge(err) {
+			half := len(texts) / 2
+			slog.Warn("Embedding batch rejected as too large, splitting", "inputs", len(texts), "error", err)
+			left, err := u.embedTexts(ctx, model, texts[:half])
+			if err != nil {
+				return nil, err
+			}
+			right, err := u.embedTexts(ctx, model, texts[half:])
+			if err != nil {
+				return nil, err
+			}
+			return append(left, right...), nil
+		}
+		return nil, err
+	}
+
+	if len(res.Data) != len(texts) {
+		return nil, fmt.Errorf("got %d embeddings for %d inputs", len(res.Data), len(texts))
+	}
+	vecs := make([][]float32, len(texts))
+	for _, e := range res.Data {
+		if e.Index < 0 || e.Index >= len(texts) || vecs[e.Index] != nil {
+			return nil, fmt.Errorf("unexpected embedding index %d", e.Index)
+		}
+		vecs[e.Index] = e.Embedding
+	}
+	return vecs, nil
+}
+
+// isBatchTooLarge detects provider errors caused by request size
+func isBatchTooLarge(err error) bool {
+	var apiErr *openai.APIError
+	if errors.As(err, &apiErr) {
+		switch apiErr.HTTPStatusCode {
+		case http.StatusRequestEntityTooLarge:
+			return true
+		case http.StatusBadRequest:
+			msg := strings.ToLower(apiErr.Message)
+			for _, s := range []string{"too many", "too large", "maximum", "max_tokens", "context length"} {
+				if strings.Contains(msg, s) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	var reqErr *openai.RequestError
+	if errors.As(err, &reqErr) {
+		return reqErr.HTTPStatusCode == http.StatusRequestEntityTooLarge
+	}
+	return false
+}
@@ -0,0 +1,112 @@
+package ingest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	openai "github.com/sashabaranov/go-openai"
+
+	"test-ragger/internal/configure/config"
+	"test-ragger/internal/utils"
+)
+
+// limitedEmbedder rejects requests over maxInputs texts or maxTokens
+// estimated tokens with err, and answers the rest in reverse index order.
+// The vector of "chunk-N" is {N}.
+type limitedEmbedder struct {
+	maxInputs int
+	maxTokens int
+	err       error
+	requests  []int
+}
+
+func (e *limitedEmbedder) CreateEmbeddings(ctx context.Context, conv openai.EmbeddingRequestConverter) (openai.EmbeddingResponse, error) {
+	req := conv.Convert()
+	texts := req.Input.([]string)
+	e.requests = append(e.requests, len(texts))
+
+	tokens := 0
+	for _, t := range texts {
+		tokens += utils.EstimateTokens(t)
+	}
+	if (e.maxInputs > 0 && len(texts) > e.maxInputs) || (e.maxTokens > 0 && tokens > e.maxTokens) {
+		return openai.EmbeddingResponse{}, e.err
+	}
+
+	var res openai.EmbeddingResponse
+	for i := len(texts) - 1; i >= 0; i-- {
+		n, err := strconv.Atoi(strings.TrimPrefix(texts[i], "chunk-"))
+		if err != nil {
+			return res, err
+		}
+		res.Data = append(res.Data, openai.Embedding{Index: i, Embedding: []float32{float32(n)}})
+	}
+	return res, nil
+}
+
+func TestEmbedTextsSplitsRejectedBatches(t *testing.T) {
+	ctx := config.IntoContext(context.Background(), config.Defaults())
+	tooMany := &openai.APIError{HTTPStatusCode: http.StatusBadRequest, Message: "Too many inputs. The max number of inputs is 3."}
+	tooLarge := &openai.RequestError{HTTPStatusCode: http.StatusRequestEntityTooLarge, Err: errors.New("request entity too large")}
+
+	tests := []struct {
+		name     string
+		texts    int
+		client   *limitedEmbedder
+		requests []int
+		wantErr  bool
+	}{
+		{"fits", 4, &limitedEmbedder{maxInputs: 4, err: tooMany}, []int{4}, false},
+		{"too many inputs", 10, &limitedEmbedder{maxInputs: 3, err: tooMany}, []int{10, 5, 2, 3, 5, 2, 3}, false},
+		{"too many tokens", 8, &limitedEmbedder{maxTokens: 10, err: tooLarge}, []int{8, 4, 2, 2, 4, 2, 2}, false},
+		{"single oversized text", 1, &limitedEmbedder{maxTokens: 1, err: tooLarge}, []int{1}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			texts := make([]string, tt.texts)
+			want := make([][]float32, tt.texts)
+			for i := range texts {
+				texts[i] = fmt.Sprintf("chunk-%d", i)
+				want[i] = []float32{float32(i)}
+			}
+			u := &Usecase{embeddingClient: tt.client}
+
+			vecs, err := u.embedTexts(ctx, "m", texts)
+			if !slices.Equal(tt.client.requests, tt.requests) {
+				t.Errorf("requests = %v, want %v", tt.client.requests, tt.requests)
+			}
+			if tt.wantErr {
+				if !errors.Is(err, tt.client.err) {
+					t.Fatalf("err = %v, want the provider error", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			// every vector lands on its own chunk, whatever the split
+			if !slices.EqualFunc(vecs, want, slices.Equal) {
+				t.Errorf("vectors = %v, want %v", vecs, want)
+			}
+		})
+	}
+}
+
+func TestEmbedTextsKeepsOtherErrors(t *testing.T) {
+	ctx := config.IntoContext(context.Background(), config.Defaults())
+	client := &limitedEmbedder{maxInputs: 1, err: &openai.APIError{HTTPStatusCode: http.StatusUnauthorized, Message: "invalid api key"}}
+	u := &Usecase{embeddingClient: client}
+
+	if _, err := u.embedTexts(ctx, "m", []string{"chunk-0", "chunk-1"}); err == nil {
+		t.Fatal("want the provider error")
+	}
+	if len(client.requests) != 1 {
+		t.Errorf("requests = %v, want no split", client.requests)
+	}
+}
@@ -0,0 +1,207 @@
+package ingest
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+)
+
+// Checkpoint journal events
+const (
+	eventStart    = "start"
+	eventInFlight = "inflight"
+	eventDone     = "done"
+)
+
+// journalEntry is a single line of the checkpoint journal
+type journalEntry struct {
+	Event string `json:"event"`
+	Time  string `json:"time"`
+
+	// start
+	Store        string `json:"store,omitempty"`
+	Collection   string `json:"collection,omitempty"`
+	Model        string `json:"model,omitempty"`
+	Dir          string `json:"dir,omitempty"`
+	ChunkSize    int    `json:"chunk_size,omitempty"`
+	ChunkOverlap int    `json:"chunk_overlap,omitempty"`
+
+	// inflight, done
+	DocID string `json:"doc_id,omitempty"`
+	Path  string `json:"path,omitempty"`
+	Hash  string `json:"hash,omitempty"`
+}
+
+// sameRun reports whether two start entries describe the same ingest run
+func (e journalEntry) sameRun(o journalEntry) bool {
+	return e.Store == o.Store &&
+		e.Collection == o.Collection &&
+		e.Model == o.Model &&
+		e.Dir == o.Dir &&
+		e.ChunkSize == o.ChunkSize &&
+		e.ChunkOverlap == o.ChunkOverlap
+}
+
+// journal is an append-only JSONL checkpoint of an ingest run. It records
+// documents whose chunks went into embedding batches and documents
+// whose points reached the vector store, so an interrupted run can be resumed.
+type journal struct {
+	mu   sync.Mutex
+	path string
+	f    *os.File
+	enc  *json.Encoder
+
+	// completed maps doc_id to the content hash written by a previous run
+	completed map[string]string
+	inFlight  int
+}
+
+// openJournal starts a new journal or, with resume, continues the existing
+// one if it belongs to the same run
+func openJournal(path string, start journalEntry, resume bool) (*journal, error) {
+	j := &journal{path: path, completed: make(map[string]string)}
+
+	flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	if resume {
+		ok, err := j.load(start)
+		if err != nil {
+			return nil, fmt.Errorf("read checkpoint %s: %w", path, err)
+		}
+		if ok {
+			flags = os.O_WRONLY | os.O_APPEND
+		} else {
+			j.completed = make(map[string]string)
+			j.inFlight = 0
+		}
+	}
+
+	f, err := os.OpenFile(path, flags, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("open checkpoint %s: %w", path, err)
+	}
+	j.f = f
+	j.enc = json.NewEncoder(f)
+
+	if flags&os.O_TRUNC != 0 {
+		start.Event = eventStart
+		if err := j.write(start); err != nil {
+			f.Close()
+			return nil, err
+		}
+	}
+	return j, nil
+}
+
+// load replays the journal. It returns false when there is nothing
+// to resume or the journal belongs to a different run.
+func (j *journal) load(start journalEntry) (bool, error) {
+	f, err := os.Open(j.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		slog.Info("No checkpoint found, starting from the beginning", "path", j.path)
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	inFlight := make(map[string]bool)
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	first := true
+	for sc.Scan() {
+		var e journalEntry
+		if err := json.Unmarshal(sc.Bytes(), &e); err != nil {
+			// a torn last line after a crash is expected
+			slog.Warn("Ignoring malformed checkpoint entry", "path", j.path, "error", err)
+			continue
+		}
+		if first {
+			first = false
+			if e.Event != eventStart || !e.sameRun(start) {
+				slog.Warn("Checkpoint belongs to a different run, starting over", "path", j.path)
+				return false, nil
+			}
+			continue
+		}
+		switch e.Event {
+		case eventInFlight:
+			inFlight[e.DocID] = true
+		case eventDone:
+			j.completed[e.DocID] = e.Hash
+			delete(inFlight, e.DocID)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return false, err
+	}
+
+	j.inFlight = len(inFlight)
+	slog.Info("Resuming ingest from checkpoint", "path", j.path, "completed", len(j.completed), "in_flight", j.inFlight)
+	return true, nil
+}
+
+// completedHash returns the content hash recorded for a finished document.
+// A nil journal (dry run) has no completed documents.
+func (j *journal) completedHash(docID string) (string, bool) {
+	if j == nil {
+		return "", false
+	}
+	h, ok := j.completed[docID]
+	return h, ok
+}
+
+func (j *journal) markInFlight(d *document) error {
+	if j == nil {
+		return nil
+	}
+	return j.write(journalEntry{Event: eventInFlight, DocID: d.docID, Path: d.path, Hash: d.hash})
+}
+
+func (j *journal) markDone(d *document) error {
+	if j == nil {
+		return nil
+	}
+	return j.write(journalEntry{Event: eventDone, DocID: d.docID, Path: d.path, Hash: d.hash})
+}
+
+func (j *journal) write(e journalEntry) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	e.Time = time.Now().Format(time.RFC3339)
+	if err := j.enc.Encode(e); err != nil {
+		return fmt.Errorf("write checkpoint: %w", err)
+	}
+	return nil
+}
+
+// Close flushes the journal to disk and keeps it for a later resume
+func (j *journal) Close() error {
+	if j == nil {
+		return nil
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if err := j.f.Sync(); err != nil {
+		j.f.Close()
+		return err
+	}
+	return j.f.Close()
+}
+
+// Remove deletes the journal after a successful run
+func (j *journal) Remove() error {
+	if j == nil {
+		return nil
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.f.Close()
+	return os.Remove(j.path)
+}
@@ -2,8 +2,8 @@ package ingest
 
 import (
 	"context"
+	"io"
 
-	qdrant "github.com/qdrant/go-client/qdrant"
 	openai "github.com/sashabaranov/go-openai"
 
 	"test-ragger/internal/models"
@@ -14,20 +14,25 @@ type EmbeddingClient interface {
 	CreateEmbeddings(ctx context.Context, req openai.EmbeddingRequestConverter) (openai.EmbeddingResponse, error)
 }
 
-// QdrantCollectionClient handles collection operations
-type QdrantCollectionClient interface {
-	Get(ctx context.Context, req *qdrant.GetCollectionInfoRequest) (*qdrant.GetCollectionInfoResponse, error)
-	Create(ctx context.Context, req *qdrant.CreateCollection) (*qdrant.CollectionOperationResponse, error)
+// VectorStore stores chunk vectors (Qdrant or the embedded local store)
+type VectorStore interface {
+	CollectionInfo(ctx context.Context, collection string) (*models.CollectionInfo, error)
+	CreateCollection(ctx context.Context, collection string, dim int) error
+	Upsert(ctx context.Context, collection string, points []models.Point) error
+	Scroll(ctx context.Context, collection string, req models.ScrollRequest) (models.ScrollPage, error)
+	Delete(ctx context.Context, collection string, filter models.Filter) error
 }
 
-// QdrantPointsClient handles point operations
-type QdrantPointsClient interface {
-	Upsert(ctx context.Context, req *qdrant.UpsertPoints) (*qdrant.PointsOperationResponse, error)
+// HTMLParser extracts text from HTML content. lang is the language
+// declared by the page or "" when it declares none.
+type HTMLParser interface {
+	ToText(ctx context.Context, r io.Reader, path string) (text, title, lang string, err error)
 }
 
-// HTMLParser extracts text from HTML content
-type HTMLParser interface {
-	ToText(ctx context.Context, path string) (text, title string, err error)
+// LangDetector identifies the language of a text; ok is false when
+// the text is too short or ambiguous
+type LangDetector interface {
+	Detect(text string) (lang string, ok bool)
 }
 
 // TextChunker splits text into chunks
@@ -0,0 +1,144 @@
+package ingest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	openai "github.com/sashabaranov/go-openai"
+
+	"test-ragger/internal/configure/config"
+	"test-ragger/internal/models"
+	"test-ragger/internal/utils"
+)
+
+// ErrDocumentNotFound is returned when deleting a document the collection does not hold
+var ErrDocumentNotFound = errors.New("document not found")
+
+// IngestDocument ingests a single uploaded HTML document under path, which
+// also determines its doc_id. Like Run it skips unchanged documents and
+// deletes chunks an update no longer produces. Uploaded documents are kept
+// by directory runs, whose pruning only removes documents of deleted files.
+func (u *Usecase) IngestDocument(ctx context.Context, path string, raw []byte, model openai.EmbeddingModel) (models.IngestResult, error) {
+	cfg, _ := config.FromContext(ctx)
+
+	existed, err := u.ensureCollection(ctx, cfg.Collection, cfg.EmbeddingDim, false)
+	if err != nil {
+		return models.IngestResult{}, fmt.Errorf("ensureCollection: %w", err)
+	}
+
+	d := &document{path: path, source: sourceUpload}
+	state := make(map[string]*indexedDoc)
+	if existed {
+		filter := &models.Filter{Must: []models.FieldMatch{{Key: "doc_id", Value: docIDFor(path)}}}
+		if state, err = u.loadIndexState(ctx, cfg.Collection, filter); err != nil {
+			return models.IngestResult{}, fmt.Errorf("load index state: %w", err)
+		}
+	}
+
+	if err := u.parseHTML(ctx, d, raw, state, nil, model); err != nil {
+		return models.IngestResult{}, err
+	}
+	res := models.IngestResult{DocID: d.docID, Path: path, Title: d.title, Lang: d.lang, Status: statusNames[d.status]}
+	if d.skip {
+		if prev, ok := state[d.docID]; ok && d.status == statusUnchanged {
+			res.Lang = prev.docLang
+			res.Chunks = len(prev.points)
+		} else {
+			res.Status = "skipped"
+		}
+		slog.Info("Document not ingested", "path", path, "doc_id", d.docID, "status", res.Status)
+		return res, nil
+	}
+
+	if err := u.chunk(ctx, d); err != nil {
+		return res, err
+	}
+	if err := u.embedDocument(ctx, d, model); err != nil {
+		return res, fmt.Errorf("embedding: %w", err)
+	}
+	if err := u.upsert(ctx, d, nil, model); err != nil {
+		return res, err
+	}
+	res.Chunks = len(d.points)
+
+	if prev, ok := state[d.docID]; ok {
+		orphans := orphanChunks(d, prev)
+		if err := u.prune(ctx, cfg.Collection, orphans, nil, state, false); err != nil {
+			return res, fmt.Errorf("prune: %w", err)
+		}
+		res.DeletedChunks = len(orphans)
+	}
+	slog.Info("Document ingested", "path", path, "doc_id", d.docID, "status", res.Status, "chunks", res.Chunks, "orphan_chunks", res.DeletedChunks)
+	return res, nil
+}
+
+// DeleteDocument removes all chunks of a document and returns their number
+func (u *Usecase) DeleteDocument(ctx context.Context, docID string) (int, error) {
+	cfg, _ := config.FromContext(ctx)
+
+	info, err := u.vectorStore.CollectionInfo(ctx, cfg.Collection)
+	if err != nil {
+		return 0, err
+	}
+	if info == nil {
+		return 0, ErrDocumentNotFound
+	}
+	filter := &models.Filter{Must: []models.FieldMatch{{Key: "doc_id", Value: docID}}}
+	state, err := u.loadIndexState(ctx, cfg.Collection, filter)
+	if err != nil {
+		return 0, fmt.Errorf("load index state: %w", err)
+	}
+	d, ok := state[docID]
+	if !ok {
+		return 0, ErrDocumentNotFound
+	}
+	if err := u.deleteDocument(ctx, cfg.Collection, docID); err != nil {
+		return 0, err
+	}
+	slog.Info("Deleted document", "doc_id", docID, "path", d.path, "chunks", len(d.points))
+	return len(d.points), nil
+}
+
+// embedDocument embeds the chunks of a single document in batches
+// bounded like the ones of the ingest pipeline
+func (u *Usecase) embedDocument(ctx context.Context, d *document, model openai.EmbeddingModel) error {
+	cfg, _ := config.FromContext(ctx)
+
+	d.vectors = make([][]float32, 0, len(d.chunks))
+	b := newBatcher(cfg.Ingest.EmbedBatchSize, cfg.Ingest.EmbedBatchTokens)
+	flush := func() error {
+		if b.empty() {
+			return nil
+		}
+		items := b.take().items
+		texts := make([]string, len(items))
+		for i, it := range items {
+			texts[i] = it.text
+		}
+		vecs, err := u.embedTexts(ctx, model, texts)
+		if err != nil {
+			return err
+		}
+		for _, v := range vecs {
+			if len(v) != cfg.EmbeddingDim {
+				return fmt.Errorf("dim mismatch: got %d want %d", len(v), cfg.EmbeddingDim)
+			}
+		}
+		d.vectors = append(d.vectors, vecs...)
+		return nil
+	}
+
+	for i, c := range d.chunks {
+		text := utils.CleanUTF8(c.Text)
+		item := chunkRef{doc: d, idx: i, text: text, tokens: utils.EstimateTokens(text)}
+		if !b.fits(item) {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+		b.add(item)
+	}
+	return flush()
+}
@@ -0,0 +1,150 @@
+package ingest_test
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	openai "github.com/sashabaranov/go-openai"
+
+	"test-ragger/internal/configure/config"
+	"test-ragger/internal/models"
+	"test-ragger/internal/usecase/ingest"
+	"test-ragger/internal/usecase/search"
+	"test-ragger/internal/utils/chunker"
+	"test-ragger/internal/utils/hashembed"
+	"test-ragger/internal/utils/htmlx"
+	"test-ragger/internal/utils/keyword"
+	"test-ragger/internal/utils/langdetect"
+	"test-ragger/internal/utils/prompt"
+	"test-ragger/internal/utils/vectorstore"
+)
+
+var pages = map[string]string{
+	"printer.html": `<html lang="en"><head><title>Printer troubleshooting</title></head><body>
+<p>If the printer shows error code E4217, open the rear tray and remove the jammed paper.
+Paper jams usually happen when the tray is overloaded.</p></body></html>`,
+	"router.html": `<html lang="en"><head><title>Router setup</title></head><body>
+<p>Connect the router to the modem, open the admin page and choose a wireless network name
+and a strong password.</p></body></html>`,
+	"otpusk.html": `<html lang="ru"><head><title>Отпуск</title></head><body>
+<p>Заявление на отпуск подают руководителю за две недели. Отпускные выплачивают
+за три дня до начала отпуска.</p></body></html>`,
+}
+
+// TestIngestSearchOffline ingests a directory with hash embeddings into the
+// local store and searches it, then re-ingests after a file is removed
+func TestIngestSearchOffline(t *testing.T) {
+	tmp := t.TempDir()
+	dir := filepath.Join(tmp, "html")
+	if err := os.Mkdir(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for name, html := range pages {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(html), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cfg := config.Defaults()
+	cfg.ConfigPath = filepath.Join(tmp, "config.toml")
+	cfg.Store.Backend = "local"
+	cfg.Embedding.Provider = "hash"
+	cfg.Model = "hash"
+	cfg.EmbeddingDim = hashembed.DefaultDim
+	ctx := config.IntoContext(context.Background(), cfg)
+	model := openai.EmbeddingModel(cfg.Model)
+
+	store, err := vectorstore.OpenLocal(cfg.StorePath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	idx, err := keyword.Open(cfg.KeywordIndexPath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ks := keyword.NewStore(store, idx)
+	defer ks.Close()
+
+	embedder := hashembed.New(cfg.EmbeddingDim, true)
+	ingestUC := ingest.New(embedder, ks, htmlParser{}, langdetect.New(), chunker.New())
+	searchUC := search.New(embedder, ks, ks, nil, promptBuilder{})
+
+	if err := ingestUC.Run(ctx, dir, model); err != nil {
+		t.Fatalf("ingest: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		query string
+		mode  string
+		lang  string
+		want  string
+	}{
+		{"dense", "printer paper jam", search.ModeDense, "", "printer.html"},
+		{"keyword error code", "E4217", search.ModeKeyword, "", "printer.html"},
+		{"hybrid", "wireless network password", search.ModeHybrid, "", "router.html"},
+		{"russian stemming", "отпускных выплата", search.ModeKeyword, "", "otpusk.html"},
+		{"lang filter", "отпуск", search.ModeHybrid, "ru", "otpusk.html"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := search.OptionsFromConfig(cfg)
+			opts.TopK = 3
+			opts.Mode = tt.mode
+			opts.Lang = tt.lang
+			hits, err := searchUC.Search(ctx, tt.query, model, opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(hits) == 0 {
+				t.Fatalf("no hits for %q", tt.query)
+			}
+			if got := filepath.Base(hits[0].Path); got != tt.want {
+				t.Errorf("top hit for %q is %s, want %s", tt.query, got, tt.want)
+			}
+			if tt.lang != "" {
+				for _, h := range hits {
+					if h.Lang != tt.lang {
+						t.Errorf("hit %s has lang %q, want %q", h.Path, h.Lang, tt.lang)
+					}
+				}
+			}
+		})
+	}
+
+	// a removed file is pruned from both the vectors and the keyword index
+	if err := os.Remove(filepath.Join(dir, "router.html")); err != nil {
+		t.Fatal(err)
+	}
+	if err := ingestUC.Run(ctx, dir, model); err != nil {
+		t.Fatalf("re-ingest: %v", err)
+	}
+	for _, mode := range []string{search.ModeDense, search.ModeKeyword} {
+		opts := search.OptionsFromConfig(cfg)
+		opts.Mode = mode
+		hits, err := searchUC.Search(ctx, "router wireless network password", model, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, h := range hits {
+			if filepath.Base(h.Path) == "router.html" {
+				t.Errorf("%s search still finds removed document %s", mode, h.Path)
+			}
+		}
+	}
+}
+
+type htmlParser struct{}
+
+func (htmlParser) ToText(ctx context.Context, r io.Reader, path string) (string, string, string, error) {
+	return htmlx.ToText(r, path)
+}
+
+type promptBuilder struct{}
+
+func (promptBuilder) Build(query string, hits []models.Hit) string {
+	return prompt.Build(query, hits)
+}
@@ -0,0 +1,208 @@
+package ingest
+
+import (
+	"context"
+	"io/fs"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"test-ragger/internal/models"
+)
+
+// docStatus tells how a document relates to what is already indexed
+type docStatus int
+
+const (
+	statusAdded docStatus = iota
+	statusUpdated
+	statusUnchanged
+)
+
+var statusNames = map[docStatus]string{
+	statusAdded:     "added",
+	statusUpdated:   "updated",
+	statusUnchanged: "unchanged",
+}
+
+// Payload "source" values: documents of the HTML directory are pruned
+// when their files disappear, uploaded ones only on explicit deletion
+const (
+	sourceDir    = "dir"
+	sourceUpload = "upload"
+)
+
+// document is the unit of work passed between pipeline stages.
+// Documents that must not be written (e.g. empty files) keep flowing
+// with skip set, so progress can still be reported in file order.
+type document struct {
+	seq    int
+	path   string
+	title  string
+	lang   string // document language; chunks may differ, see langs
+	text   string
+	docID  string
+	hash   string
+	source string // sourceDir or sourceUpload
+	root   string // absolute source directory; empty for uploads
+	rel    string // path relative to root
+	status docStatus
+	chunks []models.ChunkInfo
+	langs  []string // langs[i] is the language of chunks[i]
+	points []models.Point
+	skip   bool
+
+	// resumed documents were written by an interrupted run; they are
+	// chunked to find orphans but neither embedded nor upserted again
+	resumed bool
+
+	// vectors[i] is the embedding of chunks[i]; pending counts chunks
+	// still waiting for their batch to be embedded
+	vectors [][]float32
+	pending atomic.Int64
+}
+
+// stageFunc processes a single document in place
+type stageFunc func(ctx context.Context, d *document) error
+
+// listHTMLFiles returns all .html files under dir in walk order
+func listHTMLFiles(dir string) ([]string, error) {
+	var paths []string
+	err := filepath.WalkDir(dir, func(path string, e fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if e.IsDir() || !strings.HasSuffix(strings.ToLower(path), ".html") {
+			return nil
+		}
+		paths = append(paths, path)
+		return nil
+	})
+	return paths, err
+}
+
+// emit feeds documents of the root directory into the pipeline until all
+// paths are sent, stop is closed (graceful shutdown) or ctx is done
+func emit(ctx context.Context, stop <-chan struct{}, wg *sync.WaitGroup, root string, paths []string, buffer int) <-chan *document {
+	out := make(chan *document, buffer)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer close(out)
+		for i, p := range paths {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			select {
+			case out <- &document{seq: i, path: p, source: sourceDir, root: root, rel: relPath(root, p)}:
+			case <-stop:
+				return
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// runStage starts a pool of workers applying fn to every document from in.
+// The returned channel is closed once all workers exit. The first error is
+// reported through fail, which is expected to cancel ctx.
+func runStage(ctx context.Context, wg *sync.WaitGroup, workers, buffer int, in <-chan *document, fail func(error), fn stageFunc) <-chan *document {
+	out := make(chan *document, buffer)
+
+	var stageWG sync.WaitGroup
+	for i := 0; i < max(workers, 1); i++ {
+		stageWG.Add(1)
+		go func() {
+			defer stageWG.Done()
+			for d := range in {
+				if ctx.Err() != nil {
+					return
+				}
+				if !d.skip {
+					if err := fn(ctx, d); err != nil {
+						fail(err)
+						return
+					}
+				}
+				select {
+				case out <- d:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		stageWG.Wait()
+		close(out)
+	}()
+	return out
+}
+
+// progress logs finished documents strictly in input order,
+// holding back documents that complete ahead of their predecessors.
+type progress struct {
+	dryRun  bool
+	total   int
+	next    int
+	pending map[int]*document
+
+	added     int
+	updated   int
+	unchanged int
+	resumed   int
+	skipped   int
+	chunks    int
+}
+
+func newProgress(total int, dryRun bool) *progress {
+	return &progress{total: total, dryRun: dryRun, pending: make(map[int]*document)}
+}
+
+func (p *progress) done(d *document) {
+	p.pending[d.seq] = d
+	for {
+		d, ok := p.pending[p.next]
+		if !ok {
+			return
+		}
+		delete(p.pending, p.next)
+		p.next++
+
+		if d.status == statusUnchanged {
+			p.unchanged++
+			slog.Info("Unchanged file", "path", d.path, "progress", p.next, "total", p.total)
+			continue
+		}
+		if d.resumed {
+			p.resumed++
+			slog.Info("Already ingested before interruption", "path", d.path, "progress", p.next, "total", p.total)
+			continue
+		}
+		if d.skip {
+			p.skipped++
+			slog.Info("Skipped file", "path", d.path, "progress", p.next, "total", p.total)
+			continue
+		}
+		if d.status == statusUpdated {
+			p.updated++
+		} else {
+			p.added++
+		}
+		p.chunks += len(d.chunks)
+		if p.dryRun {
+			slog.Info("Would ingest file", "path", d.path, "chunks", len(d.chunks), "progress", p.next, "total", p.total)
+			continue
+		}
+		slog.Info("Successfully ingested file", "path", d.path, "chunks", len(d.chunks), "progress", p.next, "total", p.total)
+	}
+}
@@ -0,0 +1,114 @@
+package ingest
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"test-ragger/internal/configure/config"
+	"test-ragger/internal/models"
+)
+
+// deleteBatchSize limits the number of point IDs per Delete request
+const deleteBatchSize = 512
+
+// orphanChunk is a stored chunk that no longer exists in its document
+type orphanChunk struct {
+	docID   string
+	path    string
+	chunkID string
+	id      string
+}
+
+// orphanChunks returns stored chunks of a re-ingested document that
+// were not produced by the current chunking
+func orphanChunks(d *document, prev *indexedDoc) []orphanChunk {
+	current := make(map[string]bool, len(d.chunks))
+	for _, c := range d.chunks {
+		current[pointIDFor(d.docID, c.ChunkID)] = true
+	}
+
+	var orphans []orphanChunk
+	for _, p := range prev.points {
+		if !current[p.id] {
+			orphans = append(orphans, orphanChunk{docID: d.docID, path: d.path, chunkID: p.chunkID, id: p.id})
+		}
+	}
+	return orphans
+}
+
+// guardRemoval refuses to start a run whose pruning looks like a mistake,
+// e.g. a wrong -dir: deleting every document of an empty directory needs
+// -force, deleting more than prune_max_share of them needs -confirm-prune.
+// Dry runs only warn.
+func guardRemoval(cfg config.Config, dir string, files int, r removal) error {
+	if r.gone == 0 {
+		return nil
+	}
+	share := float64(r.gone) / float64(r.scope)
+
+	var err error
+	switch {
+	case files == 0:
+		if !cfg.Force {
+			err = fmt.Errorf("no HTML files in %s, refusing to delete its %d indexed documents; check -dir, run with -force to delete them or -no-prune to keep them", dir, r.gone)
+		}
+	case cfg.Ingest.PruneMaxShare > 0 && share > cfg.Ingest.PruneMaxShare:
+		slog.Warn("Most indexed documents of the directory would be deleted",
+			"dir", dir, "deleted", r.gone, "indexed", r.scope, "prune_max_share", cfg.Ingest.PruneMaxShare, "confirmed", cfg.ConfirmPrune)
+		if !cfg.ConfirmPrune {
+			err = fmt.Errorf("%d of %d indexed documents of %s would be deleted, more than prune_max_share = %g; run with -confirm-prune to delete them or -no-prune to keep them",
+				r.gone, r.scope, dir, cfg.Ingest.PruneMaxShare)
+		}
+	}
+	if err != nil && cfg.DryRun {
+		slog.Warn("Ingest would refuse to prune", "error", err)
+		return nil
+	}
+	return err
+}
+
+// prune deletes orphan chunks and all points of removed documents.
+// With dryRun it only lists what would be deleted.
+func (u *Usecase) prune(ctx context.Context, collection string, orphans []orphanChunk, removed []string, state map[string]*indexedDoc, dryRun bool) error {
+	if dryRun {
+		for _, o := range orphans {
+			slog.Info("Would delete orphan chunk", "doc_id", o.docID, "chunk_id", o.chunkID, "path", o.path)
+		}
+		for _, docID := range removed {
+			slog.Info("Would delete removed document", "doc_id", docID, "path", state[docID].path, "chunks", len(state[docID].points))
+		}
+		return nil
+	}
+
+	ids := make([]string, 0, len(orphans))
+	for _, o := range orphans {
+		ids = append(ids, o.id)
+	}
+	for start := 0; start < len(ids); start += deleteBatchSize {
+		end := min(start+deleteBatchSize, len(ids))
+		if err := u.vectorStore.Delete(ctx, collection, models.Filter{IDs: ids[start:end]}); err != nil {
+			return fmt.Errorf("delete orphan chunks: %w", err)
+		}
+	}
+	if len(ids) > 0 {
+		slog.Info("Deleted orphan chunks", "count", len(ids))
+	}
+
+	for _, docID := range removed {
+		if err := u.deleteDocument(ctx, collection, docID); err != nil {
+			return err
+		}
+		slog.Info("Deleted removed document", "doc_id", docID, "path", state[docID].path, "chunks", len(state[docID].points))
+	}
+	return nil
+}
+
+// deleteDocument removes all points of a document
+func (u *Usecase) deleteDocument(ctx context.Context, collection, docID string) error {
+	err := u.vectorStore.Delete(ctx, collection, models.Filter{Must: []models.FieldMatch{{Key: "doc_id", Value: docID}}})
+	if err != nil {
+		return fmt.Errorf("delete document %s: %w", docID, err)
+	}
+	return nil
+}
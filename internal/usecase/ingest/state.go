@@ -0,0 +1,185 @@
+package ingest
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"test-ragger/internal/models"
+	"test-ragger/internal/utils"
+)
+
+// scrollPageSize is the number of points fetched per Scroll request
+const scrollPageSize = 256
+
+// indexedDoc describes a document already stored in the collection
+type indexedDoc struct {
+	path         string
+	contentHash  string
+	chunkSize    int
+	chunkOverlap int
+	model        string
+	docLang      string
+	source       string
+	root         string // empty for uploads and points written before roots were stored
+	rel          string
+	points       []indexedPoint
+}
+
+// indexedPoint is a single stored chunk of a document
+type indexedPoint struct {
+	id      string
+	chunkID string
+}
+
+// needsUpdate reports whether the document must be re-embedded
+// for the given content hash, chunking parameters and model.
+// Documents written before language detection lack doc_lang.
+func (d *indexedDoc) needsUpdate(hash string, chunkSize, chunkOverlap int, model string) bool {
+	return d.docLang == "" ||
+		d.contentHash != hash ||
+		d.chunkSize != chunkSize ||
+		d.chunkOverlap != chunkOverlap ||
+		d.model != model
+}
+
+// loadIndexState scrolls the collection payloads and groups points by
+// doc_id; filter narrows the scan (nil reads the whole collection)
+func (u *Usecase) loadIndexState(ctx context.Context, collection string, filter *models.Filter) (map[string]*indexedDoc, error) {
+	state := make(map[string]*indexedDoc)
+
+	var offset string
+	for {
+		page, err := u.vectorStore.Scroll(ctx, collection, models.ScrollRequest{
+			Filter:        filter,
+			Offset:        offset,
+			Limit:         scrollPageSize,
+			PayloadFields: []string{"doc_id", "chunk_id", "path", "content_hash", "chunk_size", "chunk_overlap", "model", "doc_lang", "source", "root", "rel_path"},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("scroll %s: %w", collection, err)
+		}
+
+		for _, p := range page.Points {
+			pl := p.Payload
+			docID := pl.String("doc_id")
+			if docID == "" {
+				continue
+			}
+			d, ok := state[docID]
+			if !ok {
+				d = &indexedDoc{
+					path:         pl.String("path"),
+					contentHash:  pl.String("content_hash"),
+					chunkSize:    int(pl.Int("chunk_size")),
+					chunkOverlap: int(pl.Int("chunk_overlap")),
+					model:        pl.String("model"),
+					docLang:      pl.String("doc_lang"),
+					source:       pl.String("source"),
+					root:         pl.String("root"),
+					rel:          pl.String("rel_path"),
+				}
+				state[docID] = d
+			}
+			// chunks written by different runs disagree: force an update
+			if d.contentHash != pl.String("content_hash") {
+				d.contentHash = ""
+			}
+			d.points = append(d.points, indexedPoint{id: p.ID, chunkID: pl.String("chunk_id")})
+		}
+
+		if page.NextOffset == "" {
+			return state, nil
+		}
+		offset = page.NextOffset
+	}
+}
+
+// docIDFor derives a stable document ID from its source path
+func docIDFor(path string) string {
+	return "doc_" + utils.Sha1Hex(path)
+}
+
+// pointIDFor derives the point ID of a chunk
+func pointIDFor(docID, chunkID string) string {
+	return utils.PointUUID(docID, chunkID)
+}
+
+// sourceRoot returns the absolute form of an ingested directory, so that
+// runs with a relative or absolute -dir, from any working directory,
+// agree on which documents belong to it
+func sourceRoot(dir string) (string, error) {
+	root, err := filepath.Abs(dir)
+	if err != nil {
+		return "", fmt.Errorf("resolve %s: %w", dir, err)
+	}
+	return root, nil
+}
+
+// relPath returns path relative to root with forward slashes,
+// or "" when path is outside root
+func relPath(root, path string) string {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return ""
+	}
+	rel, err := filepath.Rel(root, abs)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return ""
+	}
+	return filepath.ToSlash(rel)
+}
+
+// removal lists the documents of a source directory that a run deletes
+type removal struct {
+	docIDs []string // documents to delete
+	gone   int      // of them, documents whose files no longer exist
+	scope  int      // indexed documents of the directory
+
+	// renamed maps documents whose files are walked under another path
+	// now to that path
+	renamed map[string]string
+}
+
+// removedDocs finds indexed documents of root that this run does not
+// produce: their files are gone, or the same file is now walked under
+// another path and gets a new doc_id. Documents of other directories and
+// uploaded documents are never removed. Points written before the root
+// was stored are located by their path from the working directory.
+func removedDocs(state map[string]*indexedDoc, root string, paths []string) removal {
+	current := make(map[string]string, len(paths)) // rel path → walked path
+	for _, p := range paths {
+		current[relPath(root, p)] = p
+	}
+
+	r := removal{renamed: make(map[string]string)}
+	for docID, d := range state {
+		if d.source == sourceUpload {
+			continue
+		}
+		rel := d.rel
+		if d.root == "" {
+			rel = relPath(root, d.path)
+		} else if d.root != root {
+			continue
+		}
+		if rel == "" {
+			continue
+		}
+		r.scope++
+		p, ok := current[rel]
+		switch {
+		case !ok:
+			r.gone++
+		case docIDFor(p) != docID:
+			r.renamed[docID] = p
+		default:
+			continue
+		}
+		r.docIDs = append(r.docIDs, docID)
+	}
+	slices.Sort(r.docIDs)
+	return r
+}
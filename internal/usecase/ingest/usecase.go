@@ -1,178 +1,364 @@
 package ingest
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"log/slog"
 	"os"
-	"path/filepath"
-	"strings"
+	"sync"
 	"time"
 
-	"github.com/AlekSi/pointer"
-	qdrant "github.com/qdrant/go-client/qdrant"
 	openai "github.com/sashabaranov/go-openai"
 
 	"test-ragger/internal/configure/config"
+	"test-ragger/internal/models"
 	"test-ragger/internal/utils"
 )
 
-// Usecase handles HTML ingestion into Qdrant
+// Usecase handles HTML ingestion into a vector store
 type Usecase struct {
-	embeddingClient        EmbeddingClient
-	qdrantCollectionClient QdrantCollectionClient
-	qdrantPointsClient     QdrantPointsClient
-	htmlParser             HTMLParser
-	textChunker            TextChunker
+	embeddingClient EmbeddingClient
+	vectorStore     VectorStore
+	htmlParser      HTMLParser
+	langDetector    LangDetector
+	textChunker     TextChunker
 }
 
 // New creates new ingest usecase
 func New(
 	embeddingClient EmbeddingClient,
-	qdrantCollectionClient QdrantCollectionClient,
-	qdrantPointsClient QdrantPointsClient,
+	vectorStore VectorStore,
 	htmlParser HTMLParser,
+	langDetector LangDetector,
 	textChunker TextChunker,
 ) *Usecase {
 	return &Usecase{
-		embeddingClient:        embeddingClient,
-		qdrantCollectionClient: qdrantCollectionClient,
-		qdrantPointsClient:     qdrantPointsClient,
-		htmlParser:             htmlParser,
-		textChunker:            textChunker,
+		embeddingClient: embeddingClient,
+		vectorStore:     vectorStore,
+		htmlParser:      htmlParser,
+		langDetector:    langDetector,
+		textChunker:     textChunker,
 	}
 }
 
-// Run executes HTML ingestion process
+// Run executes HTML ingestion process.
+// Files are processed by a staged pipeline (parse → chunk → embed → upsert),
+// each stage backed by its own worker pool. Chunks are embedded in batches
+// that may span several files. Progress is journaled to a checkpoint file,
+// so an interrupted run can continue with cfg.Resume. Cancelling ctx stops
+// reading new files and lets the pipeline drain.
+// Afterwards the collection is reconciled with the source tree: chunks that
+// re-ingested documents no longer have and removed documents are deleted.
 func (u *Usecase) Run(ctx context.Context, htmlDir string, model openai.EmbeddingModel) error {
 	cfg, _ := config.FromContext(ctx)
 
-	slog.Info("Ensuring collection exists", "collection", cfg.Collection, "dimension", cfg.EmbeddingDim)
-	if err := u.ensureCollection(ctx, cfg.Collection, cfg.EmbeddingDim); err != nil {
+	if cfg.DryRun {
+		slog.Info("Dry run: nothing will be written to the collection")
+	} else {
+		slog.Info("Ensuring collection exists", "collection", cfg.Collection, "dimension", cfg.EmbeddingDim)
+	}
+	existed, err := u.ensureCollection(ctx, cfg.Collection, cfg.EmbeddingDim, cfg.DryRun)
+	if err != nil {
 		return fmt.Errorf("ensureCollection: %w", err)
 	}
-	slog.Info("Collection ready for ingestion")
 
-	return filepath.Walk(htmlDir, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
+	root, err := sourceRoot(htmlDir)
+	if err != nil {
+		return err
+	}
+	paths, err := listHTMLFiles(htmlDir)
+	if err != nil {
+		return fmt.Errorf("walk %s: %w", htmlDir, err)
+	}
+	slog.Info("Found HTML files", "dir", htmlDir, "root", root, "count", len(paths))
+
+	state := make(map[string]*indexedDoc)
+	if existed {
+		if state, err = u.loadIndexState(ctx, cfg.Collection, nil); err != nil {
+			return fmt.Errorf("load index state: %w", err)
 		}
-		if info.IsDir() || !strings.HasSuffix(strings.ToLower(path), ".html") {
-			return nil
+	}
+	slog.Info("Loaded index state", "documents", len(state))
+
+	rm := removedDocs(state, root, paths)
+	for _, docID := range rm.docIDs {
+		if p, ok := rm.renamed[docID]; ok {
+			slog.Info("Document is walked under another path, replacing it", "doc_id", docID, "path", state[docID].path, "new_path", p)
+			continue
+		}
+		slog.Info("Document removed from source tree", "doc_id", docID, "path", state[docID].path)
+	}
+	if cfg.Prune {
+		if err := guardRemoval(cfg, htmlDir, len(paths), rm); err != nil {
+			return err
 		}
+	}
+	removed := rm.docIDs
 
-		slog.Info("Parsing HTML file", "path", path)
-		text, title, err := u.htmlParser.ToText(ctx, path)
-		if err != nil {
+	var j *journal
+	if !cfg.DryRun {
+		start := journalEntry{
+			Store:        cfg.Store.Backend,
+			Collection:   cfg.Collection,
+			Model:        string(model),
+			Dir:          htmlDir,
+			ChunkSize:    cfg.ChunkSize,
+			ChunkOverlap: cfg.ChunkOverlap,
+		}
+		if j, err = openJournal(cfg.CheckpointPath(), start, cfg.Resume); err != nil {
 			return err
 		}
-		if len(text) == 0 {
-			slog.Info("Skipping empty file", "path", path)
-			return nil
+	}
+
+	// Cancelling ctx only stops feeding new files: documents already in the
+	// pipeline, including a partially filled embedding batch, are flushed to
+	// the vector store. workCtx is cancelled on the first stage error.
+	workCtx, cancel := context.WithCancelCause(context.WithoutCancel(ctx))
+	defer cancel(nil)
+
+	w := cfg.Ingest
+	var wg sync.WaitGroup
+	fail := func(err error) { cancel(err) }
+
+	docs := emit(workCtx, ctx.Done(), &wg, root, paths, w.QueueSize)
+	docs = runStage(workCtx, &wg, w.ParseWorkers, w.QueueSize, docs, fail, func(ctx context.Context, d *document) error {
+		return u.parse(ctx, d, state, j, model)
+	})
+	docs = runStage(workCtx, &wg, w.ChunkWorkers, w.QueueSize, docs, fail, u.chunk)
+	if !cfg.DryRun {
+		docs = u.runEmbedStage(workCtx, &wg, docs, fail, j, model)
+		docs = runStage(workCtx, &wg, w.UpsertWorkers, w.QueueSize, docs, fail, func(ctx context.Context, d *document) error {
+			return u.upsert(ctx, d, j, model)
+		})
+	}
+
+	p := newProgress(len(paths), cfg.DryRun)
+	var orphans []orphanChunk
+	for d := range docs {
+		p.done(d)
+		if prev, ok := state[d.docID]; ok && d.status == statusUpdated {
+			orphans = append(orphans, orphanChunks(d, prev)...)
 		}
+	}
+	wg.Wait()
 
-		// Clean text and title from invalid UTF-8 characters early
-		text = utils.CleanUTF8(text)
-		title = utils.CleanUTF8(title)
-
-		slog.Info("Parsed HTML to text", "title", title, "characters", len(text))
-
-		docID := "doc_" + utils.Sha1Hex(path)
-
-		slog.Info("Chunking text", "chunk_size", cfg.ChunkSize, "overlap", cfg.ChunkOverlap)
-		chunks := u.textChunker.ChunkText(text, cfg.ChunkSize, cfg.ChunkOverlap)
-		slog.Info("Created chunks", "count", len(chunks))
-
-		batch := make([]*qdrant.PointStruct, 0, len(chunks))
-
-		slog.Info("Creating embeddings", "chunks_count", len(chunks))
-		for i, c := range chunks {
-			slog.Debug("Processing chunk", "chunk_index", i, "chunk_length", len(c.Text))
-
-			// Clean chunk text from invalid UTF-8 characters
-			cleanText := utils.CleanUTF8(c.Text)
-			if len(cleanText) != len(c.Text) {
-				slog.Debug("Cleaned invalid UTF-8 characters", "original_length", len(c.Text), "cleaned_length", len(cleanText))
-			}
-
-			// create embedding
-			res, err := u.embeddingClient.CreateEmbeddings(ctx, openai.EmbeddingRequest{
-				Model: model,
-				Input: []string{cleanText},
-			})
-			if err != nil {
-				return fmt.Errorf("embedding: %w", err)
-			}
-			if i%10 == 0 && i > 0 {
-				slog.Info("Embeddings progress", "completed", i, "total", len(chunks))
-			}
-
-			vec := res.Data[0].Embedding
-			if len(vec) != cfg.EmbeddingDim {
-				return fmt.Errorf("dim mismatch: got %d want %d", len(vec), cfg.EmbeddingDim)
-			}
-
-			// create payload
-			payload := map[string]*qdrant.Value{
-				"doc_id":      {Kind: &qdrant.Value_StringValue{StringValue: docID}},
-				"chunk_id":    {Kind: &qdrant.Value_StringValue{StringValue: c.ChunkID}},
-				"title":       {Kind: &qdrant.Value_StringValue{StringValue: title}},
-				"path":        {Kind: &qdrant.Value_StringValue{StringValue: path}},
-				"start":       {Kind: &qdrant.Value_DoubleValue{DoubleValue: float64(c.Start)}},
-				"end":         {Kind: &qdrant.Value_DoubleValue{DoubleValue: float64(c.End)}},
-				"text":        {Kind: &qdrant.Value_StringValue{StringValue: cleanText}},
-				"ingested_at": {Kind: &qdrant.Value_StringValue{StringValue: time.Now().Format(time.RFC3339)}},
-				"lang":        {Kind: &qdrant.Value_StringValue{StringValue: "ru"}},
-				"type":        {Kind: &qdrant.Value_StringValue{StringValue: "html"}},
-			}
-
-			// Use numeric ID instead of UUID to avoid parsing issues
-			pointId := uint64(utils.Sha1Hash(docID + "_" + c.ChunkID))
-
-			batch = append(batch, &qdrant.PointStruct{
-				Id:      &qdrant.PointId{PointIdOptions: &qdrant.PointId_Num{Num: pointId}},
-				Vectors: &qdrant.Vectors{VectorsOptions: &qdrant.Vectors_Vector{Vector: &qdrant.Vector{Data: vec}}},
-				Payload: payload,
-			})
+	if err := context.Cause(workCtx); err != nil {
+		if cerr := j.Close(); cerr != nil {
+			slog.Error("Failed to save checkpoint", "error", cerr)
+		}
+		return err
+	}
+	if ctx.Err() != nil && p.next < len(paths) {
+		if err := j.Close(); err != nil {
+			return fmt.Errorf("save checkpoint: %w", err)
 		}
+		slog.Warn("Ingest interrupted, in-flight documents were flushed",
+			"processed", p.next, "total", len(paths), "checkpoint", cfg.CheckpointPath())
+		return fmt.Errorf("ingest interrupted, run with -resume to continue: %w", ctx.Err())
+	}
 
-		slog.Info("Upserting points to Qdrant", "points_count", len(batch), "collection", cfg.Collection)
-		_, err = u.qdrantPointsClient.Upsert(ctx, &qdrant.UpsertPoints{
-			CollectionName: cfg.Collection,
-			Points:         batch,
-			Wait:           pointer.To(true),
-		})
-		if err != nil {
-			return err
+	if cfg.Prune {
+		if err := u.prune(workCtx, cfg.Collection, orphans, removed, state, cfg.DryRun); err != nil {
+			return fmt.Errorf("prune: %w", err)
 		}
+	} else if len(orphans) > 0 || len(removed) > 0 {
+		slog.Info("Pruning disabled, stale points kept", "orphan_chunks", len(orphans), "removed_documents", len(removed))
+	}
+	slog.Info("Ingest summary",
+		"files", len(paths),
+		"added", p.added,
+		"updated", p.updated,
+		"unchanged", p.unchanged,
+		"removed", len(removed),
+		"skipped", p.skipped,
+		"chunks", p.chunks,
+		"orphan_chunks", len(orphans),
+		"resumed", p.resumed,
+	)
 
-		slog.Info("Successfully ingested file", "path", path, "chunks", len(chunks))
+	if err := j.Remove(); err != nil {
+		slog.Warn("Failed to remove checkpoint", "error", err)
+	}
+	return nil
+}
 
+// parse reads HTML file and parses it with parseHTML
+func (u *Usecase) parse(ctx context.Context, d *document, state map[string]*indexedDoc, j *journal, model openai.EmbeddingModel) error {
+	raw, err := os.ReadFile(d.path)
+	if err != nil {
+		return fmt.Errorf("read %s: %w", d.path, err)
+	}
+	return u.parseHTML(ctx, d, raw, state, j, model)
+}
+
+// parseHTML decides whether the document changed since the last run
+// and extracts clean text and title
+func (u *Usecase) parseHTML(ctx context.Context, d *document, raw []byte, state map[string]*indexedDoc, j *journal, model openai.EmbeddingModel) error {
+	cfg, _ := config.FromContext(ctx)
+
+	d.docID = docIDFor(d.path)
+	d.hash = utils.Sha256Hex(raw)
+
+	d.status = statusAdded
+	if prev, ok := state[d.docID]; ok {
+		d.status = statusUpdated
+		if !cfg.Force && !prev.needsUpdate(d.hash, cfg.ChunkSize, cfg.ChunkOverlap, string(model)) {
+			slog.Debug("Document unchanged", "path", d.path)
+			d.status = statusUnchanged
+			d.skip = true
+			return nil
+		}
+	}
+
+	// Written by an interrupted run: only chunk it to find orphan chunks
+	if h, ok := j.completedHash(d.docID); ok && h == d.hash {
+		slog.Debug("Document already ingested according to checkpoint", "path", d.path)
+		d.resumed = true
+	}
+
+	slog.Debug("Parsing HTML file", "path", d.path)
+	text, title, lang, err := u.htmlParser.ToText(ctx, bytes.NewReader(raw), d.path)
+	if err != nil {
+		return fmt.Errorf("parse %s: %w", d.path, err)
+	}
+	if len(text) == 0 {
+		slog.Info("Skipping empty file", "path", d.path)
+		d.skip = true
 		return nil
-	})
+	}
+
+	// Clean text and title from invalid UTF-8 characters early
+	d.text = utils.CleanUTF8(text)
+	d.title = utils.CleanUTF8(title)
+	d.lang = u.docLang(ctx, d, lang)
+
+	slog.Debug("Parsed HTML to text", "path", d.path, "title", d.title, "lang", d.lang, "characters", len(d.text))
+	return nil
 }
 
-// Убеждаемся что создана коллекция, если нет - то создаем
-func (u *Usecase) ensureCollection(ctx context.Context, collection string, dim int) error {
-	_, err := u.qdrantCollectionClient.Get(ctx, &qdrant.GetCollectionInfoRequest{CollectionName: collection})
-	if err == nil {
-		slog.Info("Collection already exists", "collection", collection)
+// docLang picks the document language: the one declared by the page,
+// else the detected one, else the configured default
+func (u *Usecase) docLang(ctx context.Context, d *document, declared string) string {
+	cfg, _ := config.FromContext(ctx)
+
+	if declared != "" {
+		slog.Debug("Using declared language", "path", d.path, "lang", declared)
+		return declared
+	}
+	if lang, ok := u.langDetector.Detect(d.text); ok {
+		slog.Debug("Detected document language", "path", d.path, "lang", lang)
+		return lang
+	}
+	slog.Debug("Could not detect document language, using default", "path", d.path, "lang", cfg.Ingest.DefaultLang)
+	return cfg.Ingest.DefaultLang
+}
+
+// chunk splits document text into overlapping chunks and detects the
+// language of each; chunks too short or ambiguous to tell keep the
+// document language
+func (u *Usecase) chunk(ctx context.Context, d *document) error {
+	cfg, _ := config.FromContext(ctx)
+
+	d.chunks = u.textChunker.ChunkText(d.text, cfg.ChunkSize, cfg.ChunkOverlap)
+	d.langs = make([]string, len(d.chunks))
+	mixed := false
+	for i, c := range d.chunks {
+		d.langs[i] = d.lang
+		if lang, ok := u.langDetector.Detect(c.Text); ok {
+			d.langs[i] = lang
+		}
+		mixed = mixed || d.langs[i] != d.lang
+	}
+	slog.Debug("Created chunks", "path", d.path, "count", len(d.chunks), "chunk_size", cfg.ChunkSize, "overlap", cfg.ChunkOverlap, "mixed_lang", mixed)
+	return nil
+}
+
+// buildPoints turns embedded chunks into vector store points.
+// content_hash, chunking parameters and model let the next run
+// detect documents that need re-embedding.
+func (u *Usecase) buildPoints(ctx context.Context, d *document, model openai.EmbeddingModel) {
+	cfg, _ := config.FromContext(ctx)
+
+	d.points = make([]models.Point, 0, len(d.chunks))
+	for i, c := range d.chunks {
+		// create payload
+		payload := models.Payload{
+			"doc_id":      d.docID,
+			"chunk_id":    c.ChunkID,
+			"title":       d.title,
+			"path":        d.path,
+			"start":       c.Start,
+			"end":         c.End,
+			"text":        utils.CleanUTF8(c.Text),
+			"ingested_at": time.Now().Format(time.RFC3339),
+			"lang":        d.langs[i],
+			"doc_lang":    d.lang,
+			"type":        "html",
+			"source":      d.source,
+			"root":        d.root,
+			"rel_path":    d.rel,
+
+			"content_hash":  d.hash,
+			"chunk_size":    cfg.ChunkSize,
+			"chunk_overlap": cfg.ChunkOverlap,
+			"model":         string(model),
+		}
+
+		d.points = append(d.points, models.Point{
+			ID:      pointIDFor(d.docID, c.ChunkID),
+			Vector:  d.vectors[i],
+			Payload: payload,
+		})
+	}
+}
+
+// upsert writes document points to the vector store and marks it done in the checkpoint
+func (u *Usecase) upsert(ctx context.Context, d *document, j *journal, model openai.EmbeddingModel) error {
+	cfg, _ := config.FromContext(ctx)
+
+	if d.resumed {
 		return nil
 	}
+
+	u.buildPoints(ctx, d, model)
+
+	slog.Debug("Upserting points", "path", d.path, "points_count", len(d.points), "collection", cfg.Collection)
+	if err := u.vectorStore.Upsert(ctx, cfg.Collection, d.points); err != nil {
+		return fmt.Errorf("upsert %s: %w", d.path, err)
+	}
+	return j.markDone(d)
+}
+
+// Убеждаемся что создана коллекция, если нет - то создаем.
+// An existing collection must match the model's vector params;
+// with dryRun it is only checked.
+func (u *Usecase) ensureCollection(ctx context.Context, collection string, dim int, dryRun bool) (bool, error) {
+	info, err := u.vectorStore.CollectionInfo(ctx, collection)
+	if err != nil {
+		return false, err
+	}
+	if info != nil {
+		slog.Info("Collection already exists", "collection", collection, "points", info.Points)
+		return true, checkVectorParams(info, dim)
+	}
+	if dryRun {
+		return false, nil
+	}
 	slog.Info("Creating new collection", "collection", collection, "dimension", dim)
-	_, err = u.qdrantCollectionClient.Create(ctx, &qdrant.CreateCollection{
-		CollectionName: collection,
-		VectorsConfig: &qdrant.VectorsConfig{
-			Config: &qdrant.VectorsConfig_Params{
-				Params: &qdrant.VectorParams{
-					Size:     uint64(dim),
-					Distance: qdrant.Distance_Cosine,
-				},
-			},
-		},
-	})
-	if err == nil {
-		slog.Info("Collection created successfully", "collection", collection)
+	if err := u.vectorStore.CreateCollection(ctx, collection, dim); err != nil {
+		return false, err
+	}
+	slog.Info("Collection created successfully", "collection", collection)
+	return false, nil
+}
+
+// checkVectorParams refuses collections whose vectors were not produced
+// by the current model: a different size or a non-cosine distance
+func checkVectorParams(info *models.CollectionInfo, dim int) error {
+	if info.Dimension != dim || info.Distance != "Cosine" {
+		return fmt.Errorf("collection %s stores %d-dimensional vectors with %s distance, but model needs %d with Cosine; use another collection or re-create it",
+			info.Name, info.Dimension, info.Distance, dim)
 	}
-	return err
+	return nil
 }
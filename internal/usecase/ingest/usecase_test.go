@@ -0,0 +1,35 @@
+package ingest
+
+import (
+	"context"
+	"testing"
+
+	"test-ragger/internal/configure/config"
+	"test-ragger/internal/utils/langdetect"
+)
+
+func TestDocLang(t *testing.T) {
+	cfg := config.Defaults()
+	cfg.Ingest.DefaultLang = "xx"
+	ctx := config.IntoContext(context.Background(), cfg)
+	u := &Usecase{langDetector: langdetect.New()}
+
+	tests := []struct {
+		name     string
+		declared string
+		text     string
+		want     string
+	}{
+		{"declared wins", "en", "Заявление на отпуск подают руководителю за две недели.", "en"},
+		{"detected", "", "Заявление на отпуск подают руководителю за две недели.", "ru"},
+		{"too short falls back", "", "Привет", "xx"},
+		{"empty falls back", "", "", "xx"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := u.docLang(ctx, &document{text: tt.text}, tt.declared); got != tt.want {
+				t.Errorf("docLang = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
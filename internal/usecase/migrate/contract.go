@@ -0,0 +1,14 @@
+package migrate
+
+import (
+	"context"
+
+	"test-ragger/internal/models"
+)
+
+// VectorStore reads and rewrites stored points
+type VectorStore interface {
+	Scroll(ctx context.Context, collection string, req models.ScrollRequest) (models.ScrollPage, error)
+	Upsert(ctx context.Context, collection string, points []models.Point) error
+	Delete(ctx context.Context, collection string, filter models.Filter) error
+}
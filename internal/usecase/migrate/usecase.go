@@ -0,0 +1,107 @@
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+
+	"test-ragger/internal/configure/config"
+	"test-ragger/internal/models"
+	"test-ragger/internal/utils"
+)
+
+// scrollPageSize is the number of points rewritten per step
+const scrollPageSize = 128
+
+// Usecase rewrites collections to the current point ID scheme
+type Usecase struct {
+	vectorStore VectorStore
+}
+
+// New creates new migrate usecase
+func New(vectorStore VectorStore) *Usecase {
+	return &Usecase{vectorStore: vectorStore}
+}
+
+// MigratePointIDs replaces legacy numeric point IDs (truncated SHA-1)
+// with UUIDv5 IDs derived from doc_id and chunk_id. Stored vectors and
+// payloads are copied as is, so nothing is re-embedded.
+func (u *Usecase) MigratePointIDs(ctx context.Context) error {
+	cfg, _ := config.FromContext(ctx)
+
+	var (
+		offset   string
+		scanned  int
+		migrated int
+		skipped  int
+	)
+	for {
+		page, err := u.vectorStore.Scroll(ctx, cfg.Collection, models.ScrollRequest{
+			Offset:      offset,
+			Limit:       scrollPageSize,
+			WithVectors: true,
+		})
+		if err != nil {
+			return fmt.Errorf("scroll %s: %w", cfg.Collection, err)
+		}
+
+		var (
+			points []models.Point
+			oldIDs []string
+		)
+		for _, p := range page.Points {
+			scanned++
+			if !isLegacyID(p.ID) {
+				continue
+			}
+			docID := p.Payload.String("doc_id")
+			chunkID := p.Payload.String("chunk_id")
+			if docID == "" || chunkID == "" || len(p.Vector) == 0 {
+				slog.Warn("Skipping point without doc_id, chunk_id or vector", "id", p.ID)
+				skipped++
+				continue
+			}
+
+			points = append(points, models.Point{
+				ID:      utils.PointUUID(docID, chunkID),
+				Vector:  p.Vector,
+				Payload: p.Payload,
+			})
+			oldIDs = append(oldIDs, p.ID)
+		}
+
+		if len(points) > 0 && !cfg.DryRun {
+			if err := u.rewrite(ctx, cfg.Collection, points, oldIDs); err != nil {
+				return err
+			}
+		}
+		migrated += len(points)
+		slog.Info("Point ID migration progress", "scanned", scanned, "migrated", migrated, "dry_run", cfg.DryRun)
+
+		if page.NextOffset == "" {
+			break
+		}
+		offset = page.NextOffset
+	}
+
+	slog.Info("Point ID migration completed", "collection", cfg.Collection, "scanned", scanned, "migrated", migrated, "skipped", skipped, "dry_run", cfg.DryRun)
+	return nil
+}
+
+// rewrite stores points under their new IDs, then deletes the old ones
+func (u *Usecase) rewrite(ctx context.Context, collection string, points []models.Point, oldIDs []string) error {
+	if err := u.vectorStore.Upsert(ctx, collection, points); err != nil {
+		return fmt.Errorf("upsert migrated points: %w", err)
+	}
+	if err := u.vectorStore.Delete(ctx, collection, models.Filter{IDs: oldIDs}); err != nil {
+		return fmt.Errorf("delete legacy points: %w", err)
+	}
+	return nil
+}
+
+// isLegacyID reports whether a point carries a numeric (truncated SHA-1) ID
+func isLegacyID(id string) bool {
+	_, err := strconv.ParseUint(id, 10, 64)
+	return err == nil
+}
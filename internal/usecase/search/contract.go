@@ -3,7 +3,6 @@ package search
 import (
 	"context"
 
-	qdrant "github.com/qdrant/go-client/qdrant"
 	openai "github.com/sashabaranov/go-openai"
 
 	"test-ragger/internal/models"
@@ -14,9 +13,21 @@ type EmbeddingClient interface {
 	CreateEmbeddings(ctx context.Context, req openai.EmbeddingRequestConverter) (openai.EmbeddingResponse, error)
 }
 
-// QdrantPointsClient handles point operations
-type QdrantPointsClient interface {
-	Search(ctx context.Context, req *qdrant.SearchPoints) (*qdrant.SearchResponse, error)
+// VectorStore finds nearest chunks (Qdrant or the embedded local store)
+type VectorStore interface {
+	Search(ctx context.Context, collection string, req models.SearchRequest) ([]models.ScoredPoint, error)
+	Scroll(ctx context.Context, collection string, req models.ScrollRequest) (models.ScrollPage, error)
+}
+
+// KeywordIndex ranks chunks by BM25; returned points carry IDs and scores only
+type KeywordIndex interface {
+	SearchText(ctx context.Context, collection, query string, limit int, filter *models.Filter) ([]models.ScoredPoint, error)
+}
+
+// Reranker rescores retrieved hits for the query; scores are returned in
+// hit order, higher is more relevant
+type Reranker interface {
+	Rerank(ctx context.Context, query string, hits []models.Hit) ([]float32, error)
 }
 
 // PromptBuilder creates LLM prompts from search results
@@ -0,0 +1,105 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"test-ragger/internal/configure/config"
+	"test-ragger/internal/models"
+)
+
+// ErrDocumentNotFound is returned for doc_ids the collection does not hold
+var ErrDocumentNotFound = errors.New("document not found")
+
+// scrollPageSize is the number of points fetched per Scroll request
+const scrollPageSize = 256
+
+// Document returns a stored document with the text of all its chunks
+// merged by their offsets; missing chunks leave a " … " gap
+func (u *Usecase) Document(ctx context.Context, docID string) (models.Document, error) {
+	cfg, _ := config.FromContext(ctx)
+
+	doc := models.Document{DocID: docID}
+	chunks := make(map[int]storedChunk)
+	last := -1
+	err := u.scroll(ctx, cfg.Collection, models.ScrollRequest{
+		Filter:        &models.Filter{Must: []models.FieldMatch{{Key: "doc_id", Value: docID}}},
+		PayloadFields: []string{"doc_id", "chunk_id", "title", "path", "doc_lang", "start", "end", "text"},
+	}, func(p models.Point) {
+		pl := p.Payload
+		doc.Title, doc.Path, doc.Lang = pl.String("title"), pl.String("path"), pl.String("doc_lang")
+		doc.Chunks++
+		if idx, ok := chunkIndex(pl.String("chunk_id")); ok {
+			chunks[idx] = storedChunk{id: pl.String("chunk_id"), start: int(pl.Int("start")), end: int(pl.Int("end")), text: pl.String("text")}
+			last = max(last, idx)
+		}
+	})
+	if err != nil {
+		return models.Document{}, err
+	}
+	if doc.Chunks == 0 {
+		return models.Document{}, fmt.Errorf("%w: %s", ErrDocumentNotFound, docID)
+	}
+
+	var h models.Hit
+	setPassage(&h, chunks, 0, last)
+	doc.Text = h.Text
+	return doc, nil
+}
+
+// Documents lists stored documents ordered by path
+func (u *Usecase) Documents(ctx context.Context) ([]models.Document, error) {
+	cfg, _ := config.FromContext(ctx)
+
+	byID := make(map[string]*models.Document)
+	err := u.scroll(ctx, cfg.Collection, models.ScrollRequest{
+		PayloadFields: []string{"doc_id", "title", "path", "doc_lang"},
+	}, func(p models.Point) {
+		pl := p.Payload
+		docID := pl.String("doc_id")
+		if docID == "" {
+			return
+		}
+		d, ok := byID[docID]
+		if !ok {
+			d = &models.Document{DocID: docID, Title: pl.String("title"), Path: pl.String("path"), Lang: pl.String("doc_lang")}
+			byID[docID] = d
+		}
+		d.Chunks++
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	docs := make([]models.Document, 0, len(byID))
+	for _, d := range byID {
+		docs = append(docs, *d)
+	}
+	sort.Slice(docs, func(i, j int) bool {
+		if docs[i].Path != docs[j].Path {
+			return docs[i].Path < docs[j].Path
+		}
+		return docs[i].DocID < docs[j].DocID
+	})
+	return docs, nil
+}
+
+// scroll passes every point matching req to fn, page by page
+func (u *Usecase) scroll(ctx context.Context, collection string, req models.ScrollRequest, fn func(models.Point)) error {
+	req.Limit = scrollPageSize
+	for {
+		page, err := u.vectorStore.Scroll(ctx, collection, req)
+		if err != nil {
+			return fmt.Errorf("scroll %s: %w", collection, err)
+		}
+		for _, p := range page.Points {
+			fn(p)
+		}
+		if page.NextOffset == "" {
+			return nil
+		}
+		req.Offset = page.NextOffset
+	}
+}
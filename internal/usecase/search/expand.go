@@ -0,0 +1,210 @@
+package search
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/binary"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strconv"
+	"strings"
+
+	"test-ragger/internal/models"
+	"test-ragger/internal/utils"
+)
+
+// chunkIDPrefix is the prefix of chunk IDs written by the chunker: ch_<index>
+const chunkIDPrefix = "ch_"
+
+// storedChunk is a chunk of a document with its byte offsets in the document text
+type storedChunk struct {
+	id         string
+	start, end int
+	text       string
+}
+
+// expand replaces the text of every hit with a passage spanning up to n
+// neighbouring chunks on each side. Overlapping chunks are merged by their
+// stored offsets; a hit whose passage overlaps or touches a higher ranked
+// passage of the same document is merged into it, so the prompt sees every
+// piece of text once.
+func (u *Usecase) expand(ctx context.Context, collection string, hits []models.Hit, n int) ([]models.Hit, error) {
+	// chunks[docID][index]
+	chunks := make(map[string]map[int]storedChunk)
+	add := func(docID string, index int, c storedChunk) {
+		if chunks[docID] == nil {
+			chunks[docID] = make(map[int]storedChunk)
+		}
+		chunks[docID][index] = c
+	}
+
+	var (
+		ids    []string
+		legacy int
+	)
+	wanted := make(map[string]bool)
+	for _, h := range hits {
+		idx, ok := chunkIndex(h.ChunkID)
+		if !ok {
+			continue
+		}
+		add(h.DocID, idx, storedChunk{id: h.ChunkID, start: h.Start, end: h.End, text: h.Text})
+		if isLegacyID(h.ID) {
+			legacy++
+		}
+		for k := max(idx-n, 0); k <= idx+n; k++ {
+			if k == idx {
+				continue
+			}
+			for _, id := range neighbourIDs(h, k) {
+				if !wanted[id] {
+					wanted[id] = true
+					ids = append(ids, id)
+				}
+			}
+		}
+	}
+	if legacy > 0 {
+		slog.Info("Hits have legacy numeric point IDs, looking up neighbours by both ID schemes; run -mode=migrate-ids to convert the collection", "collection", collection, "hits", legacy)
+	}
+	if len(ids) > 0 {
+		page, err := u.vectorStore.Scroll(ctx, collection, models.ScrollRequest{
+			Filter:        &models.Filter{IDs: ids},
+			Limit:         len(ids),
+			PayloadFields: []string{"doc_id", "chunk_id", "start", "end", "text"},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("fetch neighbour chunks: %w", err)
+		}
+		for _, p := range page.Points {
+			pl := p.Payload
+			if idx, ok := chunkIndex(pl.String("chunk_id")); ok {
+				add(pl.String("doc_id"), idx, storedChunk{
+					id:    pl.String("chunk_id"),
+					start: int(pl.Int("start")),
+					end:   int(pl.Int("end")),
+					text:  pl.String("text"),
+				})
+			}
+		}
+		slog.Info("Fetched neighbour chunks", "requested", len(ids), "found", len(page.Points))
+	}
+
+	// passage index ranges already emitted, per document
+	type span struct{ lo, hi, hit int }
+	spans := make(map[string][]*span)
+	out := make([]models.Hit, 0, len(hits))
+	for _, h := range hits {
+		idx, ok := chunkIndex(h.ChunkID)
+		if !ok {
+			out = append(out, h)
+			continue
+		}
+		lo, hi := max(idx-n, 0), idx+n
+
+		var into *span
+		for _, s := range spans[h.DocID] {
+			if lo <= s.hi+1 && s.lo <= hi+1 {
+				into = s
+				break
+			}
+		}
+		if into != nil {
+			into.lo, into.hi = min(into.lo, lo), max(into.hi, hi)
+			setPassage(&out[into.hit], chunks[h.DocID], into.lo, into.hi)
+			continue
+		}
+		spans[h.DocID] = append(spans[h.DocID], &span{lo: lo, hi: hi, hit: len(out)})
+		setPassage(&h, chunks[h.DocID], lo, hi)
+		out = append(out, h)
+	}
+	return out, nil
+}
+
+// neighbourIDs returns the point IDs chunk k of the hit's document may be
+// stored under. Collections ingested before UUID point IDs keep numeric
+// ones until -mode=migrate-ids rewrites them, and a partly migrated
+// collection holds both, so a hit with a numeric ID asks for either.
+func neighbourIDs(h models.Hit, k int) []string {
+	chunkID := chunkIDPrefix + strconv.Itoa(k)
+	id := utils.PointUUID(h.DocID, chunkID)
+	if !isLegacyID(h.ID) {
+		return []string{id}
+	}
+	return []string{id, legacyPointID(h.DocID, chunkID)}
+}
+
+// legacyPointID is the numeric point ID of older ingests: the first four
+// bytes of SHA-1 over doc_id and chunk_id
+func legacyPointID(docID, chunkID string) string {
+	h := sha1.Sum([]byte(docID + "_" + chunkID))
+	return strconv.FormatUint(uint64(binary.BigEndian.Uint32(h[:4])), 10)
+}
+
+// isLegacyID reports whether a point carries a numeric (truncated SHA-1) ID
+func isLegacyID(id string) bool {
+	_, err := strconv.ParseUint(id, 10, 64)
+	return err == nil
+}
+
+// setPassage sets hit text to the merged chunks lo..hi that exist
+func setPassage(h *models.Hit, doc map[int]storedChunk, lo, hi int) {
+	var parts []storedChunk
+	for k := lo; k <= hi; k++ {
+		if c, ok := doc[k]; ok {
+			parts = append(parts, c)
+		}
+	}
+	if len(parts) == 0 {
+		return
+	}
+	sort.Slice(parts, func(i, j int) bool { return parts[i].start < parts[j].start })
+
+	var b strings.Builder
+	b.WriteString(parts[0].text)
+	end := parts[0].end
+	h.ChunkIDs = []string{parts[0].id}
+	for _, c := range parts[1:] {
+		switch {
+		case c.start >= end:
+			// a gap left by a missing chunk
+			if c.start > end {
+				b.WriteString(" … ")
+			}
+			b.WriteString(c.text)
+		case c.end > end:
+			b.WriteString(c.text[overlap(b.String(), c.text, end-c.start):])
+		default:
+			// fully inside the passage
+		}
+		end = max(end, c.end)
+		h.ChunkIDs = append(h.ChunkIDs, c.id)
+	}
+	h.Text = b.String()
+	h.Start, h.End = parts[0].start, end
+}
+
+// overlap returns how many leading bytes of next repeat the tail of
+// passage. want comes from the offsets; stored texts may be a few bytes
+// shorter where chunk boundaries split a rune, so nearby lengths are tried.
+func overlap(passage, next string, want int) int {
+	for d := 0; d <= 4; d++ {
+		for _, n := range []int{want - d, want + d} {
+			if n >= 0 && n <= len(next) && n <= len(passage) && passage[len(passage)-n:] == next[:n] {
+				return n
+			}
+		}
+	}
+	return min(max(want, 0), len(next))
+}
+
+// chunkIndex parses the index out of a "ch_<index>" chunk ID
+func chunkIndex(chunkID string) (int, bool) {
+	s, ok := strings.CutPrefix(chunkID, chunkIDPrefix)
+	if !ok {
+		return 0, false
+	}
+	n, err := strconv.Atoi(s)
+	return n, err == nil && n >= 0
+}
@@ -0,0 +1,143 @@
+package search
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"test-ragger/internal/models"
+	"test-ragger/internal/utils"
+)
+
+// chunkStore serves stored chunks by point ID
+type chunkStore struct {
+	fakeStore
+	points map[string]models.Point
+}
+
+func (s *chunkStore) Scroll(ctx context.Context, collection string, req models.ScrollRequest) (models.ScrollPage, error) {
+	var page models.ScrollPage
+	for _, id := range req.Filter.IDs {
+		if p, ok := s.points[id]; ok {
+			page.Points = append(page.Points, p)
+		}
+	}
+	return page, nil
+}
+
+// doc is "0123456789abcdefghij" cut into chunks of 8 bytes overlapping by 2
+var doc = []storedChunk{
+	{id: "ch_0", start: 0, end: 8, text: "01234567"},
+	{id: "ch_1", start: 6, end: 14, text: "6789abcd"},
+	{id: "ch_2", start: 12, end: 20, text: "cdefghij"},
+}
+
+// newChunkStore stores doc under the ID of id(docID, chunkID)
+func newChunkStore(docID string, id func(docID, chunkID string) string) *chunkStore {
+	s := &chunkStore{points: make(map[string]models.Point)}
+	for _, c := range doc {
+		s.points[id(docID, c.id)] = models.Point{ID: id(docID, c.id), Payload: models.Payload{
+			"doc_id": docID, "chunk_id": c.id, "start": int64(c.start), "end": int64(c.end), "text": c.text,
+		}}
+	}
+	return s
+}
+
+func hitOf(docID string, c storedChunk, id func(docID, chunkID string) string) models.Hit {
+	return models.Hit{ID: id(docID, c.id), DocID: docID, ChunkID: c.id, Start: c.start, End: c.end, Text: c.text}
+}
+
+func TestSetPassage(t *testing.T) {
+	adjacent := map[int]storedChunk{
+		0: {id: "ch_0", start: 0, end: 5, text: "Hello"},
+		1: {id: "ch_1", start: 5, end: 11, text: " world"},
+		2: {id: "ch_2", start: 11, end: 12, text: "!"},
+	}
+	overlapping := map[int]storedChunk{0: doc[0], 1: doc[1], 2: doc[2]}
+	gap := map[int]storedChunk{0: doc[0], 2: doc[2]}
+	nested := map[int]storedChunk{0: doc[0], 1: {id: "ch_1", start: 2, end: 6, text: "2345"}}
+
+	tests := []struct {
+		name       string
+		doc        map[int]storedChunk
+		lo, hi     int
+		want       string
+		chunks     []string
+		start, end int
+	}{
+		{"adjacent", adjacent, 0, 2, "Hello world!", []string{"ch_0", "ch_1", "ch_2"}, 0, 12},
+		{"overlapping", overlapping, 0, 2, "0123456789abcdefghij", []string{"ch_0", "ch_1", "ch_2"}, 0, 20},
+		{"first chunk", overlapping, 0, 1, "0123456789abcd", []string{"ch_0", "ch_1"}, 0, 14},
+		{"last chunk", overlapping, 1, 3, "6789abcdefghij", []string{"ch_1", "ch_2"}, 6, 20},
+		{"missing chunk", gap, 0, 2, "01234567 … cdefghij", []string{"ch_0", "ch_2"}, 0, 20},
+		{"chunk inside the passage", nested, 0, 1, "01234567", []string{"ch_0", "ch_1"}, 0, 8},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := models.Hit{Text: "hit"}
+			setPassage(&h, tt.doc, tt.lo, tt.hi)
+			if h.Text != tt.want || !slices.Equal(h.ChunkIDs, tt.chunks) || h.Start != tt.start || h.End != tt.end {
+				t.Errorf("passage = %q %v [%d, %d), want %q %v [%d, %d)", h.Text, h.ChunkIDs, h.Start, h.End, tt.want, tt.chunks, tt.start, tt.end)
+			}
+		})
+	}
+
+	h := models.Hit{Text: "hit"}
+	setPassage(&h, nil, 0, 2)
+	if h.Text != "hit" {
+		t.Errorf("a passage without stored chunks replaced the text with %q", h.Text)
+	}
+}
+
+func TestExpand(t *testing.T) {
+	tests := []struct {
+		name  string
+		hits  []int // chunk indexes of the hits, best first
+		n     int
+		texts []string
+	}{
+		{"first chunk", []int{0}, 1, []string{"0123456789abcd"}},
+		{"last chunk", []int{2}, 1, []string{"6789abcdefghij"}},
+		{"middle chunk", []int{1}, 1, []string{"0123456789abcdefghij"}},
+		{"overlapping passages merge into the better hit", []int{2, 0}, 1, []string{"0123456789abcdefghij"}},
+		{"adjacent passages merge", []int{0, 1}, 0, []string{"0123456789abcd"}},
+		{"separate passages", []int{0, 2}, 0, []string{"01234567", "cdefghij"}},
+		{"no neighbours", []int{1}, 0, []string{"6789abcd"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := New(fakeEmbedder{}, newChunkStore("d", utils.PointUUID), nil, nil, nil)
+			var hits []models.Hit
+			for _, i := range tt.hits {
+				hits = append(hits, hitOf("d", doc[i], utils.PointUUID))
+			}
+			got, err := u.expand(context.Background(), "docs", hits, tt.n)
+			if err != nil {
+				t.Fatal(err)
+			}
+			texts := make([]string, len(got))
+			for i, h := range got {
+				texts[i] = h.Text
+			}
+			if !slices.Equal(texts, tt.texts) {
+				t.Errorf("passages = %q, want %q", texts, tt.texts)
+			}
+		})
+	}
+}
+
+func TestExpandLegacyIDs(t *testing.T) {
+	u := New(fakeEmbedder{}, newChunkStore("d", legacyPointID), nil, nil, nil)
+	hits := []models.Hit{hitOf("d", doc[1], legacyPointID)}
+
+	got, err := u.expand(context.Background(), "docs", hits, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got[0].Text != "0123456789abcdefghij" {
+		t.Errorf("passage = %q, want the neighbours stored under numeric IDs", got[0].Text)
+	}
+	if id := legacyPointID("d", "ch_1"); !isLegacyID(id) {
+		t.Errorf("legacy ID %q is not numeric", id)
+	}
+}
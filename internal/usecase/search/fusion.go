@@ -0,0 +1,85 @@
+package search
+
+import (
+	"sort"
+
+	"test-ragger/internal/models"
+)
+
+// fused is a hit of hybrid search with its scores in both lists
+type fused struct {
+	id          string
+	score       float64
+	dense       float32
+	keyword     float32
+	denseRank   int // 1-based; 0 when the list missed the point
+	keywordRank int
+}
+
+// fuse merges dense and keyword results ranked best first
+func fuse(dense, keyword []models.ScoredPoint, opts Options) []fused {
+	byID := make(map[string]*fused, len(dense)+len(keyword))
+	get := func(id string) *fused {
+		f, ok := byID[id]
+		if !ok {
+			f = &fused{id: id}
+			byID[id] = f
+		}
+		return f
+	}
+	for i, p := range dense {
+		f := get(p.ID)
+		f.dense, f.denseRank = p.Score, i+1
+	}
+	for i, p := range keyword {
+		f := get(p.ID)
+		f.keyword, f.keywordRank = p.Score, i+1
+	}
+
+	denseNorm := minMax(dense)
+	keywordNorm := minMax(keyword)
+	out := make([]fused, 0, len(byID))
+	for _, f := range byID {
+		switch opts.Fusion {
+		case FusionRRF:
+			if f.denseRank > 0 {
+				f.score += opts.DenseWeight / float64(opts.RRFK+f.denseRank)
+			}
+			if f.keywordRank > 0 {
+				f.score += opts.KeywordWeight / float64(opts.RRFK+f.keywordRank)
+			}
+		case FusionWeighted:
+			if f.denseRank > 0 {
+				f.score += opts.DenseWeight * denseNorm(f.dense)
+			}
+			if f.keywordRank > 0 {
+				f.score += opts.KeywordWeight * keywordNorm(f.keyword)
+			}
+		}
+		out = append(out, *f)
+	}
+
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].score != out[j].score {
+			return out[i].score > out[j].score
+		}
+		return out[i].id < out[j].id
+	})
+	return out
+}
+
+// minMax scales scores of a list into [0, 1]; a single distinct score maps to 1
+func minMax(list []models.ScoredPoint) func(float32) float64 {
+	if len(list) == 0 {
+		return func(float32) float64 { return 0 }
+	}
+	lo, hi := list[0].Score, list[0].Score
+	for _, p := range list[1:] {
+		lo = min(lo, p.Score)
+		hi = max(hi, p.Score)
+	}
+	if hi == lo {
+		return func(float32) float64 { return 1 }
+	}
+	return func(s float32) float64 { return float64(s-lo) / float64(hi-lo) }
+}
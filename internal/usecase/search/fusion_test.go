@@ -0,0 +1,125 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"testing"
+
+	"test-ragger/internal/configure/config"
+	"test-ragger/internal/models"
+)
+
+func scored(ids ...string) []models.ScoredPoint {
+	out := make([]models.ScoredPoint, len(ids))
+	for i, id := range ids {
+		out[i] = models.ScoredPoint{Point: models.Point{ID: id}, Score: float32(len(ids) - i)}
+	}
+	return out
+}
+
+func fusedIDs(list []fused) string {
+	ids := make([]string, len(list))
+	for i, f := range list {
+		ids[i] = f.id
+	}
+	return fmt.Sprint(ids)
+}
+
+func TestFuseRRF(t *testing.T) {
+	tests := []struct {
+		name           string
+		dense, keyword []models.ScoredPoint
+		denseWeight    float64
+		keywordWeight  float64
+		want           string
+	}{
+		{"both lists beat one", scored("a", "b", "c"), scored("c", "d"), 1, 1, "[c a b d]"},
+		{"rank ties break by id", scored("b", "x"), scored("a", "y"), 1, 1, "[a b x y]"},
+		{"keyword weight", scored("a", "b"), scored("b", "c"), 0.2, 1, "[b c a]"},
+		{"dense only", scored("a", "b"), nil, 1, 1, "[a b]"},
+		{"keyword only", nil, scored("b", "a"), 1, 1, "[b a]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := fuse(tt.dense, tt.keyword, Options{Fusion: FusionRRF, RRFK: 60, DenseWeight: tt.denseWeight, KeywordWeight: tt.keywordWeight})
+			if ids := fusedIDs(got); ids != tt.want {
+				t.Errorf("fused = %s, want %s", ids, tt.want)
+			}
+		})
+	}
+
+	// 1/(60+3) + 1/(60+1) for c, with its ranks in both lists
+	c := fuse(scored("a", "b", "c"), scored("c", "d"), Options{Fusion: FusionRRF, RRFK: 60, DenseWeight: 1, KeywordWeight: 1})[0]
+	if c.denseRank != 3 || c.keywordRank != 1 || math.Abs(c.score-(1.0/63+1.0/61)) > 1e-12 {
+		t.Errorf("c = %+v", c)
+	}
+}
+
+func TestFuseWeighted(t *testing.T) {
+	dense := []models.ScoredPoint{
+		{Point: models.Point{ID: "a"}, Score: 0.9},
+		{Point: models.Point{ID: "b"}, Score: 0.8},
+		{Point: models.Point{ID: "c"}, Score: 0.5},
+	}
+	// BM25 scores live on another scale, so both lists are scaled to [0, 1]
+	keyword := []models.ScoredPoint{
+		{Point: models.Point{ID: "c"}, Score: 14},
+		{Point: models.Point{ID: "b"}, Score: 4},
+	}
+	got := fuse(dense, keyword, Options{Fusion: FusionWeighted, DenseWeight: 0.6, KeywordWeight: 0.4})
+
+	want := map[string]float64{
+		"a": 0.6 * 1,
+		"b": 0.6*0.75 + 0.4*0,
+		"c": 0.6*0 + 0.4*1,
+	}
+	if ids := fusedIDs(got); ids != "[a b c]" {
+		t.Errorf("fused = %s, want [a b c]", ids)
+	}
+	for _, f := range got {
+		if math.Abs(f.score-want[f.id]) > 1e-6 {
+			t.Errorf("%s score = %v, want %v", f.id, f.score, want[f.id])
+		}
+	}
+
+	// a single distinct score counts as the best one
+	single := fuse(scored("a"), []models.ScoredPoint{{Point: models.Point{ID: "b"}, Score: 3}}, Options{Fusion: FusionWeighted, DenseWeight: 1, KeywordWeight: 1})
+	if single[0].score != 1 || single[1].score != 1 {
+		t.Errorf("single-score lists = %+v", single)
+	}
+}
+
+// identifierIndex matches only the chunk quoting the error code, which
+// dense retrieval ranks fifth
+type identifierIndex struct{}
+
+func (identifierIndex) SearchText(ctx context.Context, collection, query string, limit int, filter *models.Filter) ([]models.ScoredPoint, error) {
+	if query != "ERR-4012" {
+		return nil, nil
+	}
+	return []models.ScoredPoint{{Point: models.Point{ID: "p04"}, Score: 11.2}}, nil
+}
+
+func TestHybridExactIdentifier(t *testing.T) {
+	ctx := config.IntoContext(context.Background(), config.Defaults())
+	for _, fusion := range []string{FusionRRF, FusionWeighted} {
+		t.Run(fusion, func(t *testing.T) {
+			u := New(fakeEmbedder{}, &fakeStore{}, identifierIndex{}, nil, nil)
+			hits, err := u.Search(ctx, "ERR-4012", "m", Options{TopK: 3, Mode: ModeHybrid, Fusion: fusion, RRFK: 60, DenseWeight: 1, KeywordWeight: 1, Candidates: 10})
+			if err != nil {
+				t.Fatal(err)
+			}
+			ids := make([]string, len(hits))
+			for i, h := range hits {
+				ids[i] = h.ID
+			}
+			if fmt.Sprint(ids) != "[p04 p00 p01]" {
+				t.Errorf("hits = %v, want the exact match first", ids)
+			}
+			if hits[0].KeywordScore != 11.2 || hits[0].DenseScore != 0.96 {
+				t.Errorf("hit = %+v, want both scores kept", hits[0])
+			}
+		})
+	}
+}
@@ -0,0 +1,36 @@
+package search
+
+import (
+	"sort"
+
+	"test-ragger/internal/models"
+)
+
+// group collects ranked hits into documents ordered by their best chunk;
+// documents with more matching chunks win ties. Each document keeps up to
+// size chunks (all of them when size is 0) in rank order.
+func group(hits []models.Hit, size int) []models.DocumentHit {
+	byDoc := make(map[string]int)
+	var docs []models.DocumentHit
+	for _, h := range hits {
+		i, ok := byDoc[h.DocID]
+		if !ok {
+			i = len(docs)
+			byDoc[h.DocID] = i
+			docs = append(docs, models.DocumentHit{DocID: h.DocID, Title: h.Title, Path: h.Path, Score: h.Score})
+		}
+		d := &docs[i]
+		d.Matches++
+		d.Score = max(d.Score, h.Score)
+		if size == 0 || len(d.Chunks) < size {
+			d.Chunks = append(d.Chunks, h)
+		}
+	}
+	sort.SliceStable(docs, func(i, j int) bool {
+		if docs[i].Score != docs[j].Score {
+			return docs[i].Score > docs[j].Score
+		}
+		return docs[i].Matches > docs[j].Matches
+	})
+	return docs
+}
@@ -0,0 +1,97 @@
+package search
+
+import (
+	"math"
+
+	"test-ragger/internal/models"
+)
+
+// mmr selects up to k hits by Maximal Marginal Relevance:
+// lambda·relevance − (1−lambda)·max similarity to the hits already chosen.
+// Relevance is the hit score scaled to [0, 1]; similarity is the cosine of
+// hit vectors. Hits without vectors are never considered redundant.
+// maxPerDoc > 0 skips hits of documents that already have that many.
+func mmr(hits []models.Hit, k int, lambda float64, maxPerDoc int) []models.Hit {
+	if len(hits) == 0 {
+		return hits
+	}
+	lo, hi := hits[0].Score, hits[0].Score
+	for _, h := range hits[1:] {
+		lo = min(lo, h.Score)
+		hi = max(hi, h.Score)
+	}
+	relevance := func(h models.Hit) float64 {
+		if hi == lo {
+			return 1
+		}
+		return float64(h.Score-lo) / float64(hi-lo)
+	}
+
+	norms := make([]float64, len(hits))
+	for i, h := range hits {
+		norms[i] = norm(h.Vector)
+	}
+	// maxSim[i] is the highest similarity of hit i to a selected hit
+	maxSim := make([]float64, len(hits))
+	used := make([]bool, len(hits))
+	perDoc := make(map[string]int)
+
+	selected := make([]models.Hit, 0, k)
+	for len(selected) < k {
+		best, bestScore := -1, math.Inf(-1)
+		for i, h := range hits {
+			if used[i] || (maxPerDoc > 0 && perDoc[h.DocID] >= maxPerDoc) {
+				continue
+			}
+			score := lambda*relevance(h) - (1-lambda)*maxSim[i]
+			if score > bestScore {
+				best, bestScore = i, score
+			}
+		}
+		if best < 0 {
+			break
+		}
+		used[best] = true
+		perDoc[hits[best].DocID]++
+		selected = append(selected, hits[best])
+
+		for i := range hits {
+			if !used[i] {
+				maxSim[i] = max(maxSim[i], cosine(hits[i].Vector, hits[best].Vector, norms[i], norms[best]))
+			}
+		}
+	}
+	return selected
+}
+
+// capPerDoc keeps at most n hits of every document, preserving order
+func capPerDoc(hits []models.Hit, n int) []models.Hit {
+	perDoc := make(map[string]int)
+	out := make([]models.Hit, 0, len(hits))
+	for _, h := range hits {
+		if perDoc[h.DocID] < n {
+			perDoc[h.DocID]++
+			out = append(out, h)
+		}
+	}
+	return out
+}
+
+func norm(v []float32) float64 {
+	var s float64
+	for _, x := range v {
+		s += float64(x) * float64(x)
+	}
+	return math.Sqrt(s)
+}
+
+func cosine(a, b []float32, na, nb float64) float64 {
+	if len(a) == 0 || len(a) != len(b) || na == 0 || nb == 0 {
+		return 0
+	}
+	var dot float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+	}
+	return dot / (na * nb)
+}
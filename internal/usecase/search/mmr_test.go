@@ -0,0 +1,64 @@
+package search
+
+import (
+	"fmt"
+	"testing"
+
+	"test-ragger/internal/models"
+)
+
+// nearDuplicates are three near-identical chunks of one document ranked
+// first, followed by two different ones
+var nearDuplicates = []models.Hit{
+	{ID: "a1", DocID: "a", Score: 0.95, Vector: []float32{1, 0, 0}},
+	{ID: "a2", DocID: "a", Score: 0.94, Vector: []float32{0.99, 0.01, 0}},
+	{ID: "a3", DocID: "a", Score: 0.93, Vector: []float32{0.98, 0.02, 0}},
+	{ID: "b1", DocID: "b", Score: 0.80, Vector: []float32{0, 1, 0}},
+	{ID: "c1", DocID: "c", Score: 0.70, Vector: []float32{0, 0, 1}},
+}
+
+func hitIDs(hits []models.Hit) string {
+	ids := make([]string, len(hits))
+	for i, h := range hits {
+		ids[i] = h.ID
+	}
+	return fmt.Sprint(ids)
+}
+
+func TestMMR(t *testing.T) {
+	noVectors := make([]models.Hit, len(nearDuplicates))
+	for i, h := range nearDuplicates {
+		h.Vector = nil
+		noVectors[i] = h
+	}
+
+	tests := []struct {
+		name      string
+		hits      []models.Hit
+		k         int
+		lambda    float64
+		maxPerDoc int
+		want      string
+	}{
+		{"lambda 1 keeps relevance order", nearDuplicates, 3, 1, 0, "[a1 a2 a3]"},
+		{"lambda 0 prefers diverse vectors", nearDuplicates, 3, 0, 0, "[a1 b1 c1]"},
+		{"balanced takes a duplicate after a new topic", nearDuplicates, 3, 0.6, 0, "[a1 b1 a2]"},
+		{"k above the hits", nearDuplicates, 10, 0, 0, "[a1 b1 c1 a3 a2]"},
+		{"per-document cap", nearDuplicates, 3, 1, 1, "[a1 b1 c1]"},
+		{"hits without vectors are not redundant", noVectors, 3, 0.5, 0, "[a1 a2 a3]"},
+		{"no hits", nil, 3, 0.5, 0, "[]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := hitIDs(mmr(tt.hits, tt.k, tt.lambda, tt.maxPerDoc)); got != tt.want {
+				t.Errorf("mmr = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCapPerDoc(t *testing.T) {
+	if got := hitIDs(capPerDoc(nearDuplicates, 2)); got != "[a1 a2 b1 c1]" {
+		t.Errorf("capPerDoc = %s", got)
+	}
+}
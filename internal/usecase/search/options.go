@@ -0,0 +1,134 @@
+package search
+
+import (
+	"fmt"
+
+	"test-ragger/internal/configure/config"
+)
+
+// Search modes
+const (
+	ModeDense   = "dense"
+	ModeKeyword = "keyword"
+	ModeHybrid  = "hybrid"
+)
+
+// Fusion methods of hybrid search
+const (
+	FusionRRF      = "rrf"
+	FusionWeighted = "weighted"
+)
+
+// Options tune a single query
+type Options struct {
+	TopK int
+	Lang string // payload.lang filter; "" searches all languages
+	// DocID restricts search to the chunks of one document
+	DocID string
+
+	Mode          string // dense | keyword | hybrid
+	Fusion        string // rrf | weighted
+	RRFK          int
+	DenseWeight   float64
+	KeywordWeight float64
+	// Candidates is the number of hits taken from each list before fusion
+	Candidates int
+
+	// Rerank over-fetches RerankCandidates hits and reorders them
+	// with the reranker before cutting to TopK
+	Rerank           bool
+	RerankCandidates int
+
+	// MMR re-selects hits balancing relevance (MMRLambda = 1) against
+	// redundancy with hits already chosen (MMRLambda = 0)
+	MMR       bool
+	MMRLambda float64
+	// MaxPerDoc caps hits of a single document; 0 is no cap
+	MaxPerDoc int
+
+	// GroupSize is the number of chunks kept per document by SearchDocuments
+	GroupSize int
+
+	// Neighbors widens every hit with up to that many adjacent chunks on
+	// each side, merging overlapping text into one passage
+	Neighbors int
+}
+
+// OptionsFromConfig returns query options set by config.toml and CLI flags
+func OptionsFromConfig(cfg config.Config) Options {
+	return Options{
+		TopK:          int(cfg.TopK),
+		Lang:          cfg.Lang,
+		Mode:          cfg.Search.Mode,
+		Fusion:        cfg.Search.Fusion,
+		RRFK:          cfg.Search.RRFK,
+		DenseWeight:   cfg.Search.DenseWeight,
+		KeywordWeight: cfg.Search.KeywordWeight,
+		Candidates:    cfg.Search.Candidates,
+
+		Rerank:           cfg.Rerank.Enabled,
+		RerankCandidates: cfg.Rerank.Candidates,
+
+		MMR:       cfg.Search.MMR,
+		MMRLambda: cfg.Search.MMRLambda,
+		MaxPerDoc: cfg.Search.MaxPerDoc,
+
+		GroupSize: cfg.Search.GroupSize,
+		Neighbors: cfg.Search.Neighbors,
+	}
+}
+
+func (o Options) validate() error {
+	if o.TopK <= 0 {
+		return fmt.Errorf("top-k must be positive, got %d", o.TopK)
+	}
+	if o.MMR && (o.MMRLambda < 0 || o.MMRLambda > 1) {
+		return fmt.Errorf("mmr_lambda must be within [0, 1], got %g", o.MMRLambda)
+	}
+	if o.Neighbors < 0 {
+		return fmt.Errorf("neighbors must not be negative, got %d", o.Neighbors)
+	}
+	if o.GroupSize < 0 {
+		return fmt.Errorf("group_size must not be negative, got %d", o.GroupSize)
+	}
+	if o.MaxPerDoc < 0 {
+		return fmt.Errorf("max_per_doc must not be negative, got %d", o.MaxPerDoc)
+	}
+	switch o.Mode {
+	case ModeDense, ModeKeyword:
+	case ModeHybrid:
+		switch o.Fusion {
+		case FusionRRF:
+			if o.RRFK < 0 {
+				return fmt.Errorf("rrf_k must not be negative, got %d", o.RRFK)
+			}
+		case FusionWeighted:
+		default:
+			return fmt.Errorf("unknown fusion method: %s (want rrf|weighted)", o.Fusion)
+		}
+		if o.DenseWeight < 0 || o.KeywordWeight < 0 || o.DenseWeight+o.KeywordWeight == 0 {
+			return fmt.Errorf("fusion weights must be non-negative and not both zero, got dense=%g keyword=%g", o.DenseWeight, o.KeywordWeight)
+		}
+	default:
+		return fmt.Errorf("unknown search mode: %s (want dense|keyword|hybrid)", o.Mode)
+	}
+	return nil
+}
+
+// depth is the number of hits to retrieve: reranking, MMR and the
+// per-document cap choose top-k from a larger pool
+func (o Options) depth() int {
+	n := o.TopK
+	if o.Rerank {
+		n = max(n, o.RerankCandidates)
+	}
+	if o.MMR || o.MaxPerDoc > 0 {
+		n = max(n, o.Candidates)
+	}
+	return n
+}
+
+// candidates is the per-list depth for hybrid search; never below limit
+func (o Options) candidates(limit int) int {
+	return max(o.Candidates, limit)
+}
@@ -4,84 +4,311 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"sort"
 
-	qdrant "github.com/qdrant/go-client/qdrant"
 	openai "github.com/sashabaranov/go-openai"
 
 	"test-ragger/internal/configure/config"
 	"test-ragger/internal/models"
-	"test-ragger/internal/utils"
 )
 
 // Usecase handles search operations
 type Usecase struct {
-	embeddingClient    EmbeddingClient
-	qdrantPointsClient QdrantPointsClient
-	promptBuilder      PromptBuilder
+	embeddingClient EmbeddingClient
+	vectorStore     VectorStore
+	keywordIndex    KeywordIndex
+	reranker        Reranker
+	promptBuilder   PromptBuilder
 }
 
-// New creates new search usecase
+// New creates new search usecase. keywordIndex may be nil, then only
+// dense search is available; reranker may be nil when reranking is off.
 func New(
 	embeddingClient EmbeddingClient,
-	qdrantPointsClient QdrantPointsClient,
+	vectorStore VectorStore,
+	keywordIndex KeywordIndex,
+	reranker Reranker,
 	promptBuilder PromptBuilder,
 ) *Usecase {
 	return &Usecase{
-		embeddingClient:    embeddingClient,
-		qdrantPointsClient: qdrantPointsClient,
-		promptBuilder:      promptBuilder,
+		embeddingClient: embeddingClient,
+		vectorStore:     vectorStore,
+		keywordIndex:    keywordIndex,
+		reranker:        reranker,
+		promptBuilder:   promptBuilder,
 	}
 }
 
 // Search executes search query and returns results
-func (u *Usecase) Search(ctx context.Context, query string, topK uint64, model openai.EmbeddingModel, langFilter string) ([]models.Hit, error) {
+func (u *Usecase) Search(ctx context.Context, query string, model openai.EmbeddingModel, opts Options) ([]models.Hit, error) {
+	hits, err := u.candidates(ctx, query, model, opts, opts.depth())
+	if err != nil {
+		return nil, err
+	}
+	if opts.MMR {
+		slog.Info("Diversifying results with MMR", "candidates", len(hits), "lambda", opts.MMRLambda, "max_per_doc", opts.MaxPerDoc)
+		hits = mmr(hits, opts.TopK, opts.MMRLambda, opts.MaxPerDoc)
+	} else if opts.MaxPerDoc > 0 {
+		hits = capPerDoc(hits, opts.MaxPerDoc)
+	}
+	if len(hits) > opts.TopK {
+		hits = hits[:opts.TopK]
+	}
+	if opts.Neighbors > 0 {
+		cfg, _ := config.FromContext(ctx)
+		return u.expand(ctx, cfg.Collection, hits, opts.Neighbors)
+	}
+	return hits, nil
+}
+
+// SearchDocuments groups hits by doc_id and returns top-k documents, each
+// with up to GroupSize best chunks. A document scores as its best chunk;
+// more matching chunks break ties. MMR and the per-document cap do not apply.
+func (u *Usecase) SearchDocuments(ctx context.Context, query string, model openai.EmbeddingModel, opts Options) ([]models.DocumentHit, error) {
+	hits, err := u.candidates(ctx, query, model, opts, max(opts.depth(), opts.Candidates, opts.TopK*opts.GroupSize))
+	if err != nil {
+		return nil, err
+	}
+	docs := group(hits, opts.GroupSize)
+	slog.Info("Grouped results by document", "hits", len(hits), "documents", len(docs))
+	if len(docs) > opts.TopK {
+		docs = docs[:opts.TopK]
+	}
+	if opts.Neighbors > 0 {
+		cfg, _ := config.FromContext(ctx)
+		for i := range docs {
+			if docs[i].Chunks, err = u.expand(ctx, cfg.Collection, docs[i].Chunks, opts.Neighbors); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return docs, nil
+}
+
+// candidates validates options, retrieves limit hits and reranks them when asked
+func (u *Usecase) candidates(ctx context.Context, query string, model openai.EmbeddingModel, opts Options, limit int) ([]models.Hit, error) {
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+	if opts.Mode != ModeDense && u.keywordIndex == nil {
+		return nil, fmt.Errorf("%s search needs the keyword index; enable [search] keyword_index or use -search-mode=dense", opts.Mode)
+	}
+	if opts.Rerank && u.reranker == nil {
+		return nil, fmt.Errorf("reranking is not configured; set up the [rerank] table")
+	}
+
+	hits, err := u.retrieve(ctx, query, model, opts, limit)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Rerank {
+		hits = u.rerank(ctx, query, hits)
+	}
+	return hits, nil
+}
+
+// rerank reorders hits by reranker scores. A failing reranker is not fatal:
+// the retrieval order is kept.
+func (u *Usecase) rerank(ctx context.Context, query string, hits []models.Hit) []models.Hit {
+	if len(hits) == 0 {
+		return hits
+	}
+	slog.Info("Reranking candidates", "candidates", len(hits))
+	scores, err := u.reranker.Rerank(ctx, query, hits)
+	if err != nil {
+		slog.Warn("Reranking failed, keeping retrieval order", "error", err)
+		return hits
+	}
+	for i := range hits {
+		hits[i].Reranked = true
+		hits[i].OriginalScore = hits[i].Score
+		hits[i].RerankScore = scores[i]
+		hits[i].Score = scores[i]
+	}
+	// ties keep the retrieval order
+	sort.SliceStable(hits, func(i, j int) bool { return hits[i].RerankScore > hits[j].RerankScore })
+	slog.Info("Reranking completed", "results", len(hits))
+	return hits
+}
+
+// retrieve returns up to limit hits of the configured search mode
+func (u *Usecase) retrieve(ctx context.Context, query string, model openai.EmbeddingModel, opts Options, limit int) ([]models.Hit, error) {
+	cfg, _ := config.FromContext(ctx)
+
+	// build filter if needed
+	var filter *models.Filter
+	if opts.Lang != "" {
+		slog.Info("Applying language filter", "language", opts.Lang)
+		filter = &models.Filter{Must: []models.FieldMatch{{Key: "lang", Value: opts.Lang}}}
+	}
+	if opts.DocID != "" {
+		slog.Info("Applying document filter", "doc_id", opts.DocID)
+		if filter == nil {
+			filter = &models.Filter{}
+		}
+		filter.Must = append(filter.Must, models.FieldMatch{Key: "doc_id", Value: opts.DocID})
+	}
+
+	// MMR compares hits with each other by their vectors
+	withVectors := opts.MMR
+
+	switch opts.Mode {
+	case ModeDense:
+		results, err := u.denseSearch(ctx, cfg.Collection, query, model, limit, filter, withVectors)
+		if err != nil {
+			return nil, err
+		}
+		hits := make([]models.Hit, len(results))
+		for i, r := range results {
+			hits[i] = toHit(r.Point, r.Score)
+			hits[i].DenseScore = r.Score
+		}
+		return hits, nil
+	case ModeKeyword:
+		results, err := u.keywordSearch(ctx, cfg.Collection, query, limit, filter)
+		if err != nil {
+			return nil, err
+		}
+		points, err := u.points(ctx, cfg.Collection, results, nil, withVectors)
+		if err != nil {
+			return nil, err
+		}
+		hits := make([]models.Hit, 0, len(results))
+		for _, r := range results {
+			p, ok := points[r.ID]
+			if !ok {
+				continue
+			}
+			hit := toHit(p, r.Score)
+			hit.KeywordScore = r.Score
+			hits = append(hits, hit)
+		}
+		return hits, nil
+	}
+
+	// hybrid: both lists at candidate depth, fused
+	dense, err := u.denseSearch(ctx, cfg.Collection, query, model, opts.candidates(limit), filter, withVectors)
+	if err != nil {
+		return nil, err
+	}
+	keyword, err := u.keywordSearch(ctx, cfg.Collection, query, opts.candidates(limit), filter)
+	if err != nil {
+		return nil, err
+	}
+	ranked := fuse(dense, keyword, opts)
+	if len(ranked) > limit {
+		ranked = ranked[:limit]
+	}
+	slog.Info("Fused search results", "method", opts.Fusion, "dense", len(dense), "keyword", len(keyword), "results", len(ranked))
+
+	top := make([]models.ScoredPoint, len(ranked))
+	for i, f := range ranked {
+		top[i] = models.ScoredPoint{Point: models.Point{ID: f.id}}
+	}
+	points, err := u.points(ctx, cfg.Collection, top, dense, withVectors)
+	if err != nil {
+		return nil, err
+	}
+	hits := make([]models.Hit, 0, len(ranked))
+	for _, f := range ranked {
+		p, ok := points[f.id]
+		if !ok {
+			continue
+		}
+		hit := toHit(p, float32(f.score))
+		hit.DenseScore = f.dense
+		hit.KeywordScore = f.keyword
+		hits = append(hits, hit)
+	}
+	return hits, nil
+}
+
+func (u *Usecase) denseSearch(ctx context.Context, collection, query string, model openai.EmbeddingModel, limit int, filter *models.Filter, withVectors bool) ([]models.ScoredPoint, error) {
 	cfg, _ := config.FromContext(ctx)
 
 	// create query embedding
 	slog.Info("Creating embedding for query", "query", query)
-	emb, err := u.embeddingClient.CreateEmbeddings(ctx, openai.EmbeddingRequest{Model: model, Input: []string{query}})
+	emb, err := u.embeddingClient.CreateEmbeddings(ctx, openai.EmbeddingRequest{Model: model, Input: []string{query}, Dimensions: cfg.Embedding.Dimensions})
 	if err != nil {
 		return nil, err
 	}
 	vec := emb.Data[0].Embedding
 	slog.Info("Query embedding created", "dimension", len(vec))
 
-	// build filter if needed
-	var filter *qdrant.Filter
-	if langFilter != "" {
-		slog.Info("Applying language filter", "language", langFilter)
-		filter = &qdrant.Filter{Must: []*qdrant.Condition{{ConditionOneOf: &qdrant.Condition_Field{Field: &qdrant.FieldCondition{Key: "lang", Match: &qdrant.Match{MatchValue: &qdrant.Match_Keyword{Keyword: langFilter}}}}}}}
-	}
-
 	// execute search
-	slog.Info("Executing vector search", "collection", cfg.Collection, "top_k", topK)
-	resp, err := u.qdrantPointsClient.Search(ctx, &qdrant.SearchPoints{
-		CollectionName: cfg.Collection,
-		Vector:         vec,
-		Limit:          topK,
-		Params:         &qdrant.SearchParams{HnswEf: utils.Uint64Ptr(128)},
-		Filter:         filter,
-		WithPayload:    &qdrant.WithPayloadSelector{SelectorOptions: &qdrant.WithPayloadSelector_Enable{Enable: true}},
+	slog.Info("Executing vector search", "collection", collection, "limit", limit)
+	results, err := u.vectorStore.Search(ctx, collection, models.SearchRequest{
+		Vector:      vec,
+		Limit:       limit,
+		Filter:      filter,
+		WithVectors: withVectors,
 	})
 	if err != nil {
 		return nil, err
 	}
-	slog.Info("Vector search completed", "results_retrieved", len(resp.Result))
-
-	// convert to hits
-	hits := make([]models.Hit, 0, len(resp.Result))
-	for _, r := range resp.Result {
-		pl := r.Payload
-		hits = append(hits, models.Hit{
-			Score:   r.GetScore(),
-			Title:   fmt.Sprintf("%v", pl["title"]),
-			Text:    fmt.Sprintf("%v", pl["text"]),
-			Path:    fmt.Sprintf("%v", pl["path"]),
-			DocID:   fmt.Sprintf("%v", pl["doc_id"]),
-			ChunkID: fmt.Sprintf("%v", pl["chunk_id"]),
-		})
+	slog.Info("Vector search completed", "results_retrieved", len(results))
+	return results, nil
+}
+
+func (u *Usecase) keywordSearch(ctx context.Context, collection, query string, limit int, filter *models.Filter) ([]models.ScoredPoint, error) {
+	slog.Info("Executing keyword search", "collection", collection, "limit", limit)
+	results, err := u.keywordIndex.SearchText(ctx, collection, query, limit, filter)
+	if err != nil {
+		return nil, err
+	}
+	slog.Info("Keyword search completed", "results_retrieved", len(results))
+	return results, nil
+}
+
+// points returns stored points by ID, reusing those that came with known
+// results and fetching the rest from the vector store
+func (u *Usecase) points(ctx context.Context, collection string, points, known []models.ScoredPoint, withVectors bool) (map[string]models.Point, error) {
+	out := make(map[string]models.Point, len(points))
+	for _, p := range known {
+		out[p.ID] = p.Point
+	}
+	var missing []string
+	for _, p := range points {
+		if _, ok := out[p.ID]; !ok {
+			missing = append(missing, p.ID)
+		}
+	}
+	if len(missing) == 0 {
+		return out, nil
+	}
+
+	page, err := u.vectorStore.Scroll(ctx, collection, models.ScrollRequest{
+		Filter:      &models.Filter{IDs: missing},
+		Limit:       len(missing),
+		WithVectors: withVectors,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("fetch points: %w", err)
+	}
+	for _, p := range page.Points {
+		out[p.ID] = p
+	}
+	if len(page.Points) < len(missing) {
+		slog.Warn("Keyword index refers to missing points", "missing", len(missing)-len(page.Points))
+	}
+	return out, nil
+}
+
+func toHit(p models.Point, score float32) models.Hit {
+	pl := p.Payload
+	return models.Hit{
+		ID:      p.ID,
+		Vector:  p.Vector,
+		Score:   score,
+		Title:   pl.String("title"),
+		Text:    pl.String("text"),
+		Path:    pl.String("path"),
+		DocID:   pl.String("doc_id"),
+		ChunkID: pl.String("chunk_id"),
+		Lang:    pl.String("lang"),
+		Start:   int(pl.Int("start")),
+		End:     int(pl.Int("end")),
 	}
-	return hits, nil
 }
 
 // BuildPrompt creates LLM prompt from search results
@@ -0,0 +1,113 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	openai "github.com/sashabaranov/go-openai"
+
+	"test-ragger/internal/configure/config"
+	"test-ragger/internal/models"
+)
+
+type fakeEmbedder struct{}
+
+func (fakeEmbedder) CreateEmbeddings(ctx context.Context, req openai.EmbeddingRequestConverter) (openai.EmbeddingResponse, error) {
+	return openai.EmbeddingResponse{Data: []openai.Embedding{{Embedding: []float32{1, 0}}}}, nil
+}
+
+// fakeStore returns up to 100 points for any query, p00 scoring best
+type fakeStore struct {
+	limits []int
+}
+
+func (s *fakeStore) Search(ctx context.Context, collection string, req models.SearchRequest) ([]models.ScoredPoint, error) {
+	s.limits = append(s.limits, req.Limit)
+	out := make([]models.ScoredPoint, min(req.Limit, 100))
+	for i := range out {
+		out[i] = models.ScoredPoint{
+			Point: models.Point{ID: fmt.Sprintf("p%02d", i), Payload: models.Payload{"doc_id": fmt.Sprintf("d%02d", i)}},
+			Score: 1 - float32(i)/100,
+		}
+	}
+	return out, nil
+}
+
+func (s *fakeStore) Scroll(ctx context.Context, collection string, req models.ScrollRequest) (models.ScrollPage, error) {
+	return models.ScrollPage{}, nil
+}
+
+// reverseReranker prefers the hits retrieval ranked lowest
+type reverseReranker struct {
+	seen int
+	err  error
+}
+
+func (r *reverseReranker) Rerank(ctx context.Context, query string, hits []models.Hit) ([]float32, error) {
+	r.seen = len(hits)
+	if r.err != nil {
+		return nil, r.err
+	}
+	scores := make([]float32, len(hits))
+	for i := range hits {
+		scores[i] = float32(i)
+	}
+	return scores, nil
+}
+
+func TestSearchRerankTruncation(t *testing.T) {
+	ctx := config.IntoContext(context.Background(), config.Defaults())
+	tests := []struct {
+		name       string
+		topK       int
+		candidates int
+		err        error
+		wantLimit  int
+		wantIDs    string
+	}{
+		{"over-fetch and cut", 3, 10, nil, 10, "[p09 p08 p07]"},
+		{"candidates below top-k", 4, 2, nil, 4, "[p03 p02 p01 p00]"},
+		{"failing reranker keeps retrieval order", 3, 10, errors.New("judge down"), 10, "[p00 p01 p02]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &fakeStore{}
+			reranker := &reverseReranker{err: tt.err}
+			u := New(fakeEmbedder{}, store, nil, reranker, nil)
+
+			hits, err := u.Search(ctx, "q", "m", Options{TopK: tt.topK, Mode: ModeDense, Rerank: true, RerankCandidates: tt.candidates})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if fmt.Sprint(store.limits) != fmt.Sprint([]int{tt.wantLimit}) || reranker.seen != tt.wantLimit {
+				t.Errorf("retrieved %v, reranked %d; want %d", store.limits, reranker.seen, tt.wantLimit)
+			}
+			ids := make([]string, len(hits))
+			for i, h := range hits {
+				ids[i] = h.ID
+				if h.Reranked != (tt.err == nil) {
+					t.Errorf("hit %s reranked = %v", h.ID, h.Reranked)
+				}
+			}
+			if fmt.Sprint(ids) != tt.wantIDs {
+				t.Errorf("hits = %v, want %s", ids, tt.wantIDs)
+			}
+		})
+	}
+}
+
+func TestSearchRerankKeepsOriginalScore(t *testing.T) {
+	ctx := config.IntoContext(context.Background(), config.Defaults())
+	u := New(fakeEmbedder{}, &fakeStore{}, nil, &reverseReranker{}, nil)
+
+	hits, err := u.Search(ctx, "q", "m", Options{TopK: 1, Mode: ModeDense, Rerank: true, RerankCandidates: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := hits[0]
+	if h.ID != "p04" || h.RerankScore != 4 || h.Score != 4 || h.OriginalScore != 0.96 {
+		t.Errorf("hit = %+v, want p04 with rerank score 4 and original score 0.96", h)
+	}
+}
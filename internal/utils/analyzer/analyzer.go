@@ -0,0 +1,161 @@
+package analyzer
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/kljensen/snowball/english"
+	"github.com/kljensen/snowball/russian"
+)
+
+// Version identifies the term pipeline; indexes built with another
+// version must be rebuilt
+const Version = "snowball-2"
+
+// joiners glue identifier parts together: ERR-1042, sku_88, v1.2.3
+const joiners = "-_./"
+
+// Analyzer turns text into index terms: Unicode tokenization, lowercasing,
+// ё→е folding, stop-word removal and Snowball stemming. Identifiers with
+// digits or joiners are kept verbatim next to their parts.
+type Analyzer struct {
+	lang string
+	stop func(string) bool
+	stem func(string) string
+}
+
+var (
+	russianAnalyzer = &Analyzer{lang: "ru", stop: russian.IsStopWord, stem: stemRussian}
+	englishAnalyzer = &Analyzer{lang: "en", stop: english.IsStopWord, stem: stemEnglish}
+	defaultAnalyzer = &Analyzer{lang: "", stop: func(w string) bool { return russian.IsStopWord(w) || english.IsStopWord(w) }, stem: stemByScript}
+)
+
+// For returns the analyzer of a payload.lang value: stop words and the
+// stemmer of that language. Unknown or empty languages drop both Russian
+// and English stop words and stem every word by its script.
+func For(lang string) *Analyzer {
+	switch strings.ToLower(lang) {
+	case "ru":
+		return russianAnalyzer
+	case "en":
+		return englishAnalyzer
+	}
+	return defaultAnalyzer
+}
+
+// All returns every analyzer For can select, so that a query can be
+// analyzed the way documents of each language were
+func All() []*Analyzer {
+	return []*Analyzer{russianAnalyzer, englishAnalyzer, defaultAnalyzer}
+}
+
+// Lang returns the language the analyzer was selected for ("" by default)
+func (a *Analyzer) Lang() string {
+	return a.lang
+}
+
+// Terms analyzes text into index terms in text order
+func (a *Analyzer) Terms(text string) []string {
+	var terms []string
+	for _, token := range Tokenize(text) {
+		parts := strings.FieldsFunc(token, isJoiner)
+		if len(parts) > 1 || hasDigit(token) {
+			terms = append(terms, token)
+			if len(parts) == 1 {
+				continue
+			}
+		}
+		for _, p := range parts {
+			if t, ok := a.word(p); ok {
+				terms = append(terms, t)
+			}
+		}
+	}
+	return terms
+}
+
+// word normalizes a single word; false drops a stop word
+func (a *Analyzer) word(w string) (string, bool) {
+	if hasDigit(w) {
+		return w, true
+	}
+	if a.stop(w) {
+		return "", false
+	}
+	return a.stem(w), true
+}
+
+func stemRussian(w string) string {
+	return russian.Stem(w, true)
+}
+
+func stemEnglish(w string) string {
+	return english.Stem(w, true)
+}
+
+// stemByScript stems words of documents without a known language
+func stemByScript(w string) string {
+	switch script(w) {
+	case cyrillic:
+		return stemRussian(w)
+	case latin:
+		return stemEnglish(w)
+	}
+	return w
+}
+
+// Tokenize splits text into lowercase tokens with ё folded to е. Letters,
+// digits and combining marks form words; joiners inside a token are kept.
+func Tokenize(text string) []string {
+	var tokens []string
+	for _, t := range strings.FieldsFunc(text, func(r rune) bool {
+		return !isWordRune(r) && !isJoiner(r)
+	}) {
+		t = strings.Trim(t, joiners)
+		if t == "" {
+			continue
+		}
+		tokens = append(tokens, fold(t))
+	}
+	return tokens
+}
+
+func fold(s string) string {
+	s = strings.ToLower(s)
+	return strings.ReplaceAll(s, "ё", "е")
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsMark(r)
+}
+
+func isJoiner(r rune) bool {
+	return strings.ContainsRune(joiners, r)
+}
+
+func hasDigit(s string) bool {
+	return strings.IndexFunc(s, unicode.IsDigit) >= 0
+}
+
+type scriptKind int
+
+const (
+	other scriptKind = iota
+	cyrillic
+	latin
+)
+
+// script classifies a word by its first letter
+func script(w string) scriptKind {
+	for _, r := range w {
+		switch {
+		case unicode.Is(unicode.Cyrillic, r):
+			return cyrillic
+		case unicode.Is(unicode.Latin, r):
+			return latin
+		case unicode.IsLetter(r):
+			return other
+		}
+	}
+	return other
+}
@@ -0,0 +1,57 @@
+package analyzer
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestTerms(t *testing.T) {
+	tests := []struct {
+		name string
+		lang string
+		text string
+		want string
+	}{
+		{"english stems", "en", "Printers printing printed pages", "[printer print print page]"},
+		{"english stop words", "en", "The tray is in the printer", "[tray printer]"},
+		{"russian stems", "ru", "Отпускные выплачивают до отпуска", "[отпускн выплачива отпуск]"},
+		{"russian stop words", "ru", "Заявление на отпуск подают за две недели", "[заявлен отпуск пода две недел]"},
+		{"ё is folded", "ru", "Ёлка ещё зелёная", "[елк зелен]"},
+		{"identifier kept with its parts", "en", "Error ERR-4012 again", "[error err-4012 err 4012]"},
+		{"identifier case and joiners", "ru", "Код ERR_4012, версия v1.2.3.", "[код err_4012 err 4012 верс v1.2.3 v1 2 3]"},
+		{"word with digits", "en", "sku88 costs 5", "[sku88 cost 5]"},
+		{"trailing joiners are dropped", "en", "-- printers... --", "[printer]"},
+		{"mixed script with a language", "en", "Принтер printer", "[принтер printer]"},
+		{"mixed script without a language", "", "Принтеры печатают, printers print", "[принтер печата printer print]"},
+		{"stop words of both languages", "", "и the", "[]"},
+		{"unknown language", "de", "Drucker und printers", "[drucker und printer]"},
+		{"empty", "en", "", "[]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := fmt.Sprint(For(tt.lang).Terms(tt.text)); got != tt.want {
+				t.Errorf("Terms(%q) = %s, want %s", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFor(t *testing.T) {
+	tests := []struct {
+		lang string
+		want string
+	}{
+		{"ru", "ru"},
+		{"EN", "en"},
+		{"", ""},
+		{"uk", ""},
+	}
+	for _, tt := range tests {
+		if got := For(tt.lang).Lang(); got != tt.want {
+			t.Errorf("For(%q).Lang() = %q, want %q", tt.lang, got, tt.want)
+		}
+	}
+	if n := len(All()); n != 3 {
+		t.Errorf("All() has %d analyzers, want 3", n)
+	}
+}
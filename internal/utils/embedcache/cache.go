@@ -0,0 +1,434 @@
+package embedcache
+
+import (
+	"bufio"
+	"bytes"
+	"container/list"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"test-ragger/internal/utils"
+	"test-ragger/internal/utils/filelock"
+)
+
+// fileMagic starts every cache file
+const fileMagic = "TRGEMB1\n"
+
+// entryOverhead approximates per-entry bookkeeping for size accounting
+const entryOverhead = 64
+
+// minCompactBytes keeps Put from rewriting small files
+const minCompactBytes = 1 << 20
+
+// Options configures the cache; zero limits mean unlimited
+type Options struct {
+	Path       string
+	MaxEntries int
+	MaxBytes   int64
+}
+
+// Stats describes cache contents and activity of the current process
+type Stats struct {
+	Path       string
+	Entries    int
+	Bytes      int64
+	FileBytes  int64
+	MaxEntries int
+	MaxBytes   int64
+	Models     map[string]int
+	Hits       int64
+	Misses     int64
+}
+
+type entry struct {
+	key string
+	vec []float32
+}
+
+func (e *entry) size() int64 {
+	return int64(len(e.key)) + 4*int64(len(e.vec)) + entryOverhead
+}
+
+// Cache is an LRU embedding cache persisted in a single file.
+// New entries are appended to the file as they arrive; the file is
+// rewritten in LRU order on Close, dropping evicted entries, and by Put
+// once evicted and overwritten entries make up most of it.
+//
+// Several processes may share the file: it is guarded by a lock file next
+// to it, and writes and compaction first catch up with entries other
+// processes appended (or reload a file another process rewrote).
+type Cache struct {
+	mu    sync.Mutex
+	opts  Options
+	items map[string]*list.Element
+	lru   *list.List // front = most recently used
+	bytes int64
+
+	lock   *filelock.Lock // guards the file across processes
+	f      *os.File       // nil until the file is opened
+	offset int64          // length of the file replayed into the LRU
+	dirty  bool
+
+	hits   int64
+	misses int64
+}
+
+// Key builds a cache key from model, requested dimensions and text
+func Key(model string, dimensions int, text string) string {
+	return model + "|" + strconv.Itoa(dimensions) + "|" + utils.Sha256Hex([]byte(text))
+}
+
+// Open loads the cache file, creating it if needed
+func Open(opts Options) (*Cache, error) {
+	if err := os.MkdirAll(filepath.Dir(opts.Path), 0o755); err != nil {
+		return nil, fmt.Errorf("create cache dir: %w", err)
+	}
+	lock, err := filelock.Open(opts.Path + ".lock")
+	if err != nil {
+		return nil, err
+	}
+	c := &Cache{opts: opts, items: make(map[string]*list.Element), lru: list.New(), lock: lock}
+	if err := c.exclusive(c.refresh); err != nil {
+		c.release()
+		return nil, fmt.Errorf("load cache %s: %w", opts.Path, err)
+	}
+	return c, nil
+}
+
+// exclusive runs fn holding the exclusive lock of the file
+func (c *Cache) exclusive(fn func() error) error {
+	if err := c.lock.Lock(); err != nil {
+		return err
+	}
+	err := fn()
+	return errors.Join(err, c.lock.Unlock())
+}
+
+// refresh replays entries appended to the file since the last call and
+// reloads the cache when another process rewrote or removed the file.
+// A torn tail or a foreign file is cut off. The caller holds the
+// exclusive lock.
+func (c *Cache) refresh() error {
+	st, err := os.Stat(c.opts.Path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	if c.f != nil {
+		cur, err := c.f.Stat()
+		if err != nil {
+			return err
+		}
+		if st == nil || !os.SameFile(st, cur) || st.Size() < c.offset {
+			c.f.Close()
+			c.f = nil
+		}
+	}
+	if c.f == nil {
+		f, err := os.OpenFile(c.opts.Path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0o644)
+		if err != nil {
+			return err
+		}
+		if st, err = f.Stat(); err != nil {
+			f.Close()
+			return err
+		}
+		c.f = f
+		c.offset = 0
+		c.items = make(map[string]*list.Element)
+		c.lru.Init()
+		c.bytes = 0
+	}
+	if c.offset > 0 && st.Size() == c.offset {
+		return nil
+	}
+
+	r := bufio.NewReader(io.NewSectionReader(c.f, c.offset, st.Size()-c.offset))
+	if c.offset == 0 {
+		magic := make([]byte, len(fileMagic))
+		if _, err := io.ReadFull(r, magic); err != nil || string(magic) != fileMagic {
+			if st.Size() > 0 {
+				slog.Warn("Embedding cache file is unknown, starting fresh", "path", c.opts.Path)
+			}
+			return c.truncate(0)
+		}
+		c.offset = int64(len(fileMagic))
+	}
+	n, torn := c.replay(r)
+	c.offset += n
+	c.evict()
+	if torn {
+		slog.Warn("Embedding cache file has a torn tail, truncating", "path", c.opts.Path, "offset", c.offset)
+		return c.truncate(c.offset)
+	}
+	return nil
+}
+
+// truncate cuts the file to size, writing the magic into an empty file
+func (c *Cache) truncate(size int64) error {
+	if err := c.f.Truncate(size); err != nil {
+		return err
+	}
+	if size == 0 {
+		if _, err := c.f.WriteString(fileMagic); err != nil {
+			return err
+		}
+		size = int64(len(fileMagic))
+	}
+	c.offset = size
+	return nil
+}
+
+// replay inserts the entries of r and returns the length of its valid
+// prefix; torn reports a partial entry after it
+func (c *Cache) replay(r io.Reader) (n int64, torn bool) {
+	for {
+		e, size, err := readEntry(r)
+		if err != nil {
+			return n, !errors.Is(err, io.EOF)
+		}
+		n += size
+		c.insert(e)
+	}
+}
+
+// record layout: keyLen uint16 | key | dim uint32 | dim × float32 (little endian)
+func readEntry(r io.Reader) (*entry, int64, error) {
+	var hdr [2]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil {
+		return nil, 0, err
+	}
+	key := make([]byte, binary.LittleEndian.Uint16(hdr[:]))
+	if _, err := io.ReadFull(r, key); err != nil {
+		return nil, 0, io.ErrUnexpectedEOF
+	}
+	var dim [4]byte
+	if _, err := io.ReadFull(r, dim[:]); err != nil {
+		return nil, 0, io.ErrUnexpectedEOF
+	}
+	raw := make([]byte, 4*binary.LittleEndian.Uint32(dim[:]))
+	if _, err := io.ReadFull(r, raw); err != nil {
+		return nil, 0, io.ErrUnexpectedEOF
+	}
+	vec := make([]float32, len(raw)/4)
+	for i := range vec {
+		vec[i] = math.Float32frombits(binary.LittleEndian.Uint32(raw[4*i:]))
+	}
+	return &entry{key: string(key), vec: vec}, int64(2 + len(key) + 4 + len(raw)), nil
+}
+
+func writeEntry(w io.Writer, e *entry) error {
+	buf := make([]byte, 2+len(e.key)+4+4*len(e.vec))
+	binary.LittleEndian.PutUint16(buf, uint16(len(e.key)))
+	n := 2 + copy(buf[2:], e.key)
+	binary.LittleEndian.PutUint32(buf[n:], uint32(len(e.vec)))
+	n += 4
+	for _, v := range e.vec {
+		binary.LittleEndian.PutUint32(buf[n:], math.Float32bits(v))
+		n += 4
+	}
+	_, err := w.Write(buf)
+	return err
+}
+
+// insert adds or refreshes an entry as the most recently used one
+func (c *Cache) insert(e *entry) {
+	if el, ok := c.items[e.key]; ok {
+		old := el.Value.(*entry)
+		c.bytes += e.size() - old.size()
+		el.Value = e
+		c.lru.MoveToFront(el)
+		return
+	}
+	c.items[e.key] = c.lru.PushFront(e)
+	c.bytes += e.size()
+}
+
+// evict drops least recently used entries until limits are met
+func (c *Cache) evict() {
+	for c.lru.Len() > 0 &&
+		((c.opts.MaxEntries > 0 && c.lru.Len() > c.opts.MaxEntries) ||
+			(c.opts.MaxBytes > 0 && c.bytes > c.opts.MaxBytes)) {
+		el := c.lru.Back()
+		e := el.Value.(*entry)
+		c.lru.Remove(el)
+		delete(c.items, e.key)
+		c.bytes -= e.size()
+		c.dirty = true
+	}
+}
+
+// Get returns a copy of a cached vector and marks it as recently used
+func (c *Cache) Get(key string) ([]float32, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.items[key]
+	if !ok {
+		c.misses++
+		return nil, false
+	}
+	c.hits++
+	c.lru.MoveToFront(el)
+	c.dirty = true
+	return slices.Clone(el.Value.(*entry).vec), true
+}
+
+// Put appends vectors to the cache file and stores them. Entries other
+// processes appended meanwhile are picked up on the way. The file is
+// compacted when it outgrows twice the size limit or twice its live
+// entries, so long-running servers do not grow it forever.
+func (c *Cache) Put(keys []string, vecs [][]float32) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.exclusive(func() error {
+		if err := c.refresh(); err != nil {
+			return err
+		}
+		var buf bytes.Buffer
+		for i, key := range keys {
+			if err := writeEntry(&buf, &entry{key: key, vec: vecs[i]}); err != nil {
+				return fmt.Errorf("write cache: %w", err)
+			}
+		}
+		if _, err := c.f.Write(buf.Bytes()); err != nil {
+			return fmt.Errorf("write cache: %w", err)
+		}
+		// replaying the written bytes keeps the caller's slices out of the cache
+		n, _ := c.replay(bytes.NewReader(buf.Bytes()))
+		c.offset += n
+		c.dirty = true
+		c.evict()
+		if !c.compactDue() {
+			return nil
+		}
+		slog.Info("Compacting embedding cache", "path", c.opts.Path, "file_bytes", c.offset, "entries", c.lru.Len())
+		if err := c.compact(); err != nil {
+			return err
+		}
+		c.dirty = false
+		return c.refresh()
+	})
+}
+
+// compactDue reports whether dead entries make up most of the file
+func (c *Cache) compactDue() bool {
+	if c.offset < minCompactBytes {
+		return false
+	}
+	if c.opts.MaxBytes > 0 && c.offset > 2*c.opts.MaxBytes {
+		return true
+	}
+	live := int64(len(fileMagic)) + c.bytes - entryOverhead*int64(c.lru.Len())
+	return c.offset > 2*live
+}
+
+// Stats reports cache contents
+func (c *Cache) Stats() Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	s := Stats{
+		Path:       c.opts.Path,
+		Entries:    c.lru.Len(),
+		Bytes:      c.bytes,
+		MaxEntries: c.opts.MaxEntries,
+		MaxBytes:   c.opts.MaxBytes,
+		Models:     make(map[string]int),
+		Hits:       c.hits,
+		Misses:     c.misses,
+	}
+	if fi, err := os.Stat(c.opts.Path); err == nil {
+		s.FileBytes = fi.Size()
+	}
+	for key := range c.items {
+		model, rest, _ := strings.Cut(key, "|")
+		dims, _, _ := strings.Cut(rest, "|")
+		if dims != "0" {
+			model += " (dimensions=" + dims + ")"
+		}
+		s.Models[model]++
+	}
+	return s
+}
+
+// Purge removes all entries, including those of other processes
+func (c *Cache) Purge() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.exclusive(func() error {
+		c.items = make(map[string]*list.Element)
+		c.lru.Init()
+		c.bytes = 0
+		c.dirty = false
+		if err := c.compact(); err != nil {
+			return err
+		}
+		return c.refresh()
+	})
+}
+
+// Close compacts the file in LRU order, so recency survives restarts.
+// Under the exclusive lock the cache first catches up with the whole
+// file, so the rewrite keeps entries other processes appended.
+func (c *Cache) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	defer c.release()
+	return c.exclusive(func() error {
+		if err := c.refresh(); err != nil {
+			return err
+		}
+		if !c.dirty {
+			return nil
+		}
+		return c.compact()
+	})
+}
+
+// compact replaces the file with the entries in memory
+func (c *Cache) compact() error {
+	tmp := c.opts.Path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return fmt.Errorf("compact cache: %w", err)
+	}
+	w := bufio.NewWriter(f)
+	if _, err := w.WriteString(fileMagic); err != nil {
+		f.Close()
+		return err
+	}
+	// oldest first: replaying the file restores the LRU order
+	for el := c.lru.Back(); el != nil; el = el.Prev() {
+		if err := writeEntry(w, el.Value.(*entry)); err != nil {
+			f.Close()
+			return fmt.Errorf("compact cache: %w", err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, c.opts.Path)
+}
+
+// release closes the file and the lock
+func (c *Cache) release() {
+	if c.f != nil {
+		c.f.Close()
+		c.f = nil
+	}
+	c.lock.Close()
+}
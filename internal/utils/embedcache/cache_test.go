@@ -0,0 +1,111 @@
+package embedcache
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// bigVector fills a quarter of a megabyte, so a few entries pass minCompactBytes
+func bigVector(v float32) []float32 {
+	vec := make([]float32, 64*1024)
+	for i := range vec {
+		vec[i] = v
+	}
+	return vec
+}
+
+func openCache(t *testing.T, opts Options) *Cache {
+	t.Helper()
+	c, err := Open(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestPutCompacts(t *testing.T) {
+	tests := []struct {
+		name string
+		opts Options
+	}{
+		{"evicted entries", Options{MaxEntries: 2}},
+		{"size limit", Options{MaxBytes: 600 * 1024}},
+		{"overwritten entries", Options{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.opts.Path = filepath.Join(t.TempDir(), "embeddings.bin")
+			c := openCache(t, tt.opts)
+			key := func(i int) string {
+				if tt.opts.MaxEntries == 0 && tt.opts.MaxBytes == 0 {
+					i %= 2
+				}
+				return Key("m", 0, fmt.Sprint(i))
+			}
+
+			var largest int64
+			for i := range 12 {
+				if err := c.Put([]string{key(i)}, [][]float32{bigVector(float32(i))}); err != nil {
+					t.Fatal(err)
+				}
+				st, err := os.Stat(tt.opts.Path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				largest = max(largest, st.Size())
+			}
+			// two live entries of 256KiB: without compaction the file holds all twelve
+			if largest > 1600*1024 {
+				t.Errorf("file grew to %d bytes", largest)
+			}
+			for _, i := range []int{10, 11} {
+				if vec, ok := c.Get(key(i)); !ok || vec[0] != float32(i) {
+					t.Errorf("entry %d lost by compaction", i)
+				}
+			}
+			if err := c.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			c = openCache(t, tt.opts)
+			defer c.Close()
+			if st := c.Stats(); st.Entries != 2 {
+				t.Errorf("reopened cache has %d entries, want 2", st.Entries)
+			}
+		})
+	}
+}
+
+func TestSharedFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "embeddings.bin")
+	a := openCache(t, Options{Path: path})
+	b := openCache(t, Options{Path: path})
+
+	if err := a.Put([]string{"k1"}, [][]float32{{1, 2}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Put([]string{"k2"}, [][]float32{{3, 4}}); err != nil {
+		t.Fatal(err)
+	}
+	// b picked up a's entry before appending its own
+	if _, ok := b.Get("k1"); !ok {
+		t.Error("second cache misses the first one's entry")
+	}
+	if err := b.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Purge(); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	c := openCache(t, Options{Path: path})
+	defer c.Close()
+	if st := c.Stats(); st.Entries != 0 {
+		t.Errorf("%d entries after Purge, want none", st.Entries)
+	}
+}
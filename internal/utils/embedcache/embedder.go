@@ -0,0 +1,108 @@
+package embedcache
+
+import (
+	"context"
+	"fmt"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// EmbeddingClient is the embeddings API implemented by both wrapped and wrapping clients
+type EmbeddingClient interface {
+	CreateEmbeddings(ctx context.Context, req openai.EmbeddingRequestConverter) (openai.EmbeddingResponse, error)
+}
+
+// Embedder serves embeddings from the cache and asks the wrapped
+// client only for texts it has not seen yet
+type Embedder struct {
+	next  EmbeddingClient
+	cache *Cache
+}
+
+// NewEmbedder wraps next with the cache
+func NewEmbedder(next EmbeddingClient, cache *Cache) *Embedder {
+	return &Embedder{next: next, cache: cache}
+}
+
+func (e *Embedder) CreateEmbeddings(ctx context.Context, conv openai.EmbeddingRequestConverter) (openai.EmbeddingResponse, error) {
+	req, inputs, ok := stringRequest(conv)
+	if !ok {
+		// token inputs are rare and not cached
+		return e.next.CreateEmbeddings(ctx, conv)
+	}
+
+	keys := make([]string, len(inputs))
+	vecs := make([][]float32, len(inputs))
+	var (
+		missIdx   []int
+		missTexts []string
+	)
+	for i, text := range inputs {
+		keys[i] = Key(string(req.Model), req.Dimensions, text)
+		if v, ok := e.cache.Get(keys[i]); ok {
+			vecs[i] = v
+			continue
+		}
+		missIdx = append(missIdx, i)
+		missTexts = append(missTexts, text)
+	}
+
+	res := openai.EmbeddingResponse{Object: "list", Model: req.Model}
+	if len(missTexts) > 0 {
+		missReq := req
+		missReq.Input = missTexts
+		inner, err := e.next.CreateEmbeddings(ctx, missReq)
+		if err != nil {
+			return openai.EmbeddingResponse{}, err
+		}
+		if len(inner.Data) != len(missTexts) {
+			return openai.EmbeddingResponse{}, fmt.Errorf("got %d embeddings for %d inputs", len(inner.Data), len(missTexts))
+		}
+
+		missKeys := make([]string, len(missTexts))
+		missVecs := make([][]float32, len(missTexts))
+		for _, d := range inner.Data {
+			if d.Index < 0 || d.Index >= len(missIdx) {
+				return openai.EmbeddingResponse{}, fmt.Errorf("unexpected embedding index %d", d.Index)
+			}
+			i := missIdx[d.Index]
+			vecs[i] = d.Embedding
+			missKeys[d.Index] = keys[i]
+			missVecs[d.Index] = d.Embedding
+		}
+		if err := e.cache.Put(missKeys, missVecs); err != nil {
+			return openai.EmbeddingResponse{}, err
+		}
+		res.Usage = inner.Usage
+		res.Model = inner.Model
+	}
+
+	res.Data = make([]openai.Embedding, len(inputs))
+	for i, v := range vecs {
+		res.Data[i] = openai.Embedding{Object: "embedding", Embedding: v, Index: i}
+	}
+	return res, nil
+}
+
+// stringRequest normalizes requests carrying text inputs
+func stringRequest(conv openai.EmbeddingRequestConverter) (openai.EmbeddingRequest, []string, bool) {
+	switch r := conv.(type) {
+	case openai.EmbeddingRequest:
+		switch in := r.Input.(type) {
+		case string:
+			return r, []string{in}, true
+		case []string:
+			return r, in, true
+		}
+	case openai.EmbeddingRequestStrings:
+		req := openai.EmbeddingRequest{
+			Model:          r.Model,
+			User:           r.User,
+			EncodingFormat: r.EncodingFormat,
+			Dimensions:     r.Dimensions,
+			ExtraBody:      r.ExtraBody,
+		}
+		return req, r.Input, true
+	}
+	return openai.EmbeddingRequest{}, nil, false
+}
@@ -0,0 +1,44 @@
+// Package filelock provides advisory locks shared by processes that
+// use the same on-disk stores (ingest, search and the servers).
+package filelock
+
+import (
+	"fmt"
+	"os"
+)
+
+// Lock is an advisory lock on a file next to the data it guards. The lock
+// file itself is never replaced, so data files can be compacted and renamed
+// while the lock is held.
+type Lock struct {
+	f *os.File
+}
+
+// Open opens or creates the lock file at path
+func Open(path string) (*Lock, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("open lock %s: %w", path, err)
+	}
+	return &Lock{f: f}, nil
+}
+
+// Lock waits for the exclusive lock
+func (l *Lock) Lock() error {
+	return l.lock(true)
+}
+
+// RLock waits for a shared lock
+func (l *Lock) RLock() error {
+	return l.lock(false)
+}
+
+// Unlock releases the lock
+func (l *Lock) Unlock() error {
+	return l.unlock()
+}
+
+// Close releases the lock and closes the lock file
+func (l *Lock) Close() error {
+	return l.f.Close()
+}
@@ -0,0 +1,13 @@
+//go:build !unix
+
+package filelock
+
+// Without flock the stores are only safe for a single process at a time
+
+func (l *Lock) lock(exclusive bool) error {
+	return nil
+}
+
+func (l *Lock) unlock() error {
+	return nil
+}
@@ -0,0 +1,32 @@
+//go:build unix
+
+package filelock
+
+import (
+	"fmt"
+	"syscall"
+)
+
+func (l *Lock) lock(exclusive bool) error {
+	how := syscall.LOCK_SH
+	if exclusive {
+		how = syscall.LOCK_EX
+	}
+	for {
+		err := syscall.Flock(int(l.f.Fd()), how)
+		if err == syscall.EINTR {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("lock %s: %w", l.f.Name(), err)
+		}
+		return nil
+	}
+}
+
+func (l *Lock) unlock() error {
+	if err := syscall.Flock(int(l.f.Fd()), syscall.LOCK_UN); err != nil {
+		return fmt.Errorf("unlock %s: %w", l.f.Name(), err)
+	}
+	return nil
+}
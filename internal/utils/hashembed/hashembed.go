@@ -0,0 +1,148 @@
+package hashembed
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"hash/fnv"
+	"math"
+	"math/rand/v2"
+	"strings"
+	"unicode"
+
+	openai "github.com/sashabaranov/go-openai"
+
+	"test-ragger/internal/utils"
+)
+
+// DefaultDim is used when neither the registry nor the request sets a size
+const DefaultDim = 256
+
+// hashesPerToken spreads every token over several coordinates,
+// which keeps collisions between different words cheap
+const hashesPerToken = 4
+
+// Embedder produces deterministic unit vectors without any network access.
+// In random mode every distinct text maps to an unrelated pseudo-random
+// vector. In bag-of-words mode a text is the normalized sum of hashed
+// token vectors, so texts sharing words land near each other.
+type Embedder struct {
+	dim        int
+	bagOfWords bool
+}
+
+// New creates an offline embedder with the default vector size dim
+func New(dim int, bagOfWords bool) *Embedder {
+	if dim <= 0 {
+		dim = DefaultDim
+	}
+	return &Embedder{dim: dim, bagOfWords: bagOfWords}
+}
+
+func (e *Embedder) CreateEmbeddings(ctx context.Context, conv openai.EmbeddingRequestConverter) (openai.EmbeddingResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return openai.EmbeddingResponse{}, err
+	}
+
+	var (
+		model  openai.EmbeddingModel
+		dim    int
+		inputs []string
+	)
+	switch r := conv.(type) {
+	case openai.EmbeddingRequest:
+		model, dim = r.Model, r.Dimensions
+		switch in := r.Input.(type) {
+		case string:
+			inputs = []string{in}
+		case []string:
+			inputs = in
+		default:
+			return openai.EmbeddingResponse{}, errors.New("hash embedder supports only text inputs")
+		}
+	case openai.EmbeddingRequestStrings:
+		model, dim, inputs = r.Model, r.Dimensions, r.Input
+	default:
+		return openai.EmbeddingResponse{}, errors.New("hash embedder supports only text inputs")
+	}
+	if dim <= 0 {
+		dim = e.dim
+	}
+
+	res := openai.EmbeddingResponse{Object: "list", Model: model, Data: make([]openai.Embedding, len(inputs))}
+	for i, text := range inputs {
+		res.Data[i] = openai.Embedding{Object: "embedding", Embedding: e.Embed(string(model), text, dim), Index: i}
+		res.Usage.PromptTokens += utils.EstimateTokens(text)
+	}
+	res.Usage.TotalTokens = res.Usage.PromptTokens
+	return res, nil
+}
+
+// Embed returns the unit vector of text for the given model and size
+func (e *Embedder) Embed(model, text string, dim int) []float32 {
+	v := make([]float64, dim)
+	if e.bagOfWords {
+		for _, tok := range tokenize(text) {
+			addToken(v, tok)
+		}
+	}
+	if isZero(v) {
+		// random mode, or a text without a single word
+		seed := sha256.Sum256([]byte(model + "\x00" + text))
+		rng := rand.New(rand.NewPCG(binary.LittleEndian.Uint64(seed[:8]), binary.LittleEndian.Uint64(seed[8:16])))
+		for i := range v {
+			v[i] = rng.NormFloat64()
+		}
+	}
+	return normalize(v)
+}
+
+// addToken adds the signed hashed coordinates of a token to v
+func addToken(v []float64, tok string) {
+	for k := 0; k < hashesPerToken; k++ {
+		h := fnv.New64a()
+		h.Write([]byte{byte(k)})
+		h.Write([]byte(tok))
+		sum := h.Sum64()
+		idx := int(sum % uint64(len(v)))
+		if sum>>63 == 1 {
+			v[idx]--
+		} else {
+			v[idx]++
+		}
+	}
+}
+
+// tokenize lowercases text and splits it into words of letters and digits
+func tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+func isZero(v []float64) bool {
+	for _, x := range v {
+		if x != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func normalize(v []float64) []float32 {
+	var norm float64
+	for _, x := range v {
+		norm += x * x
+	}
+	norm = math.Sqrt(norm)
+
+	out := make([]float32, len(v))
+	if norm == 0 {
+		return out
+	}
+	for i, x := range v {
+		out[i] = float32(x / norm)
+	}
+	return out
+}
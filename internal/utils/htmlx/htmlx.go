@@ -11,13 +11,16 @@ import (
 
 var cleanReSpace = regexp.MustCompile(`\s+`)
 
-// ToText parses an HTML file and returns normalized text and title.
-func ToText(r io.Reader, fallbackPath string) (string, string, error) {
+// ToText parses an HTML file and returns normalized text, title and the
+// declared language ("" when the page does not declare one).
+func ToText(r io.Reader, fallbackPath string) (string, string, string, error) {
 	doc, err := goquery.NewDocumentFromReader(r)
 	if err != nil {
-		return "", "", err
+		return "", "", "", err
 	}
 
+	lang := declaredLang(doc)
+
 	// remove noise
 	doc.Find("script,style,nav,footer,header,noscript").Each(func(i int, s *goquery.Selection) {
 		s.Remove()
@@ -30,5 +33,35 @@ func ToText(r io.Reader, fallbackPath string) (string, string, error) {
 
 	text := strings.TrimSpace(doc.Text())
 	text = cleanReSpace.ReplaceAllString(text, " ")
-	return text, title, nil
+	return text, title, lang, nil
+}
+
+// declaredLang reads <html lang> or <meta http-equiv="content-language">
+// and returns the primary language subtag: "ru-RU" → "ru"
+func declaredLang(doc *goquery.Document) string {
+	if lang, ok := doc.Find("html").First().Attr("lang"); ok && primaryTag(lang) != "" {
+		return primaryTag(lang)
+	}
+	lang := ""
+	doc.Find("meta[http-equiv]").EachWithBreak(func(i int, s *goquery.Selection) bool {
+		if !strings.EqualFold(s.AttrOr("http-equiv", ""), "content-language") {
+			return true
+		}
+		// the header may list several languages: "ru, en"
+		first, _, _ := strings.Cut(s.AttrOr("content", ""), ",")
+		lang = primaryTag(first)
+		return lang == ""
+	})
+	return lang
+}
+
+func primaryTag(tag string) string {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	primary, _, _ := strings.Cut(strings.ReplaceAll(tag, "_", "-"), "-")
+	for _, r := range primary {
+		if r < 'a' || r > 'z' {
+			return ""
+		}
+	}
+	return primary
 }
@@ -0,0 +1,620 @@
+package keyword
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"sync"
+
+	"test-ragger/internal/models"
+	"test-ragger/internal/utils/analyzer"
+	"test-ragger/internal/utils/filelock"
+)
+
+// BM25 parameters
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// Index journal events
+const (
+	eventMeta   = "meta"
+	eventAdd    = "add"
+	eventDelete = "delete"
+)
+
+// collectionName keeps index files inside the index directory
+var collectionName = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// record is a single line of an index file
+type record struct {
+	Op string `json:"op"`
+
+	// meta
+	Analyzer string `json:"analyzer,omitempty"`
+
+	// add, delete
+	ID     string         `json:"id,omitempty"`
+	Fields Fields         `json:"fields,omitempty"`
+	Terms  map[string]int `json:"terms,omitempty"`
+}
+
+// Fields are payload keywords kept next to the terms, so that
+// searches can apply the same filters as the vector store
+type Fields map[string]string
+
+// filterFields are the payload keys copied into the index
+var filterFields = []string{"doc_id", "lang", "type"}
+
+type entry struct {
+	fields Fields
+	terms  map[string]int
+	length int
+}
+
+// Index is a BM25 inverted index over chunk texts, one JSONL file per
+// collection. Additions and deletions are appended as they happen;
+// Close compacts files that carry stale records. Processes may share the
+// files: each has a lock file next to it, and every operation first
+// catches up with lines other processes appended (or reloads a file
+// another process rewrote).
+type Index struct {
+	dir string
+
+	mu          sync.Mutex
+	collections map[string]*collectionIndex
+}
+
+// collectionIndex mirrors an index file: entries and postings are the
+// replay of its first offset bytes
+type collectionIndex struct {
+	path     string
+	entries  map[string]*entry
+	postings map[string]map[string]int // term → point ID → term frequency
+	totalLen int64
+
+	lock   *filelock.Lock // guards the file across processes
+	f      *os.File       // nil until the file is opened
+	offset int64
+	stale  int
+	fresh  bool
+}
+
+// Open opens an index in dir, creating the directory if needed.
+// Collections are loaded on first use.
+func Open(dir string) (*Index, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create keyword index dir: %w", err)
+	}
+	return &Index{dir: dir, collections: make(map[string]*collectionIndex)}, nil
+}
+
+// Add indexes points by their "text" payload, replacing earlier versions
+func (x *Index) Add(collection string, points []models.Point) error {
+	return x.locked(collection, func(c *collectionIndex) error {
+		records := make([]record, 0, len(points))
+		for _, p := range points {
+			records = append(records, addRecord(p))
+		}
+		return c.append(records)
+	})
+}
+
+// Delete removes points matching filter
+func (x *Index) Delete(collection string, filter models.Filter) error {
+	return x.locked(collection, func(c *collectionIndex) error {
+		var records []record
+		for id, e := range c.entries {
+			if matches(&filter, id, e.fields) {
+				records = append(records, record{Op: eventDelete, ID: id})
+			}
+		}
+		return c.append(records)
+	})
+}
+
+// NeedsRebuild reports whether the collection file was missing or written
+// by another analyzer, so the index does not reflect the vector store
+func (x *Index) NeedsRebuild(collection string) (bool, error) {
+	var fresh bool
+	err := x.locked(collection, func(c *collectionIndex) error {
+		fresh = c.fresh
+		return nil
+	})
+	return fresh, err
+}
+
+// Len returns the number of indexed points of a collection
+func (x *Index) Len(collection string) (int, error) {
+	var n int
+	err := x.locked(collection, func(c *collectionIndex) error {
+		n = len(c.entries)
+		return nil
+	})
+	return n, err
+}
+
+// Rebuild replaces a collection with the points produced by scan. The scan
+// runs without holding the index, so searches and writes go on meanwhile;
+// lines appended to the file during the scan are replayed over its result.
+// The new file is written aside and renamed, so a crash keeps the rebuild
+// pending. If another process rebuilt the file in the meantime, its index
+// is kept. A nil scan empties the collection.
+func (x *Index) Rebuild(collection string, scan func(add func([]models.Point)) error) error {
+	n := newCollectionIndex("")
+	if scan == nil {
+		return x.locked(collection, func(c *collectionIndex) error {
+			return c.swap(n)
+		})
+	}
+
+	var (
+		file   os.FileInfo
+		offset int64
+	)
+	err := x.locked(collection, func(c *collectionIndex) error {
+		var err error
+		file, err = c.f.Stat()
+		offset = c.offset
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	err = scan(func(points []models.Point) {
+		for _, p := range points {
+			n.add(addRecord(p))
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	return x.locked(collection, func(c *collectionIndex) error {
+		cur, err := c.f.Stat()
+		if err != nil {
+			return err
+		}
+		if !os.SameFile(file, cur) || c.offset < offset {
+			if !c.fresh {
+				slog.Info("Keyword index was rebuilt by another process", "path", c.path)
+				return nil
+			}
+		} else {
+			records, err := c.records(offset)
+			if err != nil {
+				return err
+			}
+			for _, r := range records {
+				n.apply(r)
+			}
+		}
+		return c.swap(n)
+	})
+}
+
+// Search ranks points by BM25 score of query terms. Documents are analyzed
+// by their payload.lang, and the query the same way for each of them.
+func (x *Index) Search(collection, query string, limit int, filter *models.Filter) ([]models.ScoredPoint, error) {
+	var hits []models.ScoredPoint
+	err := x.locked(collection, func(c *collectionIndex) error {
+		hits = c.search(query, limit, filter)
+		return nil
+	})
+	return hits, err
+}
+
+func (c *collectionIndex) search(query string, limit int, filter *models.Filter) []models.ScoredPoint {
+	n := len(c.entries)
+	if n == 0 {
+		return nil
+	}
+	avgLen := float64(c.totalLen) / float64(n)
+
+	// every document is scored by the query terms of its own analyzer
+	scores := make(map[string]float64)
+	for _, a := range analyzer.All() {
+		for term := range termFreqs(a.Terms(query)) {
+			posting := c.postings[term]
+			if len(posting) == 0 {
+				continue
+			}
+			df := float64(len(posting))
+			idf := math.Log(1 + (float64(n)-df+0.5)/(df+0.5))
+			for id, tf := range posting {
+				e := c.entries[id]
+				if analyzer.For(e.fields["lang"]) != a || !matches(filter, id, e.fields) {
+					continue
+				}
+				f := float64(tf)
+				scores[id] += idf * f * (bm25K1 + 1) / (f + bm25K1*(1-bm25B+bm25B*float64(e.length)/avgLen))
+			}
+		}
+	}
+
+	hits := make([]models.ScoredPoint, 0, len(scores))
+	for id, s := range scores {
+		hits = append(hits, models.ScoredPoint{Point: models.Point{ID: id}, Score: float32(s)})
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].ID < hits[j].ID
+	})
+	if limit > 0 && len(hits) > limit {
+		hits = hits[:limit]
+	}
+	return hits
+}
+
+// Close compacts files with stale records and releases the collections
+func (x *Index) Close() error {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	var errs []error
+	for name, c := range x.collections {
+		if err := c.close(); err != nil {
+			errs = append(errs, fmt.Errorf("close keyword index %s: %w", name, err))
+		}
+	}
+	x.collections = make(map[string]*collectionIndex)
+	return errors.Join(errs...)
+}
+
+func (x *Index) path(collection string) string {
+	return filepath.Join(x.dir, collection+".bm25.jsonl")
+}
+
+// locked runs fn on a collection caught up with its file, holding the
+// file lock
+func (x *Index) locked(name string, fn func(c *collectionIndex) error) error {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	c, err := x.collection(name)
+	if err != nil {
+		return err
+	}
+	if err := c.lock.Lock(); err != nil {
+		return err
+	}
+	if err := c.refresh(); err != nil {
+		err = fmt.Errorf("load keyword index %s: %w", name, err)
+		return errors.Join(err, c.lock.Unlock())
+	}
+	return errors.Join(fn(c), c.lock.Unlock())
+}
+
+// collection returns the index of a collection; its file is read by the
+// first refresh
+func (x *Index) collection(name string) (*collectionIndex, error) {
+	if c, ok := x.collections[name]; ok {
+		return c, nil
+	}
+	if !collectionName.MatchString(name) {
+		return nil, fmt.Errorf("invalid collection name %q", name)
+	}
+	c := newCollectionIndex(x.path(name))
+	lock, err := filelock.Open(c.path + ".lock")
+	if err != nil {
+		return nil, err
+	}
+	c.lock = lock
+	x.collections[name] = c
+	return c, nil
+}
+
+func newCollectionIndex(path string) *collectionIndex {
+	return &collectionIndex{path: path, entries: make(map[string]*entry), postings: make(map[string]map[string]int)}
+}
+
+// refresh replays lines appended to the file since the last call and
+// reloads the index when another process rewrote the file. A missing file
+// or one written by another analyzer is replaced by an empty index marked
+// fresh, to be rebuilt from the vector store. The caller holds the lock.
+func (c *collectionIndex) refresh() error {
+	st, err := os.Stat(c.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return c.reset()
+	}
+	if err != nil {
+		return err
+	}
+	if c.f != nil {
+		cur, err := c.f.Stat()
+		if err != nil {
+			return err
+		}
+		if !os.SameFile(st, cur) || st.Size() < c.offset {
+			c.f.Close()
+			c.f = nil
+		}
+	}
+	if c.f == nil {
+		f, err := os.OpenFile(c.path, os.O_RDWR|os.O_APPEND, 0o644)
+		if err != nil {
+			return err
+		}
+		c.clear()
+		c.f = f
+	}
+
+	r := bufio.NewReaderSize(io.NewSectionReader(c.f, c.offset, st.Size()-c.offset), 64*1024)
+	for {
+		line, err := r.ReadBytes('\n')
+		if err != nil {
+			if len(line) > 0 {
+				// a torn last line after a crash
+				slog.Warn("Keyword index file has a torn tail, truncating", "path", c.path, "offset", c.offset)
+				return c.f.Truncate(c.offset)
+			}
+			return nil
+		}
+		var rec record
+		if err := json.Unmarshal(line, &rec); err != nil {
+			slog.Warn("Ignoring malformed keyword index entry", "path", c.path, "error", err)
+		}
+		if c.offset == 0 && (rec.Op != eventMeta || rec.Analyzer != analyzer.Version) {
+			slog.Info("Keyword index was built by another analyzer, rebuilding", "path", c.path)
+			return c.reset()
+		}
+		c.offset += int64(len(line))
+		c.apply(rec)
+	}
+}
+
+// records reads the lines of the file between from and the replayed offset
+func (c *collectionIndex) records(from int64) ([]record, error) {
+	var out []record
+	r := bufio.NewReaderSize(io.NewSectionReader(c.f, from, c.offset-from), 64*1024)
+	for {
+		line, err := r.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			return out, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		var rec record
+		if err := json.Unmarshal(line, &rec); err == nil {
+			out = append(out, rec)
+		}
+	}
+}
+
+// swap takes over the entries of a rebuilt index and rewrites the file
+func (c *collectionIndex) swap(n *collectionIndex) error {
+	c.entries, c.postings, c.totalLen = n.entries, n.postings, n.totalLen
+	c.fresh = false
+	return c.replace()
+}
+
+// reset replaces the file with an empty index marked fresh
+func (c *collectionIndex) reset() error {
+	c.clear()
+	c.fresh = true
+	return c.replace()
+}
+
+// clear forgets the file and everything replayed from it
+func (c *collectionIndex) clear() {
+	if c.f != nil {
+		c.f.Close()
+		c.f = nil
+	}
+	c.entries = make(map[string]*entry)
+	c.postings = make(map[string]map[string]int)
+	c.totalLen = 0
+	c.offset = 0
+	c.stale = 0
+	// a file read from disk is up to date unless reset marks it otherwise
+	c.fresh = false
+}
+
+// replace writes the live entries to a new file and continues appending to it
+func (c *collectionIndex) replace() error {
+	if c.f != nil {
+		c.f.Close()
+		c.f = nil
+	}
+	if err := c.writeSnapshot(); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(c.path, os.O_RDWR|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	st, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	c.f = f
+	c.offset = st.Size()
+	c.stale = 0
+	return nil
+}
+
+// append writes records to the file, then applies them
+func (c *collectionIndex) append(records []record) error {
+	if len(records) == 0 {
+		return nil
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, r := range records {
+		if err := enc.Encode(r); err != nil {
+			return fmt.Errorf("write keyword index: %w", err)
+		}
+	}
+	if _, err := c.f.Write(buf.Bytes()); err != nil {
+		return fmt.Errorf("write keyword index: %w", err)
+	}
+	c.offset += int64(buf.Len())
+	for _, r := range records {
+		c.apply(r)
+	}
+	return nil
+}
+
+func (c *collectionIndex) apply(r record) {
+	switch r.Op {
+	case eventAdd:
+		c.add(r)
+	case eventDelete:
+		if _, ok := c.entries[r.ID]; ok {
+			c.remove(r.ID)
+			c.stale++
+		}
+		c.stale++
+	}
+}
+
+func (c *collectionIndex) add(r record) {
+	if _, ok := c.entries[r.ID]; ok {
+		c.remove(r.ID)
+		c.stale++
+	}
+	e := &entry{fields: r.Fields, terms: r.Terms}
+	for term, tf := range r.Terms {
+		e.length += tf
+		posting, ok := c.postings[term]
+		if !ok {
+			posting = make(map[string]int)
+			c.postings[term] = posting
+		}
+		posting[r.ID] = tf
+	}
+	c.entries[r.ID] = e
+	c.totalLen += int64(e.length)
+}
+
+func (c *collectionIndex) remove(id string) {
+	e, ok := c.entries[id]
+	if !ok {
+		return
+	}
+	for term := range e.terms {
+		delete(c.postings[term], id)
+		if len(c.postings[term]) == 0 {
+			delete(c.postings, term)
+		}
+	}
+	c.totalLen -= int64(e.length)
+	delete(c.entries, id)
+}
+
+// close compacts the file if it carries stale records. The index is first
+// caught up with the file under the lock, so the rewrite keeps lines other
+// processes appended.
+func (c *collectionIndex) close() error {
+	defer c.lock.Close()
+	if err := c.lock.Lock(); err != nil {
+		return err
+	}
+	err := c.refresh()
+	if err == nil && c.stale > 0 {
+		err = c.replace()
+	}
+	if c.f != nil {
+		c.f.Close()
+		c.f = nil
+	}
+	return errors.Join(err, c.lock.Unlock())
+}
+
+// writeSnapshot rewrites the file with live entries only
+func (c *collectionIndex) writeSnapshot() error {
+	tmp := c.path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return fmt.Errorf("write keyword index: %w", err)
+	}
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	if err := enc.Encode(record{Op: eventMeta, Analyzer: analyzer.Version}); err != nil {
+		f.Close()
+		return err
+	}
+	ids := make([]string, 0, len(c.entries))
+	for id := range c.entries {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		e := c.entries[id]
+		if err := enc.Encode(record{Op: eventAdd, ID: id, Fields: e.fields, Terms: e.terms}); err != nil {
+			f.Close()
+			return fmt.Errorf("write keyword index: %w", err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, c.path)
+}
+
+func addRecord(p models.Point) record {
+	terms := analyzer.For(p.Payload.String("lang")).Terms(p.Payload.String("text"))
+	return record{Op: eventAdd, ID: p.ID, Fields: fieldsOf(p.Payload), Terms: termFreqs(terms)}
+}
+
+func fieldsOf(p models.Payload) Fields {
+	fields := make(Fields, len(filterFields))
+	for _, k := range filterFields {
+		if v := p.String(k); v != "" {
+			fields[k] = v
+		}
+	}
+	return fields
+}
+
+// matches evaluates a filter; keys that are not indexed never match
+func matches(f *models.Filter, id string, fields Fields) bool {
+	if f == nil {
+		return true
+	}
+	if len(f.IDs) > 0 {
+		found := false
+		for _, x := range f.IDs {
+			if x == id {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	for _, m := range f.Must {
+		if fields[m.Key] != m.Value {
+			return false
+		}
+	}
+	return true
+}
+
+func termFreqs(tokens []string) map[string]int {
+	tf := make(map[string]int, len(tokens))
+	for _, t := range tokens {
+		tf[t]++
+	}
+	return tf
+}
@@ -0,0 +1,231 @@
+package keyword
+
+import (
+	"slices"
+	"testing"
+
+	"test-ragger/internal/models"
+)
+
+func chunk(id, lang, doc, text string) models.Point {
+	return models.Point{ID: id, Payload: models.Payload{"text": text, "lang": lang, "doc_id": doc}}
+}
+
+var chunks = []models.Point{
+	chunk("e1", "en", "printer", "Error ERR-4012 means the paper tray is empty. Refill the tray."),
+	chunk("e2", "en", "printer", "The printer prints slowly when the toner is low."),
+	chunk("e3", "en", "router", "Restart the router and wait until the lights stop blinking. The router lights blink while it boots, which takes a while, so please be patient with the router."),
+	chunk("r1", "ru", "otpusk", "Заявление на отпуск подают за две недели."),
+	chunk("r2", "ru", "otpusk", "Отпускные выплачивают за три дня до отпуска."),
+}
+
+func openIndex(t *testing.T, dir string) *Index {
+	t.Helper()
+	x, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return x
+}
+
+func seeded(t *testing.T, dir string) *Index {
+	t.Helper()
+	x := openIndex(t, dir)
+	if err := x.Add("docs", chunks); err != nil {
+		t.Fatal(err)
+	}
+	return x
+}
+
+func searchIDs(t *testing.T, x *Index, query string, limit int, filter *models.Filter) []string {
+	t.Helper()
+	hits, err := x.Search("docs", query, limit, filter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := make([]string, len(hits))
+	for i, h := range hits {
+		out[i] = h.ID
+	}
+	return out
+}
+
+func TestSearchBM25(t *testing.T) {
+	x := seeded(t, t.TempDir())
+	defer x.Close()
+
+	tests := []struct {
+		name   string
+		query  string
+		limit  int
+		filter *models.Filter
+		want   []string
+	}{
+		{"exact identifier", "ERR-4012", 0, nil, []string{"e1"}},
+		{"identifier in a sentence", "what does err-4012 mean", 0, nil, []string{"e1"}},
+		{"identifier parts", "err 4012", 0, nil, []string{"e1"}},
+		{"english stems", "printing printers", 0, nil, []string{"e2"}},
+		{"russian stems", "отпуском выплачивают", 0, nil, []string{"r2", "r1"}},
+		{"stop words only", "the and which", 0, nil, []string{}},
+		{"unknown term", "firmware", 0, nil, []string{}},
+		{"limit", "отпуск", 1, nil, []string{"r2"}},
+		{"lang filter", "tray отпуск", 0, &models.Filter{Must: []models.FieldMatch{{Key: "lang", Value: "en"}}}, []string{"e1"}},
+		{"doc filter", "tray router", 0, &models.Filter{Must: []models.FieldMatch{{Key: "doc_id", Value: "router"}}}, []string{"e3"}},
+		{"ids filter", "отпуск", 0, &models.Filter{IDs: []string{"r1"}}, []string{"r1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := searchIDs(t, x, tt.query, tt.limit, tt.filter); !slices.Equal(got, tt.want) {
+				t.Errorf("search %q = %v, want %v", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSearchBM25Weights(t *testing.T) {
+	x := openIndex(t, t.TempDir())
+	defer x.Close()
+	err := x.Add("docs", []models.Point{
+		chunk("short", "en", "a", "router lights"),
+		chunk("long", "en", "b", "router lights and a lot of other words about cables, power supplies, fans and boxes"),
+		chunk("often", "en", "c", "router router router lights"),
+		chunk("common", "en", "d", "lights lights"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hits, err := x.Search("docs", "router lights", 0, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	score := make(map[string]float32)
+	for _, h := range hits {
+		score[h.ID] = h.Score
+	}
+	// term frequency counts, length normalizes, rare terms weigh more
+	if !(score["often"] > score["short"]) {
+		t.Errorf("more occurrences should score higher: %v", score)
+	}
+	if !(score["short"] > score["long"]) {
+		t.Errorf("a shorter chunk should score higher: %v", score)
+	}
+	if !(score["long"] > score["common"]) {
+		t.Errorf("the rarer term should outweigh a repeated common one: %v", score)
+	}
+}
+
+func TestAddReplacesAndDelete(t *testing.T) {
+	x := seeded(t, t.TempDir())
+	defer x.Close()
+
+	if err := x.Add("docs", []models.Point{chunk("e2", "en", "printer", "Replace the toner cartridge.")}); err != nil {
+		t.Fatal(err)
+	}
+	if got := searchIDs(t, x, "slowly", 0, nil); len(got) != 0 {
+		t.Errorf("old text of a replaced chunk still matches: %v", got)
+	}
+	if got := searchIDs(t, x, "cartridge", 0, nil); !slices.Equal(got, []string{"e2"}) {
+		t.Errorf("new text = %v", got)
+	}
+
+	if err := x.Delete("docs", models.Filter{Must: []models.FieldMatch{{Key: "doc_id", Value: "otpusk"}}}); err != nil {
+		t.Fatal(err)
+	}
+	if got := searchIDs(t, x, "отпуск", 0, nil); len(got) != 0 {
+		t.Errorf("deleted document still matches: %v", got)
+	}
+	if n, _ := x.Len("docs"); n != 3 {
+		t.Errorf("Len = %d, want 3", n)
+	}
+}
+
+func TestReopen(t *testing.T) {
+	dir := t.TempDir()
+	x := seeded(t, dir)
+	if err := x.Delete("docs", models.Filter{IDs: []string{"e3"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := x.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	x = openIndex(t, dir)
+	defer x.Close()
+	if rebuild, err := x.NeedsRebuild("docs"); err != nil || rebuild {
+		t.Fatalf("NeedsRebuild = %v, %v after reopening a saved index", rebuild, err)
+	}
+	if n, _ := x.Len("docs"); n != 4 {
+		t.Errorf("Len = %d, want 4", n)
+	}
+	if got := searchIDs(t, x, "ERR-4012", 0, nil); !slices.Equal(got, []string{"e1"}) {
+		t.Errorf("search after reopen = %v", got)
+	}
+}
+
+func TestRebuild(t *testing.T) {
+	x := openIndex(t, t.TempDir())
+	defer x.Close()
+
+	if rebuild, _ := x.NeedsRebuild("docs"); !rebuild {
+		t.Fatal("a missing index file must need a rebuild")
+	}
+	// points added while the store is scanned are kept
+	err := x.Rebuild("docs", func(add func([]models.Point)) error {
+		add(chunks[:2])
+		if err := x.Add("docs", chunks[3:4]); err != nil {
+			return err
+		}
+		add(chunks[2:3])
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rebuild, _ := x.NeedsRebuild("docs"); rebuild {
+		t.Error("NeedsRebuild after Rebuild")
+	}
+	if n, _ := x.Len("docs"); n != 4 {
+		t.Errorf("Len = %d, want the 3 scanned points and the one added meanwhile", n)
+	}
+
+	if err := x.Rebuild("docs", nil); err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := x.Len("docs"); n != 0 {
+		t.Errorf("Len = %d after an empty rebuild", n)
+	}
+}
+
+func TestRebuildByAnotherProcess(t *testing.T) {
+	dir := t.TempDir()
+	a := openIndex(t, dir)
+	defer a.Close()
+	b := openIndex(t, dir)
+	defer b.Close()
+
+	if rebuild, _ := a.NeedsRebuild("docs"); !rebuild {
+		t.Fatal("a missing index file must need a rebuild")
+	}
+	// b rebuilds while a is scanning: a keeps b's index
+	err := a.Rebuild("docs", func(add func([]models.Point)) error {
+		add(chunks[:1])
+		return b.Rebuild("docs", func(add func([]models.Point)) error {
+			add(chunks)
+			return nil
+		})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := a.Len("docs"); n != len(chunks) {
+		t.Errorf("Len = %d, want the %d points of the other rebuild", n, len(chunks))
+	}
+
+	// a reload of a file rebuilt elsewhere is not reported as stale
+	c := openIndex(t, dir)
+	defer c.Close()
+	if rebuild, _ := c.NeedsRebuild("docs"); rebuild {
+		t.Fatal("NeedsRebuild on a rebuilt file")
+	}
+}
@@ -0,0 +1,191 @@
+package keyword
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"test-ragger/internal/models"
+	"test-ragger/internal/utils/vectorstore"
+)
+
+// rebuildPageSize is the scroll page used to rebuild an index from the store
+const rebuildPageSize = 256
+
+// countInterval limits how often searches compare the index size with the store
+const countInterval = 30 * time.Second
+
+// Store wraps a vector store and mirrors upserts and deletes into a keyword
+// index. Missing or outdated indexes are rebuilt from stored chunk texts on
+// first use, so existing collections need no re-ingest. Searches also
+// compare the number of indexed points with the store's and log a warning
+// when they differ, e.g. after an ingest on another host against the same
+// Qdrant; the count is not exact enough to rebuild on.
+type Store struct {
+	vectorstore.Store
+	index *Index
+
+	mu         sync.Mutex
+	counted    map[string]time.Time     // last size comparison per collection
+	rebuilding map[string]chan struct{} // closed when the rebuild finishes
+}
+
+// NewStore wraps next with index. Closing the store closes both.
+func NewStore(next vectorstore.Store, index *Index) *Store {
+	return &Store{
+		Store:      next,
+		index:      index,
+		counted:    make(map[string]time.Time),
+		rebuilding: make(map[string]chan struct{}),
+	}
+}
+
+func (s *Store) CreateCollection(ctx context.Context, collection string, dim int) error {
+	if err := s.Store.CreateCollection(ctx, collection, dim); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.counted[collection] = time.Now()
+	s.mu.Unlock()
+	// leftovers of a dropped collection with the same name
+	return s.index.Rebuild(collection, nil)
+}
+
+func (s *Store) Upsert(ctx context.Context, collection string, points []models.Point) error {
+	if err := s.Store.Upsert(ctx, collection, points); err != nil {
+		return err
+	}
+	if err := s.ensure(ctx, collection, false); err != nil {
+		return err
+	}
+	return s.index.Add(collection, points)
+}
+
+func (s *Store) Delete(ctx context.Context, collection string, filter models.Filter) error {
+	if err := s.Store.Delete(ctx, collection, filter); err != nil {
+		return err
+	}
+	if err := s.ensure(ctx, collection, false); err != nil {
+		return err
+	}
+	return s.index.Delete(collection, filter)
+}
+
+// SearchText ranks chunks by BM25. Returned points carry IDs and scores only.
+func (s *Store) SearchText(ctx context.Context, collection, query string, limit int, filter *models.Filter) ([]models.ScoredPoint, error) {
+	if err := s.ensure(ctx, collection, true); err != nil {
+		return nil, err
+	}
+	return s.index.Search(collection, query, limit, filter)
+}
+
+func (s *Store) Close() error {
+	return errors.Join(s.index.Close(), s.Store.Close())
+}
+
+// ensure rebuilds the index of a collection from the store when its file
+// was missing or written by another analyzer. With count it also compares
+// the number of indexed points with the store, at most every countInterval;
+// writes skip that, as the store is ahead of the index until they finish.
+func (s *Store) ensure(ctx context.Context, collection string, count bool) error {
+	rebuild, err := s.index.NeedsRebuild(collection)
+	if err != nil {
+		return err
+	}
+	if rebuild {
+		return s.rebuild(ctx, collection)
+	}
+	if count && s.due(collection) {
+		s.compare(ctx, collection)
+	}
+	return nil
+}
+
+// due reports whether the size comparison of a collection is due
+func (s *Store) due(collection string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if time.Since(s.counted[collection]) < countInterval {
+		return false
+	}
+	s.counted[collection] = time.Now()
+	return true
+}
+
+// compare warns when the index and the store hold different numbers of
+// points. Qdrant counts are approximate and uploads reach the store before
+// the index, so a mismatch alone does not trigger a rebuild.
+func (s *Store) compare(ctx context.Context, collection string) {
+	info, err := s.Store.CollectionInfo(ctx, collection)
+	if err != nil || info == nil {
+		return
+	}
+	n, err := s.index.Len(collection)
+	if err != nil || uint64(n) == info.Points {
+		return
+	}
+	slog.Warn("Keyword index size differs from the collection; remove the index file to rebuild it if this persists",
+		"collection", collection, "indexed", n, "stored", info.Points)
+}
+
+// rebuild scans the store into the index. Concurrent callers wait for the
+// rebuild in progress; the store lock is not held during the scan.
+func (s *Store) rebuild(ctx context.Context, collection string) error {
+	s.mu.Lock()
+	if done, ok := s.rebuilding[collection]; ok {
+		s.mu.Unlock()
+		select {
+		case <-done:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	done := make(chan struct{})
+	s.rebuilding[collection] = done
+	s.counted[collection] = time.Now()
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.rebuilding, collection)
+		s.mu.Unlock()
+		close(done)
+	}()
+
+	info, err := s.Store.CollectionInfo(ctx, collection)
+	if err != nil {
+		return err
+	}
+	if info == nil || info.Points == 0 {
+		return s.index.Rebuild(collection, nil)
+	}
+	slog.Info("Building keyword index from stored chunks", "collection", collection, "points", info.Points)
+	total := 0
+	err = s.index.Rebuild(collection, func(add func([]models.Point)) error {
+		offset := ""
+		for {
+			page, err := s.Store.Scroll(ctx, collection, models.ScrollRequest{
+				Offset:        offset,
+				Limit:         rebuildPageSize,
+				PayloadFields: []string{"text", "doc_id", "lang", "type"},
+			})
+			if err != nil {
+				return err
+			}
+			add(page.Points)
+			total += len(page.Points)
+			if page.NextOffset == "" {
+				return nil
+			}
+			offset = page.NextOffset
+		}
+	})
+	if err != nil {
+		return fmt.Errorf("rebuild keyword index: %w", err)
+	}
+	slog.Info("Keyword index built", "collection", collection, "points", total)
+	return nil
+}
@@ -0,0 +1,150 @@
+package langdetect
+
+import (
+	"embed"
+	"math"
+	"path"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Profiles are sample texts, one per language: profiles/<lang>.txt.
+// Adding a file adds a language.
+//
+//go:embed profiles/*.txt
+var profileFS embed.FS
+
+const (
+	// minLetters is the least amount of letters a text needs to be classified
+	minLetters = 24
+	// maxRunes bounds the prefix of long texts that is analyzed
+	maxRunes = 4096
+	// minMargin is the least per-trigram log-likelihood gap between the best
+	// and the runner-up language for a confident answer
+	minMargin = 0.15
+	// vocabulary is the smoothing vocabulary size shared by all profiles
+	vocabulary = 10000
+)
+
+// Detector identifies the language of a text offline with a naive Bayes
+// classifier over character trigrams
+type Detector struct {
+	profiles []profile
+}
+
+type profile struct {
+	lang    string
+	logProb map[string]float64
+	unseen  float64
+}
+
+// New builds a detector from the embedded language profiles
+func New() *Detector {
+	entries, err := profileFS.ReadDir("profiles")
+	if err != nil {
+		panic(err)
+	}
+	d := &Detector{}
+	for _, e := range entries {
+		data, err := profileFS.ReadFile(path.Join("profiles", e.Name()))
+		if err != nil {
+			panic(err)
+		}
+		d.profiles = append(d.profiles, newProfile(strings.TrimSuffix(e.Name(), ".txt"), string(data)))
+	}
+	sort.Slice(d.profiles, func(i, j int) bool { return d.profiles[i].lang < d.profiles[j].lang })
+	return d
+}
+
+func newProfile(lang, sample string) profile {
+	counts, _ := trigrams(sample, -1)
+	total := 0
+	for _, c := range counts {
+		total += c
+	}
+	denom := float64(total) + 0.5*vocabulary
+	p := profile{lang: lang, logProb: make(map[string]float64, len(counts)), unseen: math.Log(0.5 / denom)}
+	for t, c := range counts {
+		p.logProb[t] = math.Log((float64(c) + 0.5) / denom)
+	}
+	return p
+}
+
+// Languages lists the languages the detector knows
+func (d *Detector) Languages() []string {
+	langs := make([]string, len(d.profiles))
+	for i, p := range d.profiles {
+		langs[i] = p.lang
+	}
+	return langs
+}
+
+// Detect returns the most likely language of text. ok is false when the
+// text has too few letters or no language is clearly ahead.
+func (d *Detector) Detect(text string) (lang string, ok bool) {
+	counts, letters := trigrams(text, maxRunes)
+	if letters < minLetters || len(d.profiles) == 0 {
+		return "", false
+	}
+
+	n := 0
+	scores := make([]float64, len(d.profiles))
+	for t, c := range counts {
+		n += c
+		for i, p := range d.profiles {
+			lp, seen := p.logProb[t]
+			if !seen {
+				lp = p.unseen
+			}
+			scores[i] += float64(c) * lp
+		}
+	}
+
+	best, second := 0, -1
+	for i := 1; i < len(scores); i++ {
+		if scores[i] > scores[best] {
+			best, second = i, best
+		} else if second < 0 || scores[i] > scores[second] {
+			second = i
+		}
+	}
+	if second >= 0 && (scores[best]-scores[second])/float64(n) < minMargin {
+		return d.profiles[best].lang, false
+	}
+	return d.profiles[best].lang, true
+}
+
+// trigrams counts letter trigrams of space-padded lowercase words in the
+// first limit runes of text (all of it when limit < 0) and the letters seen
+func trigrams(text string, limit int) (map[string]int, int) {
+	counts := make(map[string]int)
+	letters := 0
+	word := make([]rune, 0, 32)
+	flush := func() {
+		if len(word) == 0 {
+			return
+		}
+		padded := append(append([]rune{' '}, word...), ' ')
+		for i := 0; i+3 <= len(padded); i++ {
+			counts[string(padded[i:i+3])]++
+		}
+		word = word[:0]
+	}
+
+	seen := 0
+	for _, r := range text {
+		if limit >= 0 && seen >= limit {
+			break
+		}
+		seen++
+		if unicode.IsLetter(r) {
+			word = append(word, unicode.ToLower(r))
+			letters++
+			continue
+		}
+		flush()
+	}
+	flush()
+	return counts, letters
+}
@@ -0,0 +1,40 @@
+package langdetect
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestDetect(t *testing.T) {
+	d := New()
+	tests := []struct {
+		name string
+		text string
+		lang string // checked when ok
+		ok   bool
+	}{
+		{"russian", "Заявление на отпуск подают руководителю за две недели до его начала.", "ru", true},
+		{"english", "Open the rear tray of the printer and remove the jammed paper carefully.", "en", true},
+		{"russian with an english term", "Перезагрузите router и дождитесь, пока индикаторы перестанут мигать.", "ru", true},
+		{"english with a number", "Error 4012 means that the paper tray is empty, refill it.", "en", true},
+		{"short russian", "Привет, мир", "", false},
+		{"short english", "Hello world", "", false},
+		{"empty", "", "", false},
+		{"digits and punctuation", "4012 - 17:45, 2024-01-01 (№ 5) ... 12345678901234567890", "", false},
+		{"even mix", "Отпуск подают заранее. The tray is empty now.", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lang, ok := d.Detect(tt.text)
+			if ok != tt.ok || (ok && lang != tt.lang) {
+				t.Errorf("Detect(%q) = %q, %v; want %q, %v", tt.text, lang, ok, tt.lang, tt.ok)
+			}
+		})
+	}
+}
+
+func TestLanguages(t *testing.T) {
+	if got := New().Languages(); !slices.Equal(got, []string{"en", "ru"}) {
+		t.Errorf("Languages() = %v, want [en ru]", got)
+	}
+}
@@ -5,17 +5,20 @@ import (
 	"strings"
 
 	"test-ragger/internal/models"
+	"test-ragger/internal/utils"
 )
 
 func Build(userQ string, hits []models.Hit) string {
 	var ctxParts []string
 	for i, h := range hits {
+		// passages widened with neighbour chunks get room for each chunk
 		const maxFrag = 800
-		txt := h.Text
-		if len(txt) > maxFrag {
-			txt = txt[:maxFrag] + "…"
+		txt := utils.Snippet(h.Text, maxFrag*max(len(h.ChunkIDs), 1))
+		chunks := h.ChunkID
+		if len(h.ChunkIDs) > 1 {
+			chunks = h.ChunkIDs[0] + ".." + h.ChunkIDs[len(h.ChunkIDs)-1]
 		}
-		ctxParts = append(ctxParts, fmt.Sprintf("[%d] %s (%s/%s)\n%s", i+1, h.Title, h.DocID, h.ChunkID, txt))
+		ctxParts = append(ctxParts, fmt.Sprintf("[%d] %s (%s/%s)\n%s", i+1, h.Title, h.DocID, chunks, txt))
 	}
 	ctx := strings.Join(ctxParts, "\n\n---\n\n")
 	return fmt.Sprintf(`Ты — технический ассистент. Отвечай только по контексту ниже, ссылайся на [номера].
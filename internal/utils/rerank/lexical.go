@@ -0,0 +1,65 @@
+package rerank
+
+import (
+	"context"
+
+	"test-ragger/internal/models"
+	"test-ragger/internal/utils/analyzer"
+)
+
+// Lexical is an offline stand-in for a cross-encoder: the share of
+// distinct query terms found in the title and text, with a bonus for query
+// terms appearing as an adjacent pair. Deterministic, for tests and demos.
+type Lexical struct{}
+
+// NewLexical creates a lexical reranker
+func NewLexical() *Lexical {
+	return &Lexical{}
+}
+
+func (l *Lexical) Rerank(ctx context.Context, query string, hits []models.Hit) ([]float32, error) {
+	scores := make([]float32, len(hits))
+	for i, h := range hits {
+		// the query is analyzed like the passage: by its language
+		a := analyzer.For(h.Lang)
+		queryTerms := a.Terms(query)
+		distinct := make(map[string]bool, len(queryTerms))
+		for _, t := range queryTerms {
+			distinct[t] = true
+		}
+		if len(distinct) == 0 {
+			continue
+		}
+
+		terms := a.Terms(h.Title + " " + h.Text)
+		present := make(map[string]bool, len(terms))
+		pairs := make(map[[2]string]bool, len(terms))
+		for j, t := range terms {
+			present[t] = true
+			if j > 0 {
+				pairs[[2]string{terms[j-1], t}] = true
+			}
+		}
+
+		found := 0
+		for t := range distinct {
+			if present[t] {
+				found++
+			}
+		}
+		adjacent, possible := 0, 0
+		for j := 1; j < len(queryTerms); j++ {
+			possible++
+			if pairs[[2]string{queryTerms[j-1], queryTerms[j]}] {
+				adjacent++
+			}
+		}
+
+		score := float32(found) / float32(len(distinct))
+		if possible > 0 {
+			score = 0.8*score + 0.2*float32(adjacent)/float32(possible)
+		}
+		scores[i] = score
+	}
+	return scores, nil
+}
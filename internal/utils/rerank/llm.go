@@ -0,0 +1,106 @@
+package rerank
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	openai "github.com/sashabaranov/go-openai"
+
+	"test-ragger/internal/models"
+	"test-ragger/internal/utils"
+	"test-ragger/internal/utils/resilient"
+)
+
+// maxJudgeScore is the top of the scale the LLM rates passages on
+const maxJudgeScore = 10
+
+const judgeSystemPrompt = `You are a search relevance judge. Rate how well each passage answers the query on a scale from 0 (irrelevant) to 10 (answers it fully). Passages may be in Russian or English. Reply with JSON only: {"scores": [s1, s2, ...]} with exactly one number per passage, in order.`
+
+// ChatClient represents a chat-completions-compatible API client
+type ChatClient interface {
+	CreateChatCompletion(ctx context.Context, req openai.ChatCompletionRequest) (openai.ChatCompletionResponse, error)
+}
+
+// LLM reranks passages with a chat model acting as a relevance judge.
+// Passages are rated in batches; scores are scaled to [0, 1].
+type LLM struct {
+	client    ChatClient
+	model     string
+	policy    *resilient.Policy
+	batchSize int
+	maxChars  int
+}
+
+// NewLLM creates an LLM reranker. Passages longer than maxChars are cut.
+func NewLLM(client ChatClient, model string, policy *resilient.Policy, batchSize, maxChars int) *LLM {
+	return &LLM{client: client, model: model, policy: policy, batchSize: max(batchSize, 1), maxChars: maxChars}
+}
+
+func (l *LLM) Rerank(ctx context.Context, query string, hits []models.Hit) ([]float32, error) {
+	scores := make([]float32, 0, len(hits))
+	for start := 0; start < len(hits); start += l.batchSize {
+		batch := hits[start:min(start+l.batchSize, len(hits))]
+		s, err := l.judge(ctx, query, batch)
+		if err != nil {
+			return nil, err
+		}
+		scores = append(scores, s...)
+	}
+	return scores, nil
+}
+
+// judge rates one batch of passages
+func (l *LLM) judge(ctx context.Context, query string, hits []models.Hit) ([]float32, error) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Query: %s\n\nPassages:\n", query)
+	for i, h := range hits {
+		fmt.Fprintf(&b, "[%d] %s\n%s\n\n", i+1, h.Title, utils.Snippet(h.Text, l.maxChars))
+	}
+	req := openai.ChatCompletionRequest{
+		Model:       l.model,
+		Temperature: 0,
+		Messages: []openai.ChatCompletionMessage{
+			{Role: openai.ChatMessageRoleSystem, Content: judgeSystemPrompt},
+			{Role: openai.ChatMessageRoleUser, Content: b.String()},
+		},
+	}
+
+	var resp openai.ChatCompletionResponse
+	err := l.policy.Do(ctx, utils.EstimateTokens(b.String()), func(ctx context.Context) error {
+		var err error
+		resp, err = l.client.CreateChatCompletion(ctx, req)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("rerank: %w", err)
+	}
+	if len(resp.Choices) == 0 {
+		return nil, fmt.Errorf("rerank: empty response")
+	}
+	return parseScores(resp.Choices[0].Message.Content, len(hits))
+}
+
+// parseScores extracts {"scores": [...]} from a model reply, tolerating
+// surrounding prose and code fences
+func parseScores(reply string, n int) ([]float32, error) {
+	start, end := strings.Index(reply, "{"), strings.LastIndex(reply, "}")
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("rerank: no JSON in reply %q", utils.Snippet(reply, 200))
+	}
+	var out struct {
+		Scores []float64 `json:"scores"`
+	}
+	if err := json.Unmarshal([]byte(reply[start:end+1]), &out); err != nil {
+		return nil, fmt.Errorf("rerank: parse reply: %w", err)
+	}
+	if len(out.Scores) != n {
+		return nil, fmt.Errorf("rerank: got %d scores for %d passages", len(out.Scores), n)
+	}
+	scores := make([]float32, n)
+	for i, s := range out.Scores {
+		scores[i] = float32(min(max(s, 0), maxJudgeScore) / maxJudgeScore)
+	}
+	return scores, nil
+}
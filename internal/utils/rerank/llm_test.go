@@ -0,0 +1,107 @@
+package rerank
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+
+	openai "github.com/sashabaranov/go-openai"
+
+	"test-ragger/internal/models"
+	"test-ragger/internal/utils/resilient"
+)
+
+func TestParseScores(t *testing.T) {
+	tests := []struct {
+		name    string
+		reply   string
+		n       int
+		want    []float32
+		wantErr string
+	}{
+		{"plain", `{"scores": [10, 5, 0]}`, 3, []float32{1, 0.5, 0}, ""},
+		{"code fence", "```json\n{\"scores\": [2.5]}\n```", 1, []float32{0.25}, ""},
+		{"prose around", `Sure! Here you go: {"scores": [7, 3]} Hope this helps.`, 2, []float32{0.7, 0.3}, ""},
+		{"clamped", `{"scores": [-4, 15]}`, 2, []float32{0, 1}, ""},
+		{"short reply", `{"scores": [7, 3]}`, 3, nil, "got 2 scores for 3 passages"},
+		{"long reply", `{"scores": [7, 3, 1, 1]}`, 3, nil, "got 4 scores for 3 passages"},
+		{"missing key", `{"ratings": [7]}`, 1, nil, "got 0 scores for 1 passages"},
+		{"no json", "I cannot rate these passages.", 1, nil, "no JSON in reply"},
+		{"truncated", `{"scores": [7, 3`, 2, nil, "no JSON in reply"},
+		{"malformed", `{"scores": [7, "high"]}`, 2, nil, "parse reply"},
+		{"two objects", `{"scores": [1]} and {"scores": [2]}`, 1, nil, "parse reply"},
+		{"empty", "", 1, nil, "no JSON in reply"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseScores(tt.reply, tt.n)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("scores = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// passageScore reads the rating a fake judge gives a passage from its title
+var passageScore = regexp.MustCompile(`(?m)^\[\d+\] score=(\d+)$`)
+
+// fakeJudge rates every passage of a prompt by the score in its title;
+// reply overrides the answer when set
+type fakeJudge struct {
+	batches []int
+	reply   string
+}
+
+func (j *fakeJudge) CreateChatCompletion(ctx context.Context, req openai.ChatCompletionRequest) (openai.ChatCompletionResponse, error) {
+	var scores []string
+	for _, m := range passageScore.FindAllStringSubmatch(req.Messages[1].Content, -1) {
+		scores = append(scores, m[1])
+	}
+	j.batches = append(j.batches, len(scores))
+	reply := j.reply
+	if reply == "" {
+		reply = `{"scores": [` + strings.Join(scores, ", ") + `]}`
+	}
+	return openai.ChatCompletionResponse{Choices: []openai.ChatCompletionChoice{{Message: openai.ChatCompletionMessage{Content: reply}}}}, nil
+}
+
+func TestLLMRerankBatches(t *testing.T) {
+	hits := make([]models.Hit, 7)
+	for i := range hits {
+		hits[i] = models.Hit{Title: fmt.Sprintf("score=%d", i), Text: "passage"}
+	}
+	judge := &fakeJudge{}
+	l := NewLLM(judge, "judge", resilient.NewPolicy(resilient.Options{MaxAttempts: 1}), 3, 100)
+
+	scores, err := l.Rerank(context.Background(), "q", hits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(judge.batches) != "[3 3 1]" {
+		t.Errorf("batches = %v, want [3 3 1]", judge.batches)
+	}
+	for i, s := range scores {
+		if want := float32(i) / maxJudgeScore; s != want {
+			t.Errorf("score %d = %v, want %v", i, s, want)
+		}
+	}
+}
+
+func TestLLMRerankShortReply(t *testing.T) {
+	hits := []models.Hit{{Title: "score=1"}, {Title: "score=2"}}
+	l := NewLLM(&fakeJudge{reply: `{"scores": [9]}`}, "judge", resilient.NewPolicy(resilient.Options{MaxAttempts: 1}), 10, 100)
+	if _, err := l.Rerank(context.Background(), "q", hits); err == nil {
+		t.Fatal("a reply with fewer scores than passages must fail")
+	}
+}
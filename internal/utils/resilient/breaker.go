@@ -0,0 +1,98 @@
+package resilient
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned while the breaker rejects calls
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+type breakerState int
+
+const (
+	stateClosed breakerState = iota
+	stateOpen
+	stateHalfOpen
+)
+
+// Breaker opens after a number of consecutive failures and rejects calls
+// until the cooldown passes; then a single trial call decides whether
+// to close it again.
+type Breaker struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+
+	state    breakerState
+	failures int
+	openedAt time.Time
+	trial    bool
+}
+
+// NewBreaker creates a breaker; threshold <= 0 disables it
+func NewBreaker(threshold int, cooldown time.Duration) *Breaker {
+	return &Breaker{threshold: threshold, cooldown: cooldown}
+}
+
+// Allow reports whether a call may proceed. A rejected caller gets
+// ErrCircuitOpen and how long to wait before asking again.
+func (b *Breaker) Allow() (time.Duration, error) {
+	if b.threshold <= 0 {
+		return 0, nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case stateOpen:
+		if left := b.cooldown - time.Since(b.openedAt); left > 0 {
+			return left, ErrCircuitOpen
+		}
+		b.state = stateHalfOpen
+		b.trial = true
+		return 0, nil
+	case stateHalfOpen:
+		if b.trial {
+			// the trial call decides; ask again after another cooldown
+			return b.cooldown, ErrCircuitOpen
+		}
+		b.trial = true
+	}
+	return 0, nil
+}
+
+// Success records a successful call and closes the breaker
+func (b *Breaker) Success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.state = stateClosed
+	b.failures = 0
+	b.trial = false
+}
+
+// Failure records a transient failure; reaching the threshold
+// or failing the half-open trial opens the breaker
+func (b *Breaker) Failure() {
+	if b.threshold <= 0 {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures++
+	if b.state == stateHalfOpen || b.failures >= b.threshold {
+		b.state = stateOpen
+		b.openedAt = time.Now()
+		b.trial = false
+	}
+}
+
+// Release gives up a half-open trial without judging the outcome
+func (b *Breaker) Release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == stateHalfOpen {
+		b.trial = false
+	}
+}
@@ -0,0 +1,80 @@
+package resilient
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestBreakerTransitions(t *testing.T) {
+	const cooldown = 30 * time.Millisecond
+	b := NewBreaker(2, cooldown)
+
+	b.Failure()
+	if _, err := b.Allow(); err != nil {
+		t.Fatalf("below threshold: %v", err)
+	}
+	b.Failure()
+	wait, err := b.Allow()
+	if !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("at threshold: err = %v, want ErrCircuitOpen", err)
+	}
+	if wait <= 0 || wait > cooldown {
+		t.Fatalf("wait = %v, want the rest of the %v cooldown", wait, cooldown)
+	}
+
+	// after the cooldown exactly one trial call is let through
+	time.Sleep(cooldown)
+	if _, err := b.Allow(); err != nil {
+		t.Fatalf("half-open trial: %v", err)
+	}
+	if _, err := b.Allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("second call during the trial: err = %v, want ErrCircuitOpen", err)
+	}
+
+	// a failed trial opens the breaker again for a full cooldown
+	b.Failure()
+	if _, err := b.Allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("after a failed trial: err = %v, want ErrCircuitOpen", err)
+	}
+
+	time.Sleep(cooldown)
+	if _, err := b.Allow(); err != nil {
+		t.Fatalf("second trial: %v", err)
+	}
+	b.Success()
+	for i := range 3 {
+		if _, err := b.Allow(); err != nil {
+			t.Fatalf("closed breaker, call %d: %v", i, err)
+		}
+	}
+
+	// failures are counted afresh after closing
+	b.Failure()
+	if _, err := b.Allow(); err != nil {
+		t.Fatalf("one failure after closing: %v", err)
+	}
+}
+
+func TestBreakerReleasedTrial(t *testing.T) {
+	b := NewBreaker(1, 0)
+	b.Failure()
+	if _, err := b.Allow(); err != nil {
+		t.Fatalf("half-open trial: %v", err)
+	}
+	// a cancelled trial hands the slot to the next caller
+	b.Release()
+	if _, err := b.Allow(); err != nil {
+		t.Fatalf("trial after release: %v", err)
+	}
+}
+
+func TestBreakerDisabled(t *testing.T) {
+	b := NewBreaker(0, time.Hour)
+	for range 10 {
+		b.Failure()
+	}
+	if _, err := b.Allow(); err != nil {
+		t.Fatalf("disabled breaker: %v", err)
+	}
+}
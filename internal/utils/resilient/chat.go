@@ -0,0 +1,56 @@
+package resilient
+
+import (
+	"context"
+
+	openai "github.com/sashabaranov/go-openai"
+
+	"test-ragger/internal/utils"
+)
+
+// ChatClient is the chat completions API implemented by both wrapped and wrapping clients
+type ChatClient interface {
+	CreateChatCompletion(ctx context.Context, req openai.ChatCompletionRequest) (openai.ChatCompletionResponse, error)
+	CreateChatCompletionStream(ctx context.Context, req openai.ChatCompletionRequest) (*openai.ChatCompletionStream, error)
+}
+
+// Chat wraps a ChatClient with a Policy. Streams are retried only while
+// they are being opened; a broken stream is returned to the caller.
+type Chat struct {
+	next   ChatClient
+	policy *Policy
+}
+
+// NewChat wraps next with retries, rate limiting and a circuit breaker
+func NewChat(next ChatClient, policy *Policy) *Chat {
+	return &Chat{next: next, policy: policy}
+}
+
+func (c *Chat) CreateChatCompletion(ctx context.Context, req openai.ChatCompletionRequest) (openai.ChatCompletionResponse, error) {
+	var res openai.ChatCompletionResponse
+	err := c.policy.Do(ctx, chatTokens(req), func(ctx context.Context) error {
+		var err error
+		res, err = c.next.CreateChatCompletion(ctx, req)
+		return err
+	})
+	return res, err
+}
+
+func (c *Chat) CreateChatCompletionStream(ctx context.Context, req openai.ChatCompletionRequest) (*openai.ChatCompletionStream, error) {
+	var stream *openai.ChatCompletionStream
+	err := c.policy.Do(ctx, chatTokens(req), func(ctx context.Context) error {
+		var err error
+		stream, err = c.next.CreateChatCompletionStream(ctx, req)
+		return err
+	})
+	return stream, err
+}
+
+// chatTokens estimates the prompt size of a chat request
+func chatTokens(req openai.ChatCompletionRequest) int {
+	n := 0
+	for _, m := range req.Messages {
+		n += utils.EstimateTokens(m.Content)
+	}
+	return n
+}
@@ -0,0 +1,78 @@
+package resilient
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Limiter is a client-side requests-per-minute and tokens-per-minute limiter.
+// Both budgets are token buckets refilled continuously; zero disables a budget.
+type Limiter struct {
+	mu       sync.Mutex
+	requests *bucket
+	tokens   *bucket
+}
+
+type bucket struct {
+	capacity float64
+	avail    float64
+	perSec   float64
+	last     time.Time
+}
+
+func newBucket(perMinute int, now time.Time) *bucket {
+	if perMinute <= 0 {
+		return nil
+	}
+	c := float64(perMinute)
+	return &bucket{capacity: c, avail: c, perSec: c / 60, last: now}
+}
+
+func (b *bucket) refill(now time.Time) {
+	b.avail = min(b.capacity, b.avail+now.Sub(b.last).Seconds()*b.perSec)
+	b.last = now
+}
+
+// reserve takes n units and returns how long the caller must wait
+// until the bucket is no longer in debt
+func (b *bucket) reserve(n float64, now time.Time) time.Duration {
+	if b == nil {
+		return 0
+	}
+	b.refill(now)
+	b.avail -= min(n, b.capacity)
+	if b.avail >= 0 {
+		return 0
+	}
+	return time.Duration(-b.avail / b.perSec * float64(time.Second))
+}
+
+// NewLimiter creates a limiter for the given per-minute budgets
+func NewLimiter(requestsPerMinute, tokensPerMinute int) *Limiter {
+	now := time.Now()
+	return &Limiter{
+		requests: newBucket(requestsPerMinute, now),
+		tokens:   newBucket(tokensPerMinute, now),
+	}
+}
+
+// Wait blocks until one request carrying the given number of tokens is allowed
+func (l *Limiter) Wait(ctx context.Context, tokens int) error {
+	l.mu.Lock()
+	now := time.Now()
+	wait := max(l.requests.reserve(1, now), l.tokens.reserve(float64(tokens), now))
+	l.mu.Unlock()
+
+	if wait <= 0 {
+		return nil
+	}
+	t := time.NewTimer(wait)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,74 @@
+package resilient
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestBucketReserve(t *testing.T) {
+	now := time.Now()
+	b := newBucket(60, now) // one unit per second, 60 in stock
+
+	if wait := b.reserve(60, now); wait != 0 {
+		t.Fatalf("full bucket: wait = %v, want 0", wait)
+	}
+	if wait := b.reserve(2, now); wait != 2*time.Second {
+		t.Fatalf("empty bucket: wait = %v, want 2s", wait)
+	}
+	// the debt is paid off by the refill
+	if wait := b.reserve(1, now.Add(3*time.Second)); wait != 0 {
+		t.Fatalf("after refill: wait = %v, want 0", wait)
+	}
+	// the refill never exceeds the capacity
+	later := now.Add(time.Hour)
+	b.reserve(0, later)
+	if b.avail != b.capacity {
+		t.Fatalf("avail = %v after an hour, want capacity %v", b.avail, b.capacity)
+	}
+	// a request larger than the capacity waits for a full bucket, not forever
+	if wait := b.reserve(1000, later); wait != 0 {
+		t.Fatalf("oversized request on a full bucket: wait = %v, want 0", wait)
+	}
+}
+
+func TestLimiterDisabled(t *testing.T) {
+	l := NewLimiter(0, 0)
+	for range 100 {
+		if err := l.Wait(context.Background(), 1_000_000); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestLimiterWaits(t *testing.T) {
+	l := NewLimiter(600, 0) // 10 requests per second
+	ctx := context.Background()
+	for range 600 {
+		if err := l.Wait(ctx, 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	start := time.Now()
+	if err := l.Wait(ctx, 0); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("exhausted budget: waited %v, want about 100ms", elapsed)
+	}
+}
+
+func TestLimiterTokensCancelled(t *testing.T) {
+	l := NewLimiter(0, 60) // one token per second
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	if err := l.Wait(ctx, 60); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Wait(ctx, 30); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v, want DeadlineExceeded", err)
+	}
+}
@@ -0,0 +1,230 @@
+package resilient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"time"
+
+	openai "github.com/sashabaranov/go-openai"
+
+	"test-ragger/internal/utils"
+)
+
+// Options configures retries, client-side rate limiting and the circuit breaker
+type Options struct {
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	// MaxRetryAfter is the longest Retry-After delay honoured; a server
+	// asking for more fails the call. 0 honours any delay.
+	MaxRetryAfter time.Duration
+
+	RequestsPerMinute int
+	TokensPerMinute   int
+
+	BreakerThreshold int
+	BreakerCooldown  time.Duration
+}
+
+// Policy executes calls with rate limiting, retries and a circuit breaker
+type Policy struct {
+	opts    Options
+	limiter *Limiter
+	breaker *Breaker
+}
+
+// NewPolicy creates a policy from options
+func NewPolicy(opts Options) *Policy {
+	return &Policy{
+		opts:    opts,
+		limiter: NewLimiter(opts.RequestsPerMinute, opts.TokensPerMinute),
+		breaker: NewBreaker(opts.BreakerThreshold, opts.BreakerCooldown),
+	}
+}
+
+// Do runs fn until it succeeds, fails permanently or attempts are exhausted.
+// tokens is the estimated size of the call for the tokens-per-minute budget.
+// Only outages (5xx and network errors) count toward the circuit breaker;
+// while it is open, remaining attempts wait for its cooldown.
+func (p *Policy) Do(ctx context.Context, tokens int, fn func(ctx context.Context) error) error {
+	attempts := max(p.opts.MaxAttempts, 1)
+
+	var err error
+	for attempt := 0; attempt < attempts; attempt++ {
+		if wait, open := p.breaker.Allow(); open != nil {
+			if attempt == attempts-1 {
+				return fmt.Errorf("giving up after %d attempts: %w", attempts, errors.Join(open, err))
+			}
+			slog.Warn("Circuit breaker is open, waiting", "attempt", attempt+1, "max_attempts", attempts, "wait", wait)
+			if err := sleep(ctx, wait); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := p.limiter.Wait(ctx, tokens); err != nil {
+			p.breaker.Release()
+			return err
+		}
+
+		callCtx, hint := withRetryHint(ctx)
+		err = fn(callCtx)
+		if err == nil {
+			p.breaker.Success()
+			return nil
+		}
+		if !IsRetryable(err) {
+			// the provider answered, so it is healthy; a cancelled call proves nothing
+			if ctx.Err() == nil {
+				p.breaker.Success()
+			} else {
+				p.breaker.Release()
+			}
+			return err
+		}
+
+		after := hint.get()
+		if isOutage(err) && after == 0 {
+			p.breaker.Failure()
+		} else {
+			// rate limiting and servers naming a retry time are not outages
+			p.breaker.Release()
+		}
+		if attempt == attempts-1 {
+			break
+		}
+
+		wait := p.backoff(attempt)
+		if after > 0 {
+			if p.opts.MaxRetryAfter > 0 && after > p.opts.MaxRetryAfter {
+				return fmt.Errorf("server asked to retry after %v, longer than the allowed %v: %w", after, p.opts.MaxRetryAfter, err)
+			}
+			wait = after
+		}
+		slog.Warn("Transient API error, retrying", "attempt", attempt+1, "max_attempts", attempts, "wait", wait, "error", err)
+		if err := sleep(ctx, wait); err != nil {
+			return err
+		}
+	}
+	return fmt.Errorf("giving up after %d attempts: %w", attempts, err)
+}
+
+// sleep waits for d or until ctx is done
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// backoff returns exponential backoff with full jitter
+func (p *Policy) backoff(attempt int) time.Duration {
+	ceil := p.opts.InitialBackoff << attempt
+	if ceil <= 0 || (p.opts.MaxBackoff > 0 && ceil > p.opts.MaxBackoff) {
+		ceil = p.opts.MaxBackoff
+	}
+	if ceil <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int64N(int64(ceil)) + 1)
+}
+
+// IsRetryable reports whether err is transient: rate limiting,
+// server-side failures or network errors
+func IsRetryable(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var apiErr *openai.APIError
+	if errors.As(err, &apiErr) {
+		if apiErr.Code == "insufficient_quota" {
+			return false
+		}
+		return retryableStatus(apiErr.HTTPStatusCode)
+	}
+	var reqErr *openai.RequestError
+	if errors.As(err, &reqErr) {
+		return retryableStatus(reqErr.HTTPStatusCode)
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// isOutage reports whether a retryable err means the provider is failing:
+// a server error or a network error, as opposed to rate limiting
+func isOutage(err error) bool {
+	var apiErr *openai.APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.HTTPStatusCode >= 500
+	}
+	var reqErr *openai.RequestError
+	if errors.As(err, &reqErr) {
+		return reqErr.HTTPStatusCode >= 500
+	}
+	return true
+}
+
+func retryableStatus(code int) bool {
+	switch code {
+	case http.StatusRequestTimeout, http.StatusTooManyRequests:
+		return true
+	}
+	return code >= 500
+}
+
+// EmbeddingClient is the embeddings API implemented by both wrapped and wrapping clients
+type EmbeddingClient interface {
+	CreateEmbeddings(ctx context.Context, req openai.EmbeddingRequestConverter) (openai.EmbeddingResponse, error)
+}
+
+// Embedder wraps an EmbeddingClient with a Policy
+type Embedder struct {
+	next   EmbeddingClient
+	policy *Policy
+}
+
+// NewEmbedder wraps next with retries, rate limiting and a circuit breaker
+func NewEmbedder(next EmbeddingClient, policy *Policy) *Embedder {
+	return &Embedder{next: next, policy: policy}
+}
+
+func (e *Embedder) CreateEmbeddings(ctx context.Context, req openai.EmbeddingRequestConverter) (openai.EmbeddingResponse, error) {
+	var res openai.EmbeddingResponse
+	err := e.policy.Do(ctx, requestTokens(req), func(ctx context.Context) error {
+		var err error
+		res, err = e.next.CreateEmbeddings(ctx, req)
+		return err
+	})
+	return res, err
+}
+
+// requestTokens estimates the token size of an embeddings request
+func requestTokens(req openai.EmbeddingRequestConverter) int {
+	var inputs []string
+	switch r := req.(type) {
+	case openai.EmbeddingRequest:
+		switch in := r.Input.(type) {
+		case string:
+			inputs = []string{in}
+		case []string:
+			inputs = in
+		}
+	case openai.EmbeddingRequestStrings:
+		inputs = r.Input
+	}
+
+	n := 0
+	for _, s := range inputs {
+		n += utils.EstimateTokens(s)
+	}
+	return n
+}
@@ -0,0 +1,206 @@
+package resilient
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// fakeEmbeddings serves /v1/embeddings, answering the first failures
+// calls with status and headers and the rest with a one-vector response
+func fakeEmbeddings(t *testing.T, failures int32, status int, headers map[string]string) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/embeddings" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if calls.Add(1) <= failures {
+			for k, v := range headers {
+				w.Header().Set(k, v)
+			}
+			w.WriteHeader(status)
+			w.Write([]byte(`{"error":{"message":"slow down","type":"requests","code":"rate_limit_exceeded"}}`))
+			return
+		}
+		w.Write([]byte(`{"object":"list","model":"m","data":[{"object":"embedding","index":0,"embedding":[0.5,0.5]}]}`))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &calls
+}
+
+func newTestEmbedder(srv *httptest.Server, opts Options) *Embedder {
+	cfg := openai.DefaultConfig("test")
+	cfg.BaseURL = srv.URL + "/v1"
+	cfg.HTTPClient = NewHTTPClient()
+	return NewEmbedder(openai.NewClientWithConfig(cfg), NewPolicy(opts))
+}
+
+func embed(ctx context.Context, e *Embedder) error {
+	_, err := e.CreateEmbeddings(ctx, openai.EmbeddingRequest{Model: "m", Input: []string{"hello"}})
+	return err
+}
+
+func TestEmbedderHonoursRetryAfter(t *testing.T) {
+	srv, calls := fakeEmbeddings(t, 2, http.StatusTooManyRequests, map[string]string{"Retry-After-Ms": "60"})
+	e := newTestEmbedder(srv, Options{MaxAttempts: 3, InitialBackoff: time.Millisecond, MaxBackoff: time.Second})
+
+	start := time.Now()
+	if err := embed(context.Background(), e); err != nil {
+		t.Fatal(err)
+	}
+	if got := calls.Load(); got != 3 {
+		t.Errorf("calls = %d, want 3", got)
+	}
+	// the 1ms backoff is replaced by the server's 60ms, twice
+	if elapsed := time.Since(start); elapsed < 120*time.Millisecond {
+		t.Errorf("retried after %v, want at least 120ms", elapsed)
+	}
+}
+
+func TestEmbedderHonoursRetryAfterBeyondMaxBackoff(t *testing.T) {
+	srv, calls := fakeEmbeddings(t, 1, http.StatusTooManyRequests, map[string]string{"Retry-After-Ms": "80"})
+	e := newTestEmbedder(srv, Options{MaxAttempts: 2, InitialBackoff: time.Millisecond, MaxBackoff: 10 * time.Millisecond, MaxRetryAfter: time.Second})
+
+	start := time.Now()
+	if err := embed(context.Background(), e); err != nil {
+		t.Fatal(err)
+	}
+	if got := calls.Load(); got != 2 {
+		t.Errorf("calls = %d, want 2", got)
+	}
+	if elapsed := time.Since(start); elapsed < 80*time.Millisecond {
+		t.Errorf("retried after %v, want the full 80ms Retry-After", elapsed)
+	}
+}
+
+func TestEmbedderRejectsLongRetryAfter(t *testing.T) {
+	srv, calls := fakeEmbeddings(t, 1, http.StatusServiceUnavailable, map[string]string{"Retry-After": "3600"})
+	e := newTestEmbedder(srv, Options{MaxAttempts: 3, InitialBackoff: time.Millisecond, MaxRetryAfter: time.Minute})
+
+	start := time.Now()
+	err := embed(context.Background(), e)
+	var apiErr *openai.APIError
+	if !errors.As(err, &apiErr) || !strings.Contains(err.Error(), "retry after 1h0m0s") {
+		t.Fatalf("err = %v, want the Retry-After refusal wrapping the API error", err)
+	}
+	if got := calls.Load(); got != 1 {
+		t.Errorf("calls = %d, want 1", got)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("took %v, want an immediate error", elapsed)
+	}
+}
+
+func TestEmbedderGivesUp(t *testing.T) {
+	srv, calls := fakeEmbeddings(t, 100, http.StatusInternalServerError, nil)
+	e := newTestEmbedder(srv, Options{MaxAttempts: 3, InitialBackoff: time.Millisecond, MaxBackoff: 2 * time.Millisecond})
+
+	err := embed(context.Background(), e)
+	var apiErr *openai.APIError
+	if !errors.As(err, &apiErr) || apiErr.HTTPStatusCode != http.StatusInternalServerError {
+		t.Fatalf("err = %v, want the last API error", err)
+	}
+	if got := calls.Load(); got != 3 {
+		t.Errorf("calls = %d, want 3", got)
+	}
+}
+
+func TestEmbedderDoesNotRetryPermanentErrors(t *testing.T) {
+	srv, calls := fakeEmbeddings(t, 100, http.StatusBadRequest, nil)
+	e := newTestEmbedder(srv, Options{MaxAttempts: 5, InitialBackoff: time.Millisecond})
+
+	if err := embed(context.Background(), e); err == nil {
+		t.Fatal("want an error")
+	}
+	if got := calls.Load(); got != 1 {
+		t.Errorf("calls = %d, want 1", got)
+	}
+}
+
+func TestEmbedderWaitsForOpenBreaker(t *testing.T) {
+	srv, calls := fakeEmbeddings(t, 100, http.StatusBadGateway, nil)
+	e := newTestEmbedder(srv, Options{MaxAttempts: 5, InitialBackoff: time.Millisecond, MaxBackoff: time.Millisecond, BreakerThreshold: 2, BreakerCooldown: 30 * time.Millisecond})
+
+	// two failures open the breaker, the third attempt waits for the
+	// cooldown, the fourth is the failing trial, the fifth finds it open
+	start := time.Now()
+	err := embed(context.Background(), e)
+	var apiErr *openai.APIError
+	if !errors.Is(err, ErrCircuitOpen) || !errors.As(err, &apiErr) || apiErr.HTTPStatusCode != http.StatusBadGateway {
+		t.Fatalf("err = %v, want ErrCircuitOpen joined with the last API error", err)
+	}
+	if got := calls.Load(); got != 3 {
+		t.Errorf("calls = %d, want 3", got)
+	}
+	if elapsed := time.Since(start); elapsed < 30*time.Millisecond {
+		t.Errorf("gave up after %v without waiting for the cooldown", elapsed)
+	}
+}
+
+func TestEmbedderBreakerRecovers(t *testing.T) {
+	srv, calls := fakeEmbeddings(t, 2, http.StatusInternalServerError, nil)
+	e := newTestEmbedder(srv, Options{MaxAttempts: 4, InitialBackoff: time.Millisecond, MaxBackoff: time.Millisecond, BreakerThreshold: 2, BreakerCooldown: 20 * time.Millisecond})
+
+	if err := embed(context.Background(), e); err != nil {
+		t.Fatalf("the trial after the cooldown should succeed: %v", err)
+	}
+	if got := calls.Load(); got != 3 {
+		t.Errorf("calls = %d, want 3", got)
+	}
+}
+
+func TestEmbedderRateLimitsDoNotOpenBreaker(t *testing.T) {
+	srv, calls := fakeEmbeddings(t, 4, http.StatusTooManyRequests, nil)
+	e := newTestEmbedder(srv, Options{MaxAttempts: 5, InitialBackoff: time.Millisecond, MaxBackoff: time.Millisecond, BreakerThreshold: 2, BreakerCooldown: time.Hour})
+
+	if err := embed(context.Background(), e); err != nil {
+		t.Fatalf("a 429 burst must not open the breaker: %v", err)
+	}
+	if got := calls.Load(); got != 5 {
+		t.Errorf("calls = %d, want 5", got)
+	}
+}
+
+func TestBackoffCaps(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    Options
+		attempt int
+		ceil    time.Duration
+	}{
+		{"first attempt", Options{InitialBackoff: 100 * time.Millisecond, MaxBackoff: time.Second}, 0, 100 * time.Millisecond},
+		{"doubles", Options{InitialBackoff: 100 * time.Millisecond, MaxBackoff: time.Second}, 2, 400 * time.Millisecond},
+		{"capped", Options{InitialBackoff: 100 * time.Millisecond, MaxBackoff: time.Second}, 5, time.Second},
+		{"overflow", Options{InitialBackoff: time.Second, MaxBackoff: time.Minute}, 62, time.Minute},
+		{"uncapped", Options{InitialBackoff: time.Millisecond}, 3, 8 * time.Millisecond},
+		{"disabled", Options{}, 3, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewPolicy(tt.opts)
+			for range 1000 {
+				d := p.backoff(tt.attempt)
+				if tt.ceil == 0 {
+					if d != 0 {
+						t.Fatalf("backoff = %v, want 0", d)
+					}
+					continue
+				}
+				// full jitter: anywhere in (0, ceil]
+				if d <= 0 || d > tt.ceil {
+					t.Fatalf("backoff = %v, want in (0, %v]", d, tt.ceil)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package resilient
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// retryHint carries the server-provided Retry-After delay from the HTTP
+// transport back to the retry loop through the request context
+type retryHint struct {
+	mu    sync.Mutex
+	after time.Duration
+}
+
+type hintKey struct{}
+
+func withRetryHint(ctx context.Context) (context.Context, *retryHint) {
+	h := &retryHint{}
+	return context.WithValue(ctx, hintKey{}, h), h
+}
+
+func (h *retryHint) get() time.Duration {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.after
+}
+
+// Transport records Retry-After headers of 429/503 responses
+// so that the retry loop can honour them
+type Transport struct {
+	Base http.RoundTripper
+}
+
+// NewHTTPClient returns an HTTP client with the Retry-After aware transport
+func NewHTTPClient() *http.Client {
+	return &http.Client{Transport: &Transport{Base: http.DefaultTransport}}
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	resp, err := base.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+	if h, ok := req.Context().Value(hintKey{}).(*retryHint); ok {
+		if d, ok := parseRetryAfter(resp.Header, time.Now()); ok {
+			h.mu.Lock()
+			h.after = d
+			h.mu.Unlock()
+		}
+	}
+	return resp, nil
+}
+
+// parseRetryAfter reads retry-after-ms (OpenAI) or the standard
+// Retry-After header given in seconds or as an HTTP date
+func parseRetryAfter(h http.Header, now time.Time) (time.Duration, bool) {
+	if v := h.Get("Retry-After-Ms"); v != "" {
+		if ms, err := strconv.ParseFloat(v, 64); err == nil && ms >= 0 {
+			return time.Duration(ms * float64(time.Millisecond)), true
+		}
+	}
+	v := h.Get("Retry-After")
+	if v == "" {
+		return 0, false
+	}
+	if s, err := strconv.ParseFloat(v, 64); err == nil && s >= 0 {
+		return time.Duration(s * float64(time.Second)), true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return max(t.Sub(now), 0), true
+	}
+	return 0, false
+}
@@ -0,0 +1,39 @@
+package resilient
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		headers map[string]string
+		want    time.Duration
+		ok      bool
+	}{
+		{"none", nil, 0, false},
+		{"seconds", map[string]string{"Retry-After": "2"}, 2 * time.Second, true},
+		{"fractional seconds", map[string]string{"Retry-After": "0.5"}, 500 * time.Millisecond, true},
+		{"milliseconds win", map[string]string{"Retry-After-Ms": "250", "Retry-After": "9"}, 250 * time.Millisecond, true},
+		{"bad milliseconds", map[string]string{"Retry-After-Ms": "soon", "Retry-After": "1"}, time.Second, true},
+		{"http date", map[string]string{"Retry-After": "Wed, 01 May 2024 12:00:30 GMT"}, 30 * time.Second, true},
+		{"past date", map[string]string{"Retry-After": "Wed, 01 May 2024 11:00:00 GMT"}, 0, true},
+		{"negative", map[string]string{"Retry-After": "-3"}, 0, false},
+		{"garbage", map[string]string{"Retry-After": "later"}, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := http.Header{}
+			for k, v := range tt.headers {
+				h.Set(k, v)
+			}
+			got, ok := parseRetryAfter(h, now)
+			if got != tt.want || ok != tt.ok {
+				t.Errorf("parseRetryAfter = %v, %v; want %v, %v", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
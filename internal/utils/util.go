@@ -2,39 +2,78 @@ package utils
 
 import (
 	"crypto/sha1"
+	"crypto/sha256"
 	"encoding/hex"
-	"log"
-	"os"
 	"strings"
 	"unicode/utf8"
 )
 
-func MustEnv(key string) string {
-	value := os.Getenv(key)
-	if value == "" {
-		log.Fatalf("env %s is required", key)
-	}
-	return value
-}
-
 func Sha1Hex(s string) string {
 	h := sha1.Sum([]byte(s))
 	return hex.EncodeToString(h[:])
 }
 
-func Sha1Hash(s string) uint32 {
-	h := sha1.Sum([]byte(s))
-	// Convert first 4 bytes to uint32
-	return uint32(h[0])<<24 | uint32(h[1])<<16 | uint32(h[2])<<8 | uint32(h[3])
+func Sha256Hex(b []byte) string {
+	h := sha256.Sum256(b)
+	return hex.EncodeToString(h[:])
 }
 
+// NamespaceURL is the RFC 4122 name space for URLs
+var NamespaceURL = [16]byte{0x6b, 0xa7, 0xb8, 0x11, 0x9d, 0xad, 0x11, 0xd1, 0x80, 0xb4, 0x00, 0xc0, 0x4f, 0xd4, 0x30, 0xc8}
+
+// pointNamespace scopes point UUIDs of this application
+var pointNamespace = UUIDv5(NamespaceURL, "https://github.com/mikhio/test-ragger/points")
+
+// UUIDv5 returns the name-based SHA-1 UUID (RFC 4122) of name within namespace
+func UUIDv5(namespace [16]byte, name string) [16]byte {
+	h := sha1.New()
+	h.Write(namespace[:])
+	h.Write([]byte(name))
+
+	var u [16]byte
+	copy(u[:], h.Sum(nil))
+	u[6] = (u[6] & 0x0f) | 0x50 // version 5
+	u[8] = (u[8] & 0x3f) | 0x80 // RFC 4122 variant
+	return u
+}
+
+// FormatUUID renders a UUID in the canonical 8-4-4-4-12 form
+func FormatUUID(u [16]byte) string {
+	var buf [36]byte
+	hex.Encode(buf[0:8], u[0:4])
+	buf[8] = '-'
+	hex.Encode(buf[9:13], u[4:6])
+	buf[13] = '-'
+	hex.Encode(buf[14:18], u[6:8])
+	buf[18] = '-'
+	hex.Encode(buf[19:23], u[8:10])
+	buf[23] = '-'
+	hex.Encode(buf[24:], u[10:])
+	return string(buf[:])
+}
+
+// PointUUID derives a deterministic, collision-resistant point ID for a chunk
+func PointUUID(docID, chunkID string) string {
+	return FormatUUID(UUIDv5(pointNamespace, docID+"/"+chunkID))
+}
+
+// Snippet cuts s to at most max bytes without splitting a UTF-8 rune
 func Snippet(s string, max int) string {
 	if len(s) <= max {
 		return s
 	}
+	for max > 0 && !utf8.RuneStart(s[max]) {
+		max--
+	}
 	return s[:max] + "…"
 }
 
+// EstimateTokens gives a conservative token count without a tokenizer:
+// ~4 bytes per token for Latin text and ~1.5 runes per token for Cyrillic
+func EstimateTokens(s string) int {
+	return len(s)/3 + 1
+}
+
 func BoolPtr(b bool) *bool { return &b }
 
 func Uint64Ptr(u uint64) *uint64 { return &u }
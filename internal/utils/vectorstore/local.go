@@ -0,0 +1,692 @@
+package vectorstore
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"sync"
+
+	"test-ragger/internal/models"
+	"test-ragger/internal/utils/filelock"
+)
+
+// localMagic starts every collection file
+const localMagic = "TRGVEC1\n"
+
+// headerSize is the length of the magic and the uint32 dimension
+const headerSize = len(localMagic) + 4
+
+// Record kinds of a collection file
+const (
+	opUpsert byte = 'U'
+	opDelete byte = 'D'
+)
+
+// collectionName keeps collection files inside the store directory
+var collectionName = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// Local is an embedded Store keeping every collection in memory and in a
+// single file under dir. Search is brute-force cosine similarity, which is
+// fast enough for tens of thousands of chunks. Mutations are appended to
+// the file as they happen; Close compacts files that carry stale records.
+//
+// Several processes may share a store: every file has a lock file next to
+// it, and each operation first catches up with records other processes
+// appended (or reloads a file another process compacted).
+type Local struct {
+	dir string
+
+	mu          sync.RWMutex
+	collections map[string]*localCollection
+}
+
+// localCollection mirrors a collection file. points only ever changes by
+// replaying records read from the file or just written to it, so it is the
+// replay of the file up to offset.
+type localCollection struct {
+	path   string
+	dim    int
+	points map[string]*localPoint
+	ids    []string // sorted IDs for Scroll; nil when stale
+
+	lock   *filelock.Lock // guards the file across processes
+	f      *os.File       // nil until the file is opened
+	offset int64          // length of the file replayed into points
+	stale  int            // overwritten or deleted records in the file
+}
+
+type localPoint struct {
+	payload models.Payload
+	vec     []float32
+	norm    float64
+}
+
+// OpenLocal opens a store in dir, creating the directory if needed.
+// Collections are loaded on first use.
+func OpenLocal(dir string) (*Local, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create store dir: %w", err)
+	}
+	return &Local{dir: dir, collections: make(map[string]*localCollection)}, nil
+}
+
+func (s *Local) CollectionInfo(ctx context.Context, collection string) (*models.CollectionInfo, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, err := s.collection(collection)
+	if err != nil || c == nil {
+		return nil, err
+	}
+	if err := c.read(); err != nil {
+		return nil, err
+	}
+	return &models.CollectionInfo{Name: collection, Dimension: c.dim, Distance: DistanceCosine, Points: uint64(len(c.points))}, nil
+}
+
+func (s *Local) CreateCollection(ctx context.Context, collection string, dim int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, err := s.collection(collection)
+	if err != nil {
+		return err
+	}
+	if c != nil {
+		return fmt.Errorf("collection %s already exists", collection)
+	}
+	if dim <= 0 {
+		return fmt.Errorf("invalid dimension %d", dim)
+	}
+
+	c, err = createCollection(s.path(collection), dim)
+	if err != nil {
+		return fmt.Errorf("create collection %s: %w", collection, err)
+	}
+	s.collections[collection] = c
+	return nil
+}
+
+func (s *Local) Upsert(ctx context.Context, collection string, points []models.Point) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, err := s.existing(collection)
+	if err != nil {
+		return err
+	}
+
+	for _, p := range points {
+		if len(p.Vector) != c.dim {
+			return fmt.Errorf("point %s: vector dimension %d, collection %s expects %d", p.ID, len(p.Vector), collection, c.dim)
+		}
+	}
+	return c.update(func(w io.Writer) error {
+		for _, p := range points {
+			if err := writeUpsert(w, p); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s *Local) Delete(ctx context.Context, collection string, filter models.Filter) error {
+	if filter.Empty() {
+		return ErrEmptyFilter
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, err := s.existing(collection)
+	if err != nil {
+		return err
+	}
+
+	return c.update(func(w io.Writer) error {
+		for id, p := range c.points {
+			if !matches(&filter, id, p.payload) {
+				continue
+			}
+			if err := writeDelete(w, id); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s *Local) Scroll(ctx context.Context, collection string, req models.ScrollRequest) (models.ScrollPage, error) {
+	// Scroll may rebuild the sorted ID index, so it takes the write lock
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, err := s.existing(collection)
+	if err != nil {
+		return models.ScrollPage{}, err
+	}
+	if err := c.read(); err != nil {
+		return models.ScrollPage{}, err
+	}
+
+	if c.ids == nil {
+		c.ids = make([]string, 0, len(c.points))
+		for id := range c.points {
+			c.ids = append(c.ids, id)
+		}
+		slices.Sort(c.ids)
+	}
+	start, _ := slices.BinarySearch(c.ids, req.Offset)
+	limit := req.Limit
+	if limit <= 0 {
+		limit = len(c.ids)
+	}
+
+	var page models.ScrollPage
+	for i := start; i < len(c.ids); i++ {
+		id := c.ids[i]
+		p := c.points[id]
+		if !matches(req.Filter, id, p.payload) {
+			continue
+		}
+		if len(page.Points) == limit {
+			page.NextOffset = id
+			break
+		}
+		page.Points = append(page.Points, p.point(id, req.PayloadFields, req.WithVectors))
+	}
+	return page, nil
+}
+
+func (s *Local) Search(ctx context.Context, collection string, req models.SearchRequest) ([]models.ScoredPoint, error) {
+	if err := s.load(collection); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	c, ok := s.collections[collection]
+	if !ok {
+		return nil, fmt.Errorf("collection %s is closed", collection)
+	}
+	if len(req.Vector) != c.dim {
+		return nil, fmt.Errorf("query vector dimension %d, collection %s expects %d", len(req.Vector), collection, c.dim)
+	}
+
+	qnorm := norm(req.Vector)
+	var hits []models.ScoredPoint
+	for id, p := range c.points {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if !matches(req.Filter, id, p.payload) {
+			continue
+		}
+		var score float32
+		if qnorm > 0 && p.norm > 0 {
+			score = float32(dot(req.Vector, p.vec) / (qnorm * p.norm))
+		}
+		hits = append(hits, models.ScoredPoint{Point: p.point(id, nil, req.WithVectors), Score: score})
+	}
+
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].ID < hits[j].ID
+	})
+	if req.Limit > 0 && len(hits) > req.Limit {
+		hits = hits[:req.Limit]
+	}
+	return hits, nil
+}
+
+// Close compacts files with stale records and releases the collections
+func (s *Local) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var errs []error
+	for name, c := range s.collections {
+		if err := c.close(); err != nil {
+			errs = append(errs, fmt.Errorf("close collection %s: %w", name, err))
+		}
+	}
+	s.collections = make(map[string]*localCollection)
+	return errors.Join(errs...)
+}
+
+func (s *Local) path(collection string) string {
+	return filepath.Join(s.dir, collection+".vec")
+}
+
+// load catches up with a collection file ahead of a read-only operation
+func (s *Local) load(collection string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, err := s.existing(collection)
+	if err != nil {
+		return err
+	}
+	return c.read()
+}
+
+// existing returns a collection or an error if it does not exist
+func (s *Local) existing(collection string) (*localCollection, error) {
+	c, err := s.collection(collection)
+	if err != nil {
+		return nil, err
+	}
+	if c == nil {
+		return nil, fmt.Errorf("collection %s doesn't exist", collection)
+	}
+	return c, nil
+}
+
+// collection returns a loaded collection, reading its file on first use.
+// It returns nil when there is no such collection.
+func (s *Local) collection(name string) (*localCollection, error) {
+	if c, ok := s.collections[name]; ok {
+		return c, nil
+	}
+	if !collectionName.MatchString(name) {
+		return nil, fmt.Errorf("invalid collection name %q", name)
+	}
+
+	path := s.path(name)
+	if _, err := os.Stat(path); errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	lock, err := filelock.Open(path + ".lock")
+	if err != nil {
+		return nil, err
+	}
+	c := &localCollection{path: path, lock: lock}
+	if err := c.exclusive(func() error { return c.refresh(true) }); err != nil {
+		c.release()
+		return nil, fmt.Errorf("load collection %s: %w", name, err)
+	}
+	s.collections[name] = c
+	slog.Debug("Loaded local collection", "collection", name, "points", len(c.points), "dim", c.dim)
+	return c, nil
+}
+
+// createCollection writes the header of a new collection file
+func createCollection(path string, dim int) (*localCollection, error) {
+	lock, err := filelock.Open(path + ".lock")
+	if err != nil {
+		return nil, err
+	}
+	c := &localCollection{path: path, lock: lock}
+	err = c.exclusive(func() error {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+		if err != nil {
+			return err
+		}
+		hdr := make([]byte, headerSize)
+		copy(hdr, localMagic)
+		binary.LittleEndian.PutUint32(hdr[len(localMagic):], uint32(dim))
+		if _, err := f.Write(hdr); err != nil {
+			f.Close()
+			return err
+		}
+		if err := f.Close(); err != nil {
+			return err
+		}
+		return c.refresh(true)
+	})
+	if err != nil {
+		c.release()
+		return nil, err
+	}
+	return c, nil
+}
+
+// exclusive runs fn holding the exclusive lock of the file
+func (c *localCollection) exclusive(fn func() error) error {
+	if err := c.lock.Lock(); err != nil {
+		return err
+	}
+	err := fn()
+	return errors.Join(err, c.lock.Unlock())
+}
+
+// read catches up with the file under a shared lock
+func (c *localCollection) read() error {
+	if err := c.lock.RLock(); err != nil {
+		return err
+	}
+	err := c.refresh(false)
+	return errors.Join(err, c.lock.Unlock())
+}
+
+// update appends the records produced by encode under the exclusive lock.
+// encode sees points caught up with the file; the records are applied to
+// points only once they are written.
+func (c *localCollection) update(encode func(w io.Writer) error) error {
+	return c.exclusive(func() error {
+		if err := c.refresh(true); err != nil {
+			return err
+		}
+		var buf bytes.Buffer
+		if err := encode(&buf); err != nil {
+			return fmt.Errorf("write %s: %w", c.path, err)
+		}
+		if buf.Len() == 0 {
+			return nil
+		}
+		if _, err := c.f.Write(buf.Bytes()); err != nil {
+			return fmt.Errorf("write %s: %w", c.path, err)
+		}
+		n, _ := c.replay(bytes.NewReader(buf.Bytes()))
+		c.offset += n
+		return nil
+	})
+}
+
+// refresh replays records appended to the file since the last call and
+// reloads the collection when another process compacted (replaced) the
+// file. A torn tail left by a crashed writer is cut off when the caller
+// holds the exclusive lock.
+func (c *localCollection) refresh(exclusive bool) error {
+	st, err := os.Stat(c.path)
+	if err != nil {
+		return err
+	}
+	if c.f != nil {
+		cur, err := c.f.Stat()
+		if err != nil {
+			return err
+		}
+		if !os.SameFile(st, cur) || st.Size() < c.offset {
+			c.f.Close()
+			c.f = nil
+		}
+	}
+	if c.f == nil {
+		f, err := os.OpenFile(c.path, os.O_RDWR|os.O_APPEND, 0o644)
+		if err != nil {
+			return err
+		}
+		c.f = f
+		c.points = make(map[string]*localPoint)
+		c.ids = nil
+		c.offset = 0
+		c.stale = 0
+	}
+	if st.Size() == c.offset {
+		return nil
+	}
+
+	r := bufio.NewReader(io.NewSectionReader(c.f, c.offset, st.Size()-c.offset))
+	if c.offset == 0 {
+		hdr := make([]byte, headerSize)
+		if _, err := io.ReadFull(r, hdr); err != nil || string(hdr[:len(localMagic)]) != localMagic {
+			return errors.New("not a vector store file")
+		}
+		c.dim = int(binary.LittleEndian.Uint32(hdr[len(localMagic):]))
+		c.offset = int64(headerSize)
+	}
+	n, torn := c.replay(r)
+	c.offset += n
+	if torn && exclusive {
+		slog.Warn("Vector store file has a torn tail, truncating", "path", c.path, "offset", c.offset)
+		return c.f.Truncate(c.offset)
+	}
+	return nil
+}
+
+// replay applies the records of r and returns the length of its valid
+// prefix; torn reports a partial or malformed record after it
+func (c *localCollection) replay(r io.Reader) (n int64, torn bool) {
+	for {
+		op, p, size, err := readRecord(r, c.dim)
+		if err != nil {
+			return n, !errors.Is(err, io.EOF)
+		}
+		n += size
+		switch op {
+		case opUpsert:
+			c.put(p)
+		case opDelete:
+			if _, ok := c.points[p.ID]; ok {
+				delete(c.points, p.ID)
+				c.ids = nil
+				c.stale++
+			}
+			c.stale++
+		}
+	}
+}
+
+func (c *localCollection) put(p models.Point) {
+	if _, ok := c.points[p.ID]; ok {
+		c.stale++
+	} else {
+		c.ids = nil
+	}
+	c.points[p.ID] = &localPoint{payload: p.Payload, vec: p.Vector, norm: norm(p.Vector)}
+}
+
+// close compacts the file if it carries stale records. Under the exclusive
+// lock points is first caught up with the whole file, so the rewrite keeps
+// records other processes appended.
+func (c *localCollection) close() error {
+	defer c.release()
+	return c.exclusive(func() error {
+		if err := c.refresh(true); err != nil {
+			return err
+		}
+		if c.stale == 0 {
+			return nil
+		}
+		return c.compact()
+	})
+}
+
+// compact rewrites the file with live points only
+func (c *localCollection) compact() error {
+	tmp := c.path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return fmt.Errorf("compact: %w", err)
+	}
+	w := bufio.NewWriter(f)
+	var hdr [4]byte
+	binary.LittleEndian.PutUint32(hdr[:], uint32(c.dim))
+	w.WriteString(localMagic)
+	w.Write(hdr[:])
+
+	ids := make([]string, 0, len(c.points))
+	for id := range c.points {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	for _, id := range ids {
+		p := c.points[id]
+		if err := writeUpsert(w, models.Point{ID: id, Vector: p.vec, Payload: p.payload}); err != nil {
+			f.Close()
+			return fmt.Errorf("compact: %w", err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, c.path)
+}
+
+// release closes the file and the lock
+func (c *localCollection) release() {
+	if c.f != nil {
+		c.f.Close()
+		c.f = nil
+	}
+	c.lock.Close()
+}
+
+// point copies a stored point, optionally limiting payload fields
+func (p *localPoint) point(id string, fields []string, withVector bool) models.Point {
+	out := models.Point{ID: id, Payload: p.payload}
+	if fields != nil {
+		out.Payload = make(models.Payload, len(fields))
+		for _, f := range fields {
+			if v, ok := p.payload[f]; ok {
+				out.Payload[f] = v
+			}
+		}
+	}
+	if withVector {
+		out.Vector = p.vec
+	}
+	return out
+}
+
+// matches evaluates a filter against a point
+func matches(f *models.Filter, id string, payload models.Payload) bool {
+	if f == nil {
+		return true
+	}
+	if len(f.IDs) > 0 && !slices.Contains(f.IDs, id) {
+		return false
+	}
+	for _, m := range f.Must {
+		if payload.String(m.Key) != m.Value {
+			return false
+		}
+	}
+	return true
+}
+
+func dot(a, b []float32) float64 {
+	var s float64
+	for i := range a {
+		s += float64(a[i]) * float64(b[i])
+	}
+	return s
+}
+
+func norm(v []float32) float64 {
+	return math.Sqrt(dot(v, v))
+}
+
+// record layout (little endian):
+//
+//	'U' | idLen uint16 | id | payloadLen uint32 | payload JSON | dim × float32
+//	'D' | idLen uint16 | id
+func writeUpsert(w io.Writer, p models.Point) error {
+	payload, err := json.Marshal(p.Payload)
+	if err != nil {
+		return err
+	}
+	buf := make([]byte, 1+2+len(p.ID)+4+len(payload)+4*len(p.Vector))
+	buf[0] = opUpsert
+	binary.LittleEndian.PutUint16(buf[1:], uint16(len(p.ID)))
+	n := 3 + copy(buf[3:], p.ID)
+	binary.LittleEndian.PutUint32(buf[n:], uint32(len(payload)))
+	n += 4
+	n += copy(buf[n:], payload)
+	for _, v := range p.Vector {
+		binary.LittleEndian.PutUint32(buf[n:], math.Float32bits(v))
+		n += 4
+	}
+	_, err = w.Write(buf)
+	return err
+}
+
+func writeDelete(w io.Writer, id string) error {
+	buf := make([]byte, 1+2+len(id))
+	buf[0] = opDelete
+	binary.LittleEndian.PutUint16(buf[1:], uint16(len(id)))
+	copy(buf[3:], id)
+	_, err := w.Write(buf)
+	return err
+}
+
+func readRecord(r io.Reader, dim int) (byte, models.Point, int64, error) {
+	var hdr [3]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			return 0, models.Point{}, 0, err
+		}
+		return 0, models.Point{}, 0, io.EOF
+	}
+	id := make([]byte, binary.LittleEndian.Uint16(hdr[1:]))
+	if _, err := io.ReadFull(r, id); err != nil {
+		return 0, models.Point{}, 0, io.ErrUnexpectedEOF
+	}
+	p := models.Point{ID: string(id)}
+	n := int64(3 + len(id))
+
+	switch hdr[0] {
+	case opDelete:
+		return opDelete, p, n, nil
+	case opUpsert:
+	default:
+		return 0, models.Point{}, 0, fmt.Errorf("unknown record kind %q", hdr[0])
+	}
+
+	var plen [4]byte
+	if _, err := io.ReadFull(r, plen[:]); err != nil {
+		return 0, models.Point{}, 0, io.ErrUnexpectedEOF
+	}
+	raw := make([]byte, binary.LittleEndian.Uint32(plen[:])+uint32(4*dim))
+	if _, err := io.ReadFull(r, raw); err != nil {
+		return 0, models.Point{}, 0, io.ErrUnexpectedEOF
+	}
+	payloadLen := len(raw) - 4*dim
+	payload, err := decodePayload(raw[:payloadLen])
+	if err != nil {
+		return 0, models.Point{}, 0, err
+	}
+	p.Payload = payload
+	p.Vector = make([]float32, dim)
+	for i := range p.Vector {
+		p.Vector[i] = math.Float32frombits(binary.LittleEndian.Uint32(raw[payloadLen+4*i:]))
+	}
+	return opUpsert, p, n + 4 + int64(len(raw)), nil
+}
+
+// decodePayload restores integers, which plain JSON decoding turns into floats
+func decodePayload(raw []byte) (models.Payload, error) {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var m map[string]any
+	if err := dec.Decode(&m); err != nil {
+		return nil, err
+	}
+	for k, v := range m {
+		m[k] = fromJSON(v)
+	}
+	return m, nil
+}
+
+func fromJSON(v any) any {
+	switch x := v.(type) {
+	case json.Number:
+		if i, err := x.Int64(); err == nil {
+			return i
+		}
+		f, _ := x.Float64()
+		return f
+	case []any:
+		for i := range x {
+			x[i] = fromJSON(x[i])
+		}
+	case map[string]any:
+		for k := range x {
+			x[k] = fromJSON(x[k])
+		}
+	}
+	return v
+}
@@ -0,0 +1,289 @@
+package vectorstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"test-ragger/internal/models"
+)
+
+// testPoints are three chunks of two documents in 3-dimensional space
+var testPoints = []models.Point{
+	{ID: "a0", Vector: []float32{1, 0, 0}, Payload: models.Payload{"doc_id": "a", "lang": "en", "text": "alpha", "chunk_index": int64(0)}},
+	{ID: "a1", Vector: []float32{0.9, 0.1, 0}, Payload: models.Payload{"doc_id": "a", "lang": "en", "text": "alpha two", "chunk_index": int64(1)}},
+	{ID: "b0", Vector: []float32{0, 1, 0}, Payload: models.Payload{"doc_id": "b", "lang": "ru", "text": "бета", "chunk_index": int64(0)}},
+}
+
+func openTestStore(t *testing.T, dir string) *Local {
+	t.Helper()
+	s, err := OpenLocal(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+// seed creates collection docs with testPoints
+func seed(t *testing.T, s *Local) {
+	t.Helper()
+	ctx := context.Background()
+	if err := s.CreateCollection(ctx, "docs", 3); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Upsert(ctx, "docs", testPoints); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func searchIDs(t *testing.T, s *Local, req models.SearchRequest) []string {
+	t.Helper()
+	hits, err := s.Search(context.Background(), "docs", req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := make([]string, len(hits))
+	for i, h := range hits {
+		out[i] = h.ID
+	}
+	return out
+}
+
+func scrollIDs(t *testing.T, s *Local, req models.ScrollRequest) []string {
+	t.Helper()
+	page, err := s.Scroll(context.Background(), "docs", req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := make([]string, len(page.Points))
+	for i, p := range page.Points {
+		out[i] = p.ID
+	}
+	return out
+}
+
+func match(key, value string) models.FieldMatch {
+	return models.FieldMatch{Key: key, Value: value}
+}
+
+func TestLocalSearch(t *testing.T) {
+	s := openTestStore(t, t.TempDir())
+	defer s.Close()
+	seed(t, s)
+
+	tests := []struct {
+		name   string
+		vector []float32
+		limit  int
+		filter *models.Filter
+		want   []string
+	}{
+		{"cosine order", []float32{1, 0, 0}, 0, nil, []string{"a0", "a1", "b0"}},
+		{"limit", []float32{0, 1, 0}, 1, nil, []string{"b0"}},
+		{"doc_id", []float32{0, 1, 0}, 0, &models.Filter{Must: []models.FieldMatch{match("doc_id", "a")}}, []string{"a1", "a0"}},
+		{"lang", []float32{1, 0, 0}, 0, &models.Filter{Must: []models.FieldMatch{match("lang", "ru")}}, []string{"b0"}},
+		{"ids", []float32{1, 0, 0}, 0, &models.Filter{IDs: []string{"b0", "a1"}}, []string{"a1", "b0"}},
+		{"ids and payload", []float32{1, 0, 0}, 0, &models.Filter{IDs: []string{"b0", "a1"}, Must: []models.FieldMatch{match("lang", "en")}}, []string{"a1"}},
+		{"no match", []float32{1, 0, 0}, 0, &models.Filter{Must: []models.FieldMatch{match("lang", "de")}}, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := searchIDs(t, s, models.SearchRequest{Vector: tt.vector, Limit: tt.limit, Filter: tt.filter})
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("search = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := s.Search(context.Background(), "docs", models.SearchRequest{Vector: []float32{1, 0}}); err == nil {
+		t.Error("a query vector of the wrong size must fail")
+	}
+	if err := s.Upsert(context.Background(), "docs", []models.Point{{ID: "x", Vector: []float32{1}}}); err == nil {
+		t.Error("a point vector of the wrong size must fail")
+	}
+}
+
+func TestLocalScroll(t *testing.T) {
+	s := openTestStore(t, t.TempDir())
+	defer s.Close()
+	seed(t, s)
+	ctx := context.Background()
+
+	// pages follow ID order
+	var all []string
+	offset := ""
+	for {
+		page, err := s.Scroll(ctx, "docs", models.ScrollRequest{Offset: offset, Limit: 2})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, p := range page.Points {
+			all = append(all, p.ID)
+		}
+		if page.NextOffset == "" {
+			break
+		}
+		offset = page.NextOffset
+	}
+	if want := []string{"a0", "a1", "b0"}; !slices.Equal(all, want) {
+		t.Errorf("scrolled %v, want %v", all, want)
+	}
+
+	if got := scrollIDs(t, s, models.ScrollRequest{Filter: &models.Filter{Must: []models.FieldMatch{match("doc_id", "a")}}}); !slices.Equal(got, []string{"a0", "a1"}) {
+		t.Errorf("doc_id filter = %v", got)
+	}
+	if got := scrollIDs(t, s, models.ScrollRequest{Filter: &models.Filter{IDs: []string{"b0"}}}); !slices.Equal(got, []string{"b0"}) {
+		t.Errorf("ids filter = %v", got)
+	}
+
+	// payload fields, integer payloads and vectors round-trip
+	page, err := s.Scroll(ctx, "docs", models.ScrollRequest{Filter: &models.Filter{IDs: []string{"a1"}}, PayloadFields: []string{"text", "chunk_index"}, WithVectors: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := page.Points[0]
+	if len(p.Payload) != 2 || p.Payload.String("text") != "alpha two" || p.Payload["chunk_index"] != int64(1) {
+		t.Errorf("payload = %#v", p.Payload)
+	}
+	if !slices.Equal(p.Vector, testPoints[1].Vector) {
+		t.Errorf("vector = %v", p.Vector)
+	}
+}
+
+func TestLocalDelete(t *testing.T) {
+	s := openTestStore(t, t.TempDir())
+	defer s.Close()
+	seed(t, s)
+	ctx := context.Background()
+
+	if err := s.Delete(ctx, "docs", models.Filter{}); !errors.Is(err, ErrEmptyFilter) {
+		t.Fatalf("empty filter: err = %v, want ErrEmptyFilter", err)
+	}
+	if err := s.Delete(ctx, "docs", models.Filter{Must: []models.FieldMatch{match("doc_id", "a")}}); err != nil {
+		t.Fatal(err)
+	}
+	if got := scrollIDs(t, s, models.ScrollRequest{}); !slices.Equal(got, []string{"b0"}) {
+		t.Errorf("after deleting doc a: %v", got)
+	}
+	if err := s.Delete(ctx, "docs", models.Filter{IDs: []string{"b0"}}); err != nil {
+		t.Fatal(err)
+	}
+	info, err := s.CollectionInfo(ctx, "docs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Points != 0 || info.Dimension != 3 {
+		t.Errorf("info = %+v, want an empty 3-dimensional collection", info)
+	}
+}
+
+func TestLocalReopen(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+	s := openTestStore(t, dir)
+	seed(t, s)
+	// overwrite and delete, so Close has stale records to compact
+	updated := testPoints[0]
+	updated.Payload = models.Payload{"doc_id": "a", "lang": "en", "text": "alpha updated"}
+	if err := s.Upsert(ctx, "docs", []models.Point{updated}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Delete(ctx, "docs", models.Filter{IDs: []string{"b0"}}); err != nil {
+		t.Fatal(err)
+	}
+	before, _ := os.Stat(filepath.Join(dir, "docs.vec"))
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	after, _ := os.Stat(filepath.Join(dir, "docs.vec"))
+	if after.Size() >= before.Size() {
+		t.Errorf("file is %d bytes after Close, %d before; want it compacted", after.Size(), before.Size())
+	}
+
+	s = openTestStore(t, dir)
+	defer s.Close()
+	page, err := s.Scroll(ctx, "docs", models.ScrollRequest{WithVectors: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := fmt.Sprintf("%d %s %s", len(page.Points), page.Points[0].Payload.String("text"), page.Points[1].ID); got != "2 alpha updated a1" {
+		t.Errorf("reopened store: %s", got)
+	}
+	if got := searchIDs(t, s, models.SearchRequest{Vector: []float32{1, 0, 0}}); !slices.Equal(got, []string{"a0", "a1"}) {
+		t.Errorf("search after reopen = %v", got)
+	}
+	if err := s.CreateCollection(ctx, "docs", 3); err == nil {
+		t.Error("creating an existing collection must fail")
+	}
+}
+
+func TestLocalTornTail(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+	s := openTestStore(t, dir)
+	seed(t, s)
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// a crash in the middle of the last record
+	path := filepath.Join(dir, "docs.vec")
+	st, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Truncate(path, st.Size()-5); err != nil {
+		t.Fatal(err)
+	}
+
+	s = openTestStore(t, dir)
+	if got := scrollIDs(t, s, models.ScrollRequest{}); !slices.Equal(got, []string{"a0", "a1"}) {
+		t.Fatalf("after a torn tail: %v, want the complete records", got)
+	}
+	// the tail is cut off, so new records are readable after it
+	if err := s.Upsert(ctx, "docs", []models.Point{testPoints[2]}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	s = openTestStore(t, dir)
+	defer s.Close()
+	if got := scrollIDs(t, s, models.ScrollRequest{}); !slices.Equal(got, []string{"a0", "a1", "b0"}) {
+		t.Errorf("after rewriting the tail: %v", got)
+	}
+}
+
+func TestLocalSharedFile(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+	a := openTestStore(t, dir)
+	seed(t, a)
+	b := openTestStore(t, dir)
+
+	// b sees a's records, and a's compaction keeps b's
+	if got := scrollIDs(t, b, models.ScrollRequest{}); len(got) != 3 {
+		t.Fatalf("second store sees %v", got)
+	}
+	extra := models.Point{ID: "c0", Vector: []float32{0, 0, 1}, Payload: models.Payload{"doc_id": "c"}}
+	if err := b.Upsert(ctx, "docs", []models.Point{extra}); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Delete(ctx, "docs", models.Filter{IDs: []string{"a0"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got := scrollIDs(t, b, models.ScrollRequest{}); !slices.Equal(got, []string{"a1", "b0", "c0"}) {
+		t.Errorf("after the other store compacted: %v", got)
+	}
+	if err := b.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
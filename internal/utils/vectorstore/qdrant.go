@@ -0,0 +1,295 @@
+package vectorstore
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/AlekSi/pointer"
+	qdrant "github.com/qdrant/go-client/qdrant"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"test-ragger/internal/models"
+	"test-ragger/internal/utils"
+)
+
+// searchHnswEf trades search speed for recall
+const searchHnswEf = 128
+
+// Qdrant is a Store backed by a Qdrant server over gRPC
+type Qdrant struct {
+	collections qdrant.CollectionsClient
+	points      qdrant.PointsClient
+}
+
+// NewQdrant creates a store over Qdrant gRPC clients. The caller owns the connection.
+func NewQdrant(collections qdrant.CollectionsClient, points qdrant.PointsClient) *Qdrant {
+	return &Qdrant{collections: collections, points: points}
+}
+
+func (q *Qdrant) CollectionInfo(ctx context.Context, collection string) (*models.CollectionInfo, error) {
+	resp, err := q.collections.Get(ctx, &qdrant.GetCollectionInfoRequest{CollectionName: collection})
+	if err != nil {
+		if isNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	vc := resp.GetResult().GetConfig().GetParams().GetVectorsConfig()
+	if vc.GetParamsMap() != nil {
+		return nil, fmt.Errorf("collection %s uses named vectors, expected a single unnamed vector", collection)
+	}
+	params := vc.GetParams()
+	return &models.CollectionInfo{
+		Name:      collection,
+		Dimension: int(params.GetSize()),
+		Distance:  params.GetDistance().String(),
+		Points:    resp.GetResult().GetPointsCount(),
+	}, nil
+}
+
+func (q *Qdrant) CreateCollection(ctx context.Context, collection string, dim int) error {
+	_, err := q.collections.Create(ctx, &qdrant.CreateCollection{
+		CollectionName: collection,
+		VectorsConfig: &qdrant.VectorsConfig{
+			Config: &qdrant.VectorsConfig_Params{
+				Params: &qdrant.VectorParams{
+					Size:     uint64(dim),
+					Distance: qdrant.Distance_Cosine,
+				},
+			},
+		},
+	})
+	return err
+}
+
+func (q *Qdrant) Upsert(ctx context.Context, collection string, points []models.Point) error {
+	structs := make([]*qdrant.PointStruct, len(points))
+	for i, p := range points {
+		structs[i] = &qdrant.PointStruct{
+			Id:      toPointID(p.ID),
+			Vectors: &qdrant.Vectors{VectorsOptions: &qdrant.Vectors_Vector{Vector: &qdrant.Vector{Data: p.Vector}}},
+			Payload: toPayload(p.Payload),
+		}
+	}
+	_, err := q.points.Upsert(ctx, &qdrant.UpsertPoints{
+		CollectionName: collection,
+		Points:         structs,
+		Wait:           pointer.To(true),
+	})
+	return err
+}
+
+func (q *Qdrant) Delete(ctx context.Context, collection string, filter models.Filter) error {
+	if filter.Empty() {
+		return ErrEmptyFilter
+	}
+	sel := &qdrant.PointsSelector{PointsSelectorOneOf: &qdrant.PointsSelector_Filter{Filter: toFilter(&filter)}}
+	if len(filter.Must) == 0 {
+		sel = &qdrant.PointsSelector{PointsSelectorOneOf: &qdrant.PointsSelector_Points{Points: &qdrant.PointsIdsList{Ids: toPointIDs(filter.IDs)}}}
+	}
+	_, err := q.points.Delete(ctx, &qdrant.DeletePoints{
+		CollectionName: collection,
+		Wait:           pointer.To(true),
+		Points:         sel,
+	})
+	return err
+}
+
+func (q *Qdrant) Scroll(ctx context.Context, collection string, req models.ScrollRequest) (models.ScrollPage, error) {
+	limit := uint32(req.Limit)
+	scroll := &qdrant.ScrollPoints{
+		CollectionName: collection,
+		Filter:         toFilter(req.Filter),
+		Limit:          &limit,
+		WithPayload:    payloadSelector(req.PayloadFields),
+		WithVectors:    &qdrant.WithVectorsSelector{SelectorOptions: &qdrant.WithVectorsSelector_Enable{Enable: req.WithVectors}},
+	}
+	if req.Offset != "" {
+		scroll.Offset = toPointID(req.Offset)
+	}
+	resp, err := q.points.Scroll(ctx, scroll)
+	if err != nil {
+		return models.ScrollPage{}, err
+	}
+
+	page := models.ScrollPage{Points: make([]models.Point, len(resp.Result))}
+	for i, p := range resp.Result {
+		page.Points[i] = models.Point{
+			ID:      fromPointID(p.Id),
+			Vector:  vectorData(p.Vectors.GetVector()),
+			Payload: fromPayload(p.Payload),
+		}
+	}
+	if resp.NextPageOffset != nil {
+		page.NextOffset = fromPointID(resp.NextPageOffset)
+	}
+	return page, nil
+}
+
+func (q *Qdrant) Search(ctx context.Context, collection string, req models.SearchRequest) ([]models.ScoredPoint, error) {
+	resp, err := q.points.Search(ctx, &qdrant.SearchPoints{
+		CollectionName: collection,
+		Vector:         req.Vector,
+		Limit:          uint64(req.Limit),
+		Params:         &qdrant.SearchParams{HnswEf: utils.Uint64Ptr(searchHnswEf)},
+		Filter:         toFilter(req.Filter),
+		WithPayload:    &qdrant.WithPayloadSelector{SelectorOptions: &qdrant.WithPayloadSelector_Enable{Enable: true}},
+		WithVectors:    &qdrant.WithVectorsSelector{SelectorOptions: &qdrant.WithVectorsSelector_Enable{Enable: req.WithVectors}},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	hits := make([]models.ScoredPoint, len(resp.Result))
+	for i, r := range resp.Result {
+		hits[i] = models.ScoredPoint{
+			Point: models.Point{
+				ID:      fromPointID(r.Id),
+				Vector:  vectorData(r.Vectors.GetVector()),
+				Payload: fromPayload(r.Payload),
+			},
+			Score: r.GetScore(),
+		}
+	}
+	return hits, nil
+}
+
+// Close is a no-op: the gRPC connection belongs to the caller
+func (q *Qdrant) Close() error { return nil }
+
+func isNotFound(err error) bool {
+	if status.Code(err) == codes.NotFound {
+		return true
+	}
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "doesn't exist") || strings.Contains(msg, "not found")
+}
+
+// toPointID maps decimal IDs to legacy numeric points and everything else to UUIDs
+func toPointID(id string) *qdrant.PointId {
+	if n, err := strconv.ParseUint(id, 10, 64); err == nil {
+		return &qdrant.PointId{PointIdOptions: &qdrant.PointId_Num{Num: n}}
+	}
+	return &qdrant.PointId{PointIdOptions: &qdrant.PointId_Uuid{Uuid: id}}
+}
+
+func toPointIDs(ids []string) []*qdrant.PointId {
+	out := make([]*qdrant.PointId, len(ids))
+	for i, id := range ids {
+		out[i] = toPointID(id)
+	}
+	return out
+}
+
+func fromPointID(id *qdrant.PointId) string {
+	if u := id.GetUuid(); u != "" {
+		return u
+	}
+	return strconv.FormatUint(id.GetNum(), 10)
+}
+
+func toFilter(f *models.Filter) *qdrant.Filter {
+	if f == nil || f.Empty() {
+		return nil
+	}
+	var must []*qdrant.Condition
+	if len(f.IDs) > 0 {
+		must = append(must, &qdrant.Condition{ConditionOneOf: &qdrant.Condition_HasId{HasId: &qdrant.HasIdCondition{HasId: toPointIDs(f.IDs)}}})
+	}
+	for _, m := range f.Must {
+		must = append(must, &qdrant.Condition{ConditionOneOf: &qdrant.Condition_Field{Field: &qdrant.FieldCondition{
+			Key:   m.Key,
+			Match: &qdrant.Match{MatchValue: &qdrant.Match_Keyword{Keyword: m.Value}},
+		}}})
+	}
+	return &qdrant.Filter{Must: must}
+}
+
+func payloadSelector(fields []string) *qdrant.WithPayloadSelector {
+	if fields == nil {
+		return &qdrant.WithPayloadSelector{SelectorOptions: &qdrant.WithPayloadSelector_Enable{Enable: true}}
+	}
+	return &qdrant.WithPayloadSelector{SelectorOptions: &qdrant.WithPayloadSelector_Include{Include: &qdrant.PayloadIncludeSelector{Fields: fields}}}
+}
+
+// vectorData extracts a dense vector from either the current
+// or the deprecated representation
+func vectorData(v *qdrant.VectorOutput) []float32 {
+	if d := v.GetDense(); d != nil {
+		return d.GetData()
+	}
+	return v.GetData()
+}
+
+func toPayload(p models.Payload) map[string]*qdrant.Value {
+	out := make(map[string]*qdrant.Value, len(p))
+	for k, v := range p {
+		out[k] = toValue(v)
+	}
+	return out
+}
+
+func toValue(v any) *qdrant.Value {
+	switch x := v.(type) {
+	case string:
+		return &qdrant.Value{Kind: &qdrant.Value_StringValue{StringValue: x}}
+	case int:
+		return &qdrant.Value{Kind: &qdrant.Value_IntegerValue{IntegerValue: int64(x)}}
+	case int64:
+		return &qdrant.Value{Kind: &qdrant.Value_IntegerValue{IntegerValue: x}}
+	case float32:
+		return &qdrant.Value{Kind: &qdrant.Value_DoubleValue{DoubleValue: float64(x)}}
+	case float64:
+		return &qdrant.Value{Kind: &qdrant.Value_DoubleValue{DoubleValue: x}}
+	case bool:
+		return &qdrant.Value{Kind: &qdrant.Value_BoolValue{BoolValue: x}}
+	case []string:
+		list := make([]*qdrant.Value, len(x))
+		for i, s := range x {
+			list[i] = toValue(s)
+		}
+		return &qdrant.Value{Kind: &qdrant.Value_ListValue{ListValue: &qdrant.ListValue{Values: list}}}
+	case []any:
+		list := make([]*qdrant.Value, len(x))
+		for i, e := range x {
+			list[i] = toValue(e)
+		}
+		return &qdrant.Value{Kind: &qdrant.Value_ListValue{ListValue: &qdrant.ListValue{Values: list}}}
+	case map[string]any:
+		return &qdrant.Value{Kind: &qdrant.Value_StructValue{StructValue: &qdrant.Struct{Fields: toPayload(x)}}}
+	}
+	return &qdrant.Value{Kind: &qdrant.Value_NullValue{}}
+}
+
+func fromPayload(p map[string]*qdrant.Value) models.Payload {
+	out := make(models.Payload, len(p))
+	for k, v := range p {
+		out[k] = fromValue(v)
+	}
+	return out
+}
+
+func fromValue(v *qdrant.Value) any {
+	switch x := v.GetKind().(type) {
+	case *qdrant.Value_StringValue:
+		return x.StringValue
+	case *qdrant.Value_IntegerValue:
+		return x.IntegerValue
+	case *qdrant.Value_DoubleValue:
+		return x.DoubleValue
+	case *qdrant.Value_BoolValue:
+		return x.BoolValue
+	case *qdrant.Value_ListValue:
+		list := make([]any, len(x.ListValue.GetValues()))
+		for i, e := range x.ListValue.GetValues() {
+			list[i] = fromValue(e)
+		}
+		return list
+	case *qdrant.Value_StructValue:
+		return map[string]any(fromPayload(x.StructValue.GetFields()))
+	}
+	return nil
+}
@@ -0,0 +1,28 @@
+package vectorstore
+
+import (
+	"context"
+	"errors"
+
+	"test-ragger/internal/models"
+)
+
+// DistanceCosine is the only distance collections are created with
+const DistanceCosine = "Cosine"
+
+// ErrEmptyFilter protects against deleting a whole collection by mistake
+var ErrEmptyFilter = errors.New("delete requires a non-empty filter")
+
+// Store is a backend-neutral vector database
+type Store interface {
+	// CollectionInfo returns nil without an error when the collection does not exist
+	CollectionInfo(ctx context.Context, collection string) (*models.CollectionInfo, error)
+	CreateCollection(ctx context.Context, collection string, dim int) error
+
+	Upsert(ctx context.Context, collection string, points []models.Point) error
+	Delete(ctx context.Context, collection string, filter models.Filter) error
+	Scroll(ctx context.Context, collection string, req models.ScrollRequest) (models.ScrollPage, error)
+	Search(ctx context.Context, collection string, req models.SearchRequest) ([]models.ScoredPoint, error)
+
+	Close() error
+}